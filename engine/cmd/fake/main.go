@@ -1,18 +1,30 @@
 package main
 
 import (
+	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var (
 	WITH_FAKE_ERROR_DATABASE = false
 	WITH_FAKE_ERROR_WHATSAPP = false
+	WITH_FAKE_ERROR_GRPC     = false
+	WITH_FAKE_ERROR_REALTIME = false
 )
 
 // To simulate errors in services for testing purposes.
 func main() {
+	healthServer := startGrpcHealthServer()
+
 	r := gin.Default()
 
 	r.GET("/check-database", func(c *gin.Context) {
@@ -31,6 +43,47 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok", "message": "whatsapp queue is healthy"})
 	})
 
+	// Echoes every message back, or stays silent while the error is on.
+	r.GET("/realtime/ws", gin.WrapH(websocket.Handler(func(conn *websocket.Conn) {
+		for {
+			var message string
+			if err := websocket.Message.Receive(conn, &message); err != nil {
+				return
+			}
+			if WITH_FAKE_ERROR_REALTIME {
+				continue
+			}
+			if err := websocket.Message.Send(conn, message); err != nil {
+				return
+			}
+		}
+	})))
+
+	// Streams a tick event every second, or only keep-alives while the
+	// error is on.
+	r.GET("/realtime/events", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for tick := 1; ; tick++ {
+			if WITH_FAKE_ERROR_REALTIME {
+				fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			} else {
+				fmt.Fprintf(c.Writer, "event: tick\ndata: {\"tick\": %d}\n\n", tick)
+			}
+			c.Writer.Flush()
+
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+
 	r.POST("/toggle", func(c *gin.Context) {
 		kind := c.Query("kind")
 
@@ -43,6 +96,20 @@ func main() {
 			WITH_FAKE_ERROR_WHATSAPP = !WITH_FAKE_ERROR_WHATSAPP
 			c.JSON(http.StatusOK, gin.H{"status": "ok", "whatsapp_error": WITH_FAKE_ERROR_WHATSAPP})
 			return
+		case "grpc":
+			WITH_FAKE_ERROR_GRPC = !WITH_FAKE_ERROR_GRPC
+			servingStatus := healthpb.HealthCheckResponse_SERVING
+			if WITH_FAKE_ERROR_GRPC {
+				servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+			}
+			healthServer.SetServingStatus("", servingStatus)
+			healthServer.SetServingStatus("payments", servingStatus)
+			c.JSON(http.StatusOK, gin.H{"status": "ok", "grpc_error": WITH_FAKE_ERROR_GRPC})
+			return
+		case "realtime":
+			WITH_FAKE_ERROR_REALTIME = !WITH_FAKE_ERROR_REALTIME
+			c.JSON(http.StatusOK, gin.H{"status": "ok", "realtime_error": WITH_FAKE_ERROR_REALTIME})
+			return
 		}
 
 		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "invalid kind"})
@@ -52,3 +119,26 @@ func main() {
 		panic(err)
 	}
 }
+
+// startGrpcHealthServer exposes grpc.health.v1.Health on :6277 for the
+// overall server ("") and a "payments" service.
+func startGrpcHealthServer() *health.Server {
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("payments", healthpb.HealthCheckResponse_SERVING)
+
+	listener, err := net.Listen("tcp", ":6277")
+	if err != nil {
+		panic(err)
+	}
+
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			log.Printf("grpc health server stopped: %v", err)
+		}
+	}()
+
+	return healthServer
+}
@@ -2,15 +2,23 @@ package main
 
 import (
 	"log"
+	"net/http"
+	"time"
 
 	"github.com/mateusgcoelho/sentinel/engine/internal/apikey"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
 	"github.com/mateusgcoelho/sentinel/engine/internal/auth"
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
 	"github.com/mateusgcoelho/sentinel/engine/internal/config"
 	"github.com/mateusgcoelho/sentinel/engine/internal/database"
+	"github.com/mateusgcoelho/sentinel/engine/internal/escalation"
 	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
 	"github.com/mateusgcoelho/sentinel/engine/internal/monitor"
+	"github.com/mateusgcoelho/sentinel/engine/internal/oidc"
 	"github.com/mateusgcoelho/sentinel/engine/internal/request"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
 	"github.com/mateusgcoelho/sentinel/engine/internal/server"
+	"github.com/mateusgcoelho/sentinel/engine/internal/session"
 	"github.com/mateusgcoelho/sentinel/engine/internal/user"
 	"gorm.io/gorm"
 )
@@ -32,27 +40,63 @@ func main() {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
-	startWorkers(gormDb)
+	monitor.Configure(monitor.ExecutionSettings{
+		ExecEnabled:       appConfig.ExecMonitorsEnabled,
+		ExecAllowedPaths:  appConfig.ExecAllowedPaths,
+		ResponseBodyLimit: appConfig.ResponseBodyLimit,
+	})
+
+	startWorkers(gormDb, appConfig)
 
 	apiKeyMiddleware := apikey.NewApiKeyMiddleware(gormDb)
 
+	authSettings := auth.Settings{
+		RequireTwoFactor:    appConfig.RequireTwoFactor,
+		LocalSignInDisabled: appConfig.LocalSignInDisabled,
+	}
+
+	if appConfig.Oidc.Enabled() {
+		authSettings.Oidc = &auth.OidcSettings{
+			Provider: oidc.NewProvider(oidc.Config{
+				Issuer:       appConfig.Oidc.Issuer,
+				ClientID:     appConfig.Oidc.ClientID,
+				ClientSecret: appConfig.Oidc.ClientSecret,
+				RedirectURL:  appConfig.Oidc.RedirectURL,
+				Scopes:       appConfig.Oidc.Scopes,
+			}, &http.Client{Timeout: 10 * time.Second}, clock.System),
+			GroupsClaim:  appConfig.Oidc.GroupsClaim,
+			RoleMapping:  appConfig.Oidc.RoleMapping,
+			DefaultRole:  appConfig.Oidc.DefaultRole,
+			PostLoginURL: appConfig.Oidc.PostLoginURL,
+		}
+	}
+
+	authHandler := auth.NewHandler(gormDb, appConfig.JwtSecret, authSettings)
+	accessMiddleware := authHandler.AccessMiddleware()
+	adminMiddleware := authHandler.AdminMiddleware()
+
 	handlers := []server.IHandler{
-		auth.NewHandler(gormDb, appConfig.JwtSecret),
-		monitor.NewHandler(gormDb),
-		integration.NewHandler(gormDb),
-		user.NewHandler(gormDb),
-		request.NewHandler(gormDb, apiKeyMiddleware.ValidateApiKey),
-		apikey.NewHandler(gormDb),
+		authHandler,
+		monitor.NewHandler(gormDb, accessMiddleware, adminMiddleware),
+		integration.NewHandler(gormDb, accessMiddleware),
+		user.NewHandler(gormDb, authHandler.AuthMiddleware()),
+		request.NewHandler(gormDb, apiKeyMiddleware.ValidateApiKey, accessMiddleware),
+		apikey.NewHandler(gormDb, adminMiddleware),
+		escalation.NewHandler(gormDb, accessMiddleware),
+		secret.NewHandler(gormDb, adminMiddleware),
+		audit.NewHandler(gormDb, adminMiddleware),
 	}
 
-	server := server.New(appConfig, handlers)
+	auditRecorder := audit.NewRecorder(gormDb)
+
+	server := server.New(appConfig, handlers, authHandler.IdentifyMiddleware(), auditRecorder.Middleware(), authHandler.PasswordChangeMiddleware())
 
 	if err := server.Run(); err != nil {
 		log.Fatalf("failed to run server: %v", err)
 	}
 }
 
-func startWorkers(gormDb *gorm.DB) {
+func startWorkers(gormDb *gorm.DB, appConfig config.Config) {
 	monitorWorker := monitor.NewWorker(gormDb)
 
 	go func() {
@@ -69,7 +113,23 @@ func startWorkers(gormDb *gorm.DB) {
 		}
 	}()
 
+	runningWatchdogWorker := monitor.NewRunningWatchdogWorker(gormDb)
+
+	go func() {
+		if err := runningWatchdogWorker.StartWorker(); err != nil {
+			log.Fatalf("running watchdog worker encountered an error: %v", err)
+		}
+	}()
+
 	pruneRequestsWorker := request.NewPruneRequestsWorker(gormDb)
 
 	go pruneRequestsWorker.StartWorker()
+
+	pruneAuditWorker := audit.NewPruneWorker(gormDb, appConfig.AuditRetentionDays)
+
+	go pruneAuditWorker.StartWorker()
+
+	pruneSessionsWorker := session.NewPruneWorker(gormDb)
+
+	go pruneSessionsWorker.StartWorker()
 }
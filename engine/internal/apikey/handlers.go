@@ -4,24 +4,30 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
 	"gorm.io/gorm"
 )
 
 type ApiKeyHandler struct {
 	database *gorm.DB
+
+	adminMiddleware gin.HandlerFunc
 }
 
-func NewHandler(db *gorm.DB) *ApiKeyHandler {
+// NewHandler keeps the routes behind adminMiddleware, as listing the keys
+// reveals their values.
+func NewHandler(db *gorm.DB, adminMiddleware gin.HandlerFunc) *ApiKeyHandler {
 	return &ApiKeyHandler{
-		database: db,
+		database:        db,
+		adminMiddleware: adminMiddleware,
 	}
 }
 
 func (h *ApiKeyHandler) SetupRoutes(r *gin.Engine) {
 	request := r.Group("/keys")
 	{
-		request.GET("", h.HandleListApiKeys)
-		request.POST("", h.HandleCreateApiKey)
+		request.GET("", h.adminMiddleware, h.HandleListApiKeys)
+		request.POST("", audit.Action("api_key.create"), h.adminMiddleware, h.HandleCreateApiKey)
 	}
 }
 
@@ -51,6 +57,9 @@ func (h *ApiKeyHandler) HandleCreateApiKey(c *gin.Context) {
 		return
 	}
 
+	audit.SetTarget(c, "API_KEY", apiKey.ID)
+	audit.SetSummary(c, nil, gin.H{"name": apiKey.Name})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "API key created successfully",
 	})
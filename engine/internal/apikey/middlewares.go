@@ -17,16 +17,27 @@ func NewApiKeyMiddleware(db *gorm.DB) *ApiKeyMiddleware {
 	}
 }
 
+// HeaderName is the request header API keys are sent in.
+const HeaderName = "X-API-KEY"
+
+// Find returns the API key with the given value, unless it was revoked.
+func Find(database *gorm.DB, value string) (ApiKeyConfig, error) {
+	var apiKey ApiKeyConfig
+	err := database.Where("value = ? AND revoked = false", value).First(&apiKey).Error
+
+	return apiKey, err
+}
+
 func (m *ApiKeyMiddleware) ValidateApiKey(c *gin.Context) {
-	apiKeyToken := c.GetHeader("X-API-KEY")
+	apiKeyToken := c.GetHeader(HeaderName)
 	if apiKeyToken == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "API key is required"})
 		c.Abort()
 		return
 	}
 
-	var apiKey ApiKeyConfig
-	if err := m.database.Where("value = ? AND revoked = false", apiKeyToken).First(&apiKey).Error; err != nil {
+	apiKey, err := Find(m.database, apiKeyToken)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to validate API key"})
 
 		c.Abort()
@@ -34,6 +45,7 @@ func (m *ApiKeyMiddleware) ValidateApiKey(c *gin.Context) {
 	}
 
 	c.Set("api_key_config_id", apiKey.ID)
+	c.Set("api_key_name", apiKey.Name)
 
 	c.Next()
 }
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/pagination"
+	"gorm.io/gorm"
+)
+
+const defaultPerPage = 50
+
+type AuditHandler struct {
+	database *gorm.DB
+
+	adminMiddleware gin.HandlerFunc
+}
+
+func NewHandler(db *gorm.DB, adminMiddleware gin.HandlerFunc) *AuditHandler {
+	return &AuditHandler{
+		database:        db,
+		adminMiddleware: adminMiddleware,
+	}
+}
+
+func (h *AuditHandler) SetupRoutes(r *gin.Engine) {
+	audit := r.Group("/audit")
+	{
+		audit.GET("", h.adminMiddleware, h.HandleListEntries)
+	}
+}
+
+func (h *AuditHandler) HandleListEntries(c *gin.Context) {
+	var query EntryListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	var total int64
+	if err := query.Apply(h.database.Model(&Entry{})).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to count audit entries"})
+		return
+	}
+
+	page := max(query.Page, 1)
+
+	perPage := query.PerPage
+	if perPage == 0 {
+		perPage = defaultPerPage
+	}
+
+	entries := []Entry{}
+	if err := query.Apply(h.database.Model(&Entry{})).
+		Order("id DESC").
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve audit entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       entries,
+		"pagination": pagination.New(int(total), perPage, page),
+	})
+}
+
+func (q EntryListQuery) Apply(query *gorm.DB) *gorm.DB {
+	if q.ActorType != "" {
+		query = query.Where("actor_type = ?", q.ActorType)
+	}
+	if q.ActorID != nil {
+		query = query.Where("actor_id = ?", *q.ActorID)
+	}
+	if q.Actor != "" {
+		query = query.Where("actor_name = ?", q.Actor)
+	}
+
+	if prefix, ok := strings.CutSuffix(q.Action, ".*"); ok {
+		query = query.Where("action LIKE ? ESCAPE '\\'", escapeLike(prefix)+".%")
+	} else if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+
+	if q.TargetType != "" {
+		query = query.Where("target_type = ?", q.TargetType)
+	}
+	if q.TargetID != "" {
+		query = query.Where("target_id = ?", q.TargetID)
+	}
+	if q.Outcome != "" {
+		query = query.Where("outcome = ?", q.Outcome)
+	}
+	if q.From > 0 {
+		query = query.Where("created_at >= ?", q.From)
+	}
+	if q.To > 0 {
+		query = query.Where("created_at <= ?", q.To)
+	}
+
+	return query
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PruneWorker struct {
+	database      *gorm.DB
+	retentionDays int
+}
+
+// NewPruneWorker returns a worker that deletes entries older than
+// retentionDays. A retention of zero keeps entries forever.
+func NewPruneWorker(db *gorm.DB, retentionDays int) *PruneWorker {
+	return &PruneWorker{
+		database:      db,
+		retentionDays: retentionDays,
+	}
+}
+
+func (w *PruneWorker) StartWorker() {
+	if w.retentionDays <= 0 {
+		log.Println("[prune-audit-worker] audit retention disabled, entries are kept forever")
+		return
+	}
+
+	log.Printf("[prune-audit-worker] starting prune audit worker, keeping %d days", w.retentionDays)
+
+	for {
+		cutoff := time.Now().AddDate(0, 0, -w.retentionDays)
+
+		result := w.database.Where("created_at < ?", cutoff.Unix()).Delete(&Entry{})
+
+		if result.Error != nil {
+			log.Printf("[prune-audit-worker] failed to prune audit entries: %v", result.Error)
+		} else if result.RowsAffected > 0 {
+			log.Printf("[prune-audit-worker] pruned %d audit entries older than %d days", result.RowsAffected, w.retentionDays)
+		}
+
+		time.Sleep(1 * time.Hour)
+	}
+}
@@ -0,0 +1,241 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"gorm.io/gorm"
+)
+
+const (
+	actionKey     = "audit_action"
+	targetTypeKey = "audit_target_type"
+	targetIDKey   = "audit_target_id"
+	beforeKey     = "audit_before"
+	afterKey      = "audit_after"
+	skipKey       = "audit_skip"
+)
+
+type Recorder struct {
+	database *gorm.DB
+}
+
+func NewRecorder(db *gorm.DB) *Recorder {
+	return &Recorder{
+		database: db,
+	}
+}
+
+// Action marks a route as audited under the given action name, e.g.
+// "monitor.update". The entry itself is written by Recorder.Middleware.
+func Action(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(actionKey, name)
+		c.Next()
+	}
+}
+
+// SetTarget sets what the action was applied to. Routes with an :id or :name
+// parameter default to it, typed after the action's prefix.
+func SetTarget(c *gin.Context, targetType string, targetID any) {
+	c.Set(targetTypeKey, targetType)
+	c.Set(targetIDKey, fmt.Sprint(targetID))
+}
+
+// SetSummary sets the before and after summary of the action. Changes to
+// monitors and integrations are summarized from their recorded versions
+// without it.
+func SetSummary(c *gin.Context, before any, after any) {
+	c.Set(beforeKey, before)
+	c.Set(afterKey, after)
+}
+
+// Skip leaves the current request out of the audit log, for requests such as
+// dry runs that change nothing.
+func Skip(c *gin.Context) {
+	c.Set(skipKey, true)
+}
+
+// Middleware writes an entry for every request that went through a route
+// marked with Action, once the handler has responded. Failing to write the
+// entry does not fail the request.
+func (r *Recorder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		action := c.GetString(actionKey)
+		if action == "" || c.GetBool(skipKey) {
+			return
+		}
+
+		entry, err := r.buildEntry(c, action)
+		if err == nil {
+			err = r.database.Create(&entry).Error
+		}
+		if err != nil {
+			log.Printf("[audit] failed to record %s: %v", action, err)
+		}
+	}
+}
+
+// Write records an entry outside of the route's own one, such as a lockout
+// triggered by a sign-in. The actor, IP and user agent come from the request.
+func Write(database *gorm.DB, c *gin.Context, entry Entry) error {
+	entry.IP = c.ClientIP()
+	entry.UserAgent = c.Request.UserAgent()
+	setActor(c, &entry)
+
+	return database.Create(&entry).Error
+}
+
+func (r *Recorder) buildEntry(c *gin.Context, action string) (Entry, error) {
+	status := c.Writer.Status()
+
+	entry := Entry{
+		Action:     action,
+		Outcome:    OutcomeSuccess,
+		Status:     status,
+		TargetType: c.GetString(targetTypeKey),
+		TargetID:   c.GetString(targetIDKey),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+
+	if status >= 400 {
+		entry.Outcome = OutcomeFailure
+	}
+
+	setActor(c, &entry)
+
+	before, _ := c.Get(beforeKey)
+	after, _ := c.Get(afterKey)
+
+	// Versions recorded by a failed request belong to a rolled back
+	// transaction, so they are only used for successful ones.
+	if versions := history.Recorded(c); entry.Outcome == OutcomeSuccess && len(versions) > 0 && before == nil && after == nil {
+		before, after = versionSummary(versions)
+
+		if entry.TargetType == "" {
+			entry.TargetType, entry.TargetID = versionTarget(versions)
+		}
+	}
+
+	if entry.TargetType == "" {
+		entry.TargetType, entry.TargetID = routeTarget(c, action)
+	}
+
+	var err error
+	if entry.Before, err = summaryJSON(before); err != nil {
+		return entry, err
+	}
+	if entry.After, err = summaryJSON(after); err != nil {
+		return entry, err
+	}
+
+	return entry, nil
+}
+
+// setActor attributes the entry to the signed in user or, failing that, to
+// the API key the request was made with.
+func setActor(c *gin.Context, entry *Entry) {
+	entry.ActorType = ActorTypeAnonymous
+
+	if userID, err := strconv.ParseUint(c.GetString("user_id"), 10, 64); err == nil {
+		id := uint(userID)
+		entry.ActorType = ActorTypeUser
+		entry.ActorID = &id
+		entry.ActorName = c.GetString("username")
+		return
+	}
+
+	if apiKeyID := c.GetUint("api_key_config_id"); apiKeyID != 0 {
+		entry.ActorType = ActorTypeApiKey
+		entry.ActorID = &apiKeyID
+		entry.ActorName = c.GetString("api_key_name")
+	}
+}
+
+// routeTarget derives the target from the route's :id or :name parameter,
+// using the action's prefix as its type.
+func routeTarget(c *gin.Context, action string) (string, string) {
+	targetID := c.Param("id")
+	if targetID == "" {
+		targetID = c.Param("name")
+	}
+	if targetID == "" {
+		return "", ""
+	}
+
+	prefix, _, _ := strings.Cut(action, ".")
+
+	return strings.ToUpper(prefix), targetID
+}
+
+// versionTarget returns the entity the versions belong to, or only their type
+// when they belong to several entities of the same type.
+func versionTarget(versions []history.Version) (string, string) {
+	entityType := versions[0].EntityType
+	for _, version := range versions[1:] {
+		if version.EntityType != entityType {
+			return "", ""
+		}
+	}
+
+	if len(versions) > 1 {
+		return string(entityType), ""
+	}
+
+	return string(entityType), strconv.FormatUint(uint64(versions[0].EntityID), 10)
+}
+
+// versionSummary turns recorded versions into the before and after values of
+// the fields they changed. Changes to several entities are keyed by
+// "TYPE:id".
+func versionSummary(versions []history.Version) (any, any) {
+	summarize := func(version history.Version) (map[string]any, map[string]any) {
+		before := map[string]any{}
+		after := map[string]any{}
+
+		for _, change := range version.Changes {
+			if change.Before != nil {
+				before[change.Field] = change.Before
+			}
+			if change.After != nil {
+				after[change.Field] = change.After
+			}
+		}
+
+		return before, after
+	}
+
+	if len(versions) == 1 {
+		return summarize(versions[0])
+	}
+
+	before := map[string]any{}
+	after := map[string]any{}
+
+	for _, version := range versions {
+		key := fmt.Sprintf("%s:%d", version.EntityType, version.EntityID)
+		before[key], after[key] = summarize(version)
+	}
+
+	return before, after
+}
+
+func summaryJSON(value any) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if fields, ok := value.(map[string]any); ok && len(fields) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(value)
+}
@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/database/dbtest"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"gorm.io/gorm"
+)
+
+// newTestRouter records the audited test routes. The actor comes from the
+// X-User and X-Api-Key headers, standing in for the auth middlewares.
+func newTestRouter(t *testing.T) (*gorm.DB, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	database := dbtest.Open(t, &Entry{}, &history.Version{})
+
+	r := gin.New()
+	r.Use(NewRecorder(database).Middleware())
+	r.Use(func(c *gin.Context) {
+		if username := c.GetHeader("X-User"); username != "" {
+			c.Set("user_id", "3")
+			c.Set("username", username)
+		}
+		if name := c.GetHeader("X-Api-Key"); name != "" {
+			c.Set("api_key_config_id", uint(7))
+			c.Set("api_key_name", name)
+		}
+	})
+
+	// The monitor is renamed and the version recorded, then the request
+	// fails when asked to, as if the transaction was rolled back.
+	r.PUT("/monitors/:id", Action("monitor.update"), func(c *gin.Context) {
+		before := map[string]any{"name": "api", "interval": 60}
+		after := map[string]any{"name": "api v2", "interval": 60}
+
+		if err := history.Record(database, history.EntityTypeMonitor, 12, history.AuthorFromContext(c), before, after); err != nil {
+			t.Errorf("failed to record version: %v", err)
+		}
+
+		if c.Query("fail") != "" {
+			c.Status(http.StatusConflict)
+			return
+		}
+
+		c.Status(http.StatusOK)
+	})
+
+	r.DELETE("/secrets/:name", Action("secret.delete"), func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	r.GET("/monitors", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	return database, r
+}
+
+func serve(t *testing.T, database *gorm.DB, r *gin.Engine, req *http.Request) Entry {
+	t.Helper()
+
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entries []Entry
+	if err := database.Order("id DESC").Limit(1).Find(&entries).Error; err != nil {
+		t.Fatalf("failed to load entries: %v", err)
+	}
+
+	if len(entries) == 0 {
+		return Entry{}
+	}
+
+	return entries[0]
+}
+
+func TestRecorderAttributesActor(t *testing.T) {
+	database, r := newTestRouter(t)
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		actorType ActorType
+		actorID   uint
+		actorName string
+	}{
+		{name: "user", headers: map[string]string{"X-User": "jane"}, actorType: ActorTypeUser, actorID: 3, actorName: "jane"},
+		{name: "api key", headers: map[string]string{"X-Api-Key": "ci"}, actorType: ActorTypeApiKey, actorID: 7, actorName: "ci"},
+		{name: "user over api key", headers: map[string]string{"X-User": "jane", "X-Api-Key": "ci"}, actorType: ActorTypeUser, actorID: 3, actorName: "jane"},
+		{name: "anonymous", actorType: ActorTypeAnonymous},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, "/secrets/token", nil)
+		for key, value := range tt.headers {
+			req.Header.Set(key, value)
+		}
+
+		entry := serve(t, database, r, req)
+
+		if entry.ActorType != tt.actorType || entry.ActorName != tt.actorName {
+			t.Errorf("%s: actor = %s %q, want %s %q", tt.name, entry.ActorType, entry.ActorName, tt.actorType, tt.actorName)
+		}
+
+		if (entry.ActorID == nil) != (tt.actorID == 0) || (entry.ActorID != nil && *entry.ActorID != tt.actorID) {
+			t.Errorf("%s: actor id = %v, want %d", tt.name, entry.ActorID, tt.actorID)
+		}
+	}
+}
+
+func TestRecorderMarksClientErrorsAsFailures(t *testing.T) {
+	database, r := newTestRouter(t)
+
+	entry := serve(t, database, r, httptest.NewRequest(http.MethodDelete, "/secrets/token", nil))
+
+	if entry.Action != "secret.delete" || entry.Outcome != OutcomeFailure || entry.Status != http.StatusNotFound {
+		t.Errorf("entry = %s %s %d, want a failed secret.delete", entry.Action, entry.Outcome, entry.Status)
+	}
+
+	if entry.TargetType != "SECRET" || entry.TargetID != "token" {
+		t.Errorf("target = %s %q, want the route's secret", entry.TargetType, entry.TargetID)
+	}
+}
+
+func TestRecorderSummarizesRecordedVersions(t *testing.T) {
+	database, r := newTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPut, "/monitors/12", nil)
+	req.Header.Set("X-User", "jane")
+
+	entry := serve(t, database, r, req)
+
+	if entry.Outcome != OutcomeSuccess || entry.TargetType != string(history.EntityTypeMonitor) || entry.TargetID != "12" {
+		t.Fatalf("entry = %s on %s %q, want a success on the monitor", entry.Outcome, entry.TargetType, entry.TargetID)
+	}
+
+	var before, after map[string]any
+	if err := json.Unmarshal(entry.Before, &before); err != nil {
+		t.Fatalf("failed to decode before: %v", err)
+	}
+	if err := json.Unmarshal(entry.After, &after); err != nil {
+		t.Fatalf("failed to decode after: %v", err)
+	}
+
+	// Only the changed field is summarized.
+	if len(before) != 1 || before["name"] != "api" || len(after) != 1 || after["name"] != "api v2" {
+		t.Errorf("summary = %v -> %v, want only the name change", before, after)
+	}
+
+	// Versions of a failed request belong to a rolled back change.
+	entry = serve(t, database, r, httptest.NewRequest(http.MethodPut, "/monitors/12?fail=1", nil))
+
+	if entry.Outcome != OutcomeFailure || entry.Before != nil || entry.After != nil {
+		t.Errorf("failed request = %s with %s -> %s, want a failure without summary", entry.Outcome, entry.Before, entry.After)
+	}
+
+	if entry.TargetType != "MONITOR" || entry.TargetID != "12" {
+		t.Errorf("failed request target = %s %q, want the route's monitor", entry.TargetType, entry.TargetID)
+	}
+}
+
+func TestRecorderSkipsUnauditedRoutes(t *testing.T) {
+	database, r := newTestRouter(t)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/monitors", nil))
+
+	var count int64
+	if err := database.Model(&Entry{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count entries: %v", err)
+	}
+
+	if count != 0 {
+		t.Errorf("got %d entries for a route without an action", count)
+	}
+}
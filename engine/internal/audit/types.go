@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"gorm.io/datatypes"
+)
+
+type ActorType string
+
+const (
+	ActorTypeUser      ActorType = "USER"
+	ActorTypeApiKey    ActorType = "API_KEY"
+	ActorTypeAnonymous ActorType = "ANONYMOUS"
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "SUCCESS"
+	OutcomeFailure Outcome = "FAILURE"
+)
+
+// Entry is one management or authentication action. Entries are only ever
+// inserted, and removed once they are older than the retention period.
+type Entry struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	ActorType  ActorType      `gorm:"not null;index:idx_audit_entries_actor,priority:1" json:"actor_type"`
+	ActorID    *uint          `gorm:"index:idx_audit_entries_actor,priority:2" json:"actor_id"`
+	ActorName  string         `gorm:"not null;default:''" json:"actor_name"`
+	Action     string         `gorm:"not null;index" json:"action"`
+	Outcome    Outcome        `gorm:"not null" json:"outcome"`
+	Status     int            `gorm:"not null" json:"status"`
+	TargetType string         `gorm:"not null;default:'';index:idx_audit_entries_target,priority:1" json:"target_type"`
+	TargetID   string         `gorm:"not null;default:'';index:idx_audit_entries_target,priority:2" json:"target_id"`
+	IP         string         `gorm:"not null;default:''" json:"ip"`
+	UserAgent  string         `gorm:"not null;default:''" json:"user_agent"`
+	Before     datatypes.JSON `gorm:"type:json" json:"before"`
+	After      datatypes.JSON `gorm:"type:json" json:"after"`
+	CreatedAt  int64          `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// EntryListQuery filters the audit log. Action matches exactly, or every
+// action under a prefix when it ends in ".*", e.g. "monitor.*".
+type EntryListQuery struct {
+	ActorType  ActorType `form:"actor_type" binding:"omitempty,oneof=USER API_KEY ANONYMOUS"`
+	ActorID    *uint     `form:"actor_id"`
+	Actor      string    `form:"actor"`
+	Action     string    `form:"action"`
+	TargetType string    `form:"target_type"`
+	TargetID   string    `form:"target_id"`
+	Outcome    Outcome   `form:"outcome" binding:"omitempty,oneof=SUCCESS FAILURE"`
+	From       int64     `form:"from" binding:"omitempty,min=0"`
+	To         int64     `form:"to" binding:"omitempty,min=0"`
+	Page       int       `form:"page" binding:"omitempty,min=1"`
+	PerPage    int       `form:"per_page" binding:"omitempty,min=1,max=500"`
+}
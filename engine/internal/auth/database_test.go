@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/apikey"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
+	"github.com/mateusgcoelho/sentinel/engine/internal/database/dbtest"
+	"github.com/mateusgcoelho/sentinel/engine/internal/session"
+	"github.com/mateusgcoelho/sentinel/engine/internal/user"
+	"gorm.io/gorm"
+)
+
+// newTestDatabase opens a test database with the tables the auth package
+// touches.
+func newTestDatabase(tb testing.TB) *gorm.DB {
+	return dbtest.Open(tb,
+		&user.User{},
+		&user.RecoveryCode{},
+		&apikey.ApiKeyConfig{},
+		&audit.Entry{},
+		&session.Session{},
+		&SignInFailure{},
+		&Lockout{},
+	)
+}
@@ -1,12 +1,19 @@
 package auth
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
 	"github.com/mateusgcoelho/sentinel/engine/internal/password"
+	"github.com/mateusgcoelho/sentinel/engine/internal/session"
 	"github.com/mateusgcoelho/sentinel/engine/internal/user"
 	"gorm.io/gorm"
 )
@@ -14,26 +21,49 @@ import (
 type AuthHandler struct {
 	database  *gorm.DB
 	jwtSecret []byte
+	settings  Settings
+	guard     *Guard
 }
 
-func NewHandler(db *gorm.DB, jwtSecret []byte) *AuthHandler {
+func NewHandler(db *gorm.DB, jwtSecret []byte, settings Settings) *AuthHandler {
 	return &AuthHandler{
 		database:  db,
 		jwtSecret: jwtSecret,
+		settings:  settings,
+		guard:     NewGuard(db, clock.System, DefaultLockoutPolicies),
 	}
 }
 
 func (h *AuthHandler) SetupRoutes(r *gin.Engine) {
 	auth := r.Group("/auth")
 	{
-		auth.POST("", h.HandleSignIn)
+		auth.POST("", audit.Action("auth.sign_in"), h.HandleSignIn)
+		auth.GET("/methods", h.HandleSignInMethods)
+		auth.GET("/oidc/login", h.HandleOidcLogin)
+		auth.GET("/oidc/callback", audit.Action("auth.sign_in_oidc"), h.HandleOidcCallback)
 		auth.GET("/me", h.AuthMiddleware(), h.HandleMe)
-		auth.POST("/sign-out", h.AuthMiddleware(), h.HandleSignOut)
+		auth.POST("/sign-out", audit.Action("auth.sign_out"), h.AuthMiddleware(), h.HandleSignOut)
+		auth.POST("/refresh", h.AuthMiddleware(), h.HandleRefresh)
+		auth.GET("/sessions", h.AuthMiddleware(), h.HandleListSessions)
+		auth.DELETE("/sessions", audit.Action("session.revoke_others"), h.AuthMiddleware(), h.HandleRevokeOtherSessions)
+		auth.DELETE("/sessions/:id", audit.Action("session.revoke"), h.AuthMiddleware(), h.HandleRevokeSession)
+		auth.GET("/lockouts", h.AdminMiddleware(), h.HandleListLockouts)
+		auth.DELETE("/lockouts/:id", audit.Action("lockout.unlock"), h.AdminMiddleware(), h.HandleUnlock)
+	}
+
+	twoFactor := auth.Group("/2fa")
+	{
+		twoFactor.GET("", h.AuthMiddleware(), h.HandleTwoFactorStatus)
+		twoFactor.POST("/verify", audit.Action("auth.sign_in_two_factor"), h.HandleVerifyTwoFactor)
+		twoFactor.POST("/enroll", audit.Action("two_factor.enroll"), h.HandleEnrollTwoFactor)
+		twoFactor.POST("/confirm", audit.Action("two_factor.confirm"), h.HandleConfirmTwoFactor)
+		twoFactor.POST("/recovery-codes", audit.Action("two_factor.regenerate_recovery_codes"), h.AuthMiddleware(), h.HandleRegenerateRecoveryCodes)
+		twoFactor.DELETE("", audit.Action("two_factor.disable"), h.AuthMiddleware(), h.HandleDisableTwoFactor)
 	}
 }
 
 func (h *AuthHandler) HandleMe(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "authenticated"})
+	c.JSON(http.StatusOK, gin.H{"message": "authenticated", "data": gin.H{"must_change_password": c.GetBool("must_change_password")}})
 }
 
 func (h *AuthHandler) HandleSignIn(c *gin.Context) {
@@ -43,37 +73,263 @@ func (h *AuthHandler) HandleSignIn(c *gin.Context) {
 		return
 	}
 
+	// Failed attempts are recorded against the username that was tried, as
+	// it may not exist.
+	audit.SetSummary(c, nil, gin.H{"username": req.Username})
+
+	if h.settings.LocalSignInDisabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "local sign-in is disabled, use single sign-on"})
+		return
+	}
+
+	if h.refuseLocked(c, req.Username) {
+		return
+	}
+
 	var user user.User
 	if err := h.database.Where("username = ?", req.Username).First(&user).Error; err != nil {
+		h.signInFailed(c, req.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
 		return
 	}
 
+	audit.SetTarget(c, "USER", user.ID)
+
 	if valid := password.Compare(user.Password, req.Password); !valid {
+		h.signInFailed(c, req.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
 		return
 	}
 
-	signedToken, err := NewJwtToken(fmt.Sprint(user.ID), h.jwtSecret)
+	// With two-factor the password only earns a short-lived pending token;
+	// the session is opened by the second step.
+	if user.TotpEnabled || h.settings.RequireTwoFactor {
+		step := TwoFactorStepVerify
+		if !user.TotpEnabled {
+			step = TwoFactorStepEnroll
+		}
+
+		if err := h.setPendingCookie(c, user, step); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication required", "data": gin.H{"two_factor": step}})
+		return
+	}
+
+	if err := h.beginSession(c, user, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "sign-in successful", "data": gin.H{"must_change_password": user.MustChangePassword}})
+}
+
+// refuseLocked answers 429 when the IP or username is locked out. Locked out
+// attempts are refused before any credential is checked, so guessing on gets
+// nothing until the lockout ends.
+func (h *AuthHandler) refuseLocked(c *gin.Context, username string) bool {
+	lockedUntil, err := h.guard.LockedUntil(c.ClientIP(), username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check sign-in attempts"})
+		return true
+	}
+
+	if lockedUntil > 0 {
+		c.Header("Retry-After", strconv.FormatInt(lockedUntil-clock.System.Now().Unix(), 10))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed sign-in attempts, try again later"})
+		return true
+	}
+
+	return false
+}
+
+// beginSession opens a session for a user whose sign in is complete and sets
+// its token cookie.
+func (h *AuthHandler) beginSession(c *gin.Context, user user.User, twoFactor bool) error {
+	if err := h.guard.RecordSuccess(user.Username); err != nil {
+		log.Printf("[auth] failed to clear sign-in failures of %q: %v", user.Username, err)
+	}
+
+	userSession, err := session.Create(h.database, user.ID, c.ClientIP(), c.Request.UserAgent(), twoFactor)
+	if err != nil {
+		return err
+	}
+
+	if err := h.setTokenCookie(c, userSession); err != nil {
+		return err
+	}
+
+	c.Set("user_id", fmt.Sprint(user.ID))
+	c.Set("username", user.Username)
+
+	return nil
+}
+
+// signInFailed counts a failed attempt against the username and IP, and logs
+// and audits the lockouts it triggers.
+func (h *AuthHandler) signInFailed(c *gin.Context, username string) {
+	lockouts, err := h.guard.RecordFailure(c.ClientIP(), username)
+	if err != nil {
+		log.Printf("[auth] failed to record failed sign-in of %q: %v", username, err)
+	}
+
+	for _, lockout := range lockouts {
+		log.Printf("[auth] locked %s %q until %d after repeated failed sign-ins", lockout.Scope, lockout.Key, lockout.LockedUntil)
+
+		summary, _ := json.Marshal(gin.H{"locked_until": lockout.LockedUntil, "lockouts": lockout.Lockouts})
+		if err := audit.Write(h.database, c, audit.Entry{
+			Action:     "auth.lockout",
+			Outcome:    audit.OutcomeSuccess,
+			Status:     http.StatusUnauthorized,
+			TargetType: string(lockout.Scope),
+			TargetID:   lockout.Key,
+			After:      summary,
+		}); err != nil {
+			log.Printf("[auth] failed to audit lockout of %s %q: %v", lockout.Scope, lockout.Key, err)
+		}
+	}
+}
+
+func (h *AuthHandler) HandleListLockouts(c *gin.Context) {
+	lockouts, err := h.guard.ActiveLockouts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list lockouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": lockouts})
+}
+
+// HandleUnlock lifts a lockout before it ends, e.g. for a user who locked
+// themselves out.
+func (h *AuthHandler) HandleUnlock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lockout id"})
+		return
+	}
+
+	unlocked, err := h.guard.Unlock(uint(id))
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock"})
+		return
+	}
+
+	if !unlocked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "lockout not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "unlocked successfully"})
+}
+
+// HandleRefresh extends the current session to a full idle timeout and
+// reissues its token.
+func (h *AuthHandler) HandleRefresh(c *gin.Context) {
+	userSession := currentSession(c)
+
+	if err := session.Extend(h.database, &userSession); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh session"})
+		return
+	}
+
+	if err := h.setTokenCookie(c, userSession); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"message": "session refreshed", "data": gin.H{"expires_at": userSession.ExpiresAt}})
+}
+
+func (h *AuthHandler) HandleListSessions(c *gin.Context) {
+	current := currentSession(c)
+
+	sessions, err := session.ListActive(h.database, current.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current.ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+func (h *AuthHandler) HandleRevokeSession(c *gin.Context) {
+	current := currentSession(c)
+
+	revoked, err := session.Revoke(h.database, current.UserID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	if !revoked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	if c.Param("id") == current.ID {
+		clearTokenCookie(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+// HandleRevokeOtherSessions signs the user out everywhere but the current
+// session.
+func (h *AuthHandler) HandleRevokeOtherSessions(c *gin.Context) {
+	current := currentSession(c)
+
+	count, err := session.RevokeAll(h.database, current.UserID, current.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	audit.SetTarget(c, "USER", current.UserID)
+	audit.SetSummary(c, nil, gin.H{"revoked": count})
+
+	c.JSON(http.StatusOK, gin.H{"message": "sessions revoked", "data": gin.H{"revoked": count}})
+}
+
+func (h *AuthHandler) HandleSignOut(c *gin.Context) {
+	current := currentSession(c)
+
+	if _, err := session.Revoke(h.database, current.UserID, current.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to end session"})
+		return
+	}
+
+	clearTokenCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{"message": "sign-out successful"})
+}
+
+func (h *AuthHandler) setTokenCookie(c *gin.Context, userSession session.Session) error {
+	signedToken, err := NewJwtToken(fmt.Sprint(userSession.UserID), userSession.ID, userSession.ExpiresAt, h.jwtSecret)
+	if err != nil {
+		return err
+	}
+
 	c.SetCookie(
 		"auth_token",
 		signedToken,
-		86400,
+		int(userSession.ExpiresAt-clock.System.Now().Unix()),
 		"/",
 		"",
 		false,
 		true,
 	)
 
-	c.JSON(http.StatusOK, gin.H{"message": "sign-in successful"})
+	return nil
 }
 
-func (h *AuthHandler) HandleSignOut(c *gin.Context) {
+func clearTokenCookie(c *gin.Context) {
 	c.SetCookie(
 		"auth_token",
 		"",
@@ -83,27 +339,225 @@ func (h *AuthHandler) HandleSignOut(c *gin.Context) {
 		false,
 		true,
 	)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "sign-out successful"})
+func currentSession(c *gin.Context) session.Session {
+	value, _ := c.Get("session")
+	userSession, _ := value.(session.Session)
+
+	return userSession
+}
+
+// authenticate resolves the session behind the request's token, sliding its
+// expiry and reissuing the token while it is in use.
+func (h *AuthHandler) authenticate(c *gin.Context) (session.Session, error) {
+	if value, ok := c.Get("session"); ok {
+		return value.(session.Session), nil
+	}
+
+	tokenStr, err := c.Cookie("auth_token")
+	if err != nil {
+		return session.Session{}, errAuthenticationRequired
+	}
+
+	token, err := validateJwtToken(tokenStr, h.jwtSecret)
+	if err != nil {
+		return session.Session{}, errInvalidToken
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	sessionID, _ := claims["jti"].(string)
+	subject, _ := claims["sub"].(string)
+
+	userSession, err := session.Find(h.database, sessionID)
+	if err != nil {
+		return session.Session{}, err
+	}
+
+	if h.settings.RequireTwoFactor && !userSession.TwoFactor {
+		return session.Session{}, errTwoFactorRequired
+	}
+
+	if subject != strconv.FormatUint(uint64(userSession.UserID), 10) {
+		return session.Session{}, errInvalidToken
+	}
+
+	moved, err := session.Touch(h.database, &userSession)
+	if err != nil {
+		return session.Session{}, err
+	}
+
+	if moved {
+		if err := h.setTokenCookie(c, userSession); err != nil {
+			return session.Session{}, err
+		}
+	}
+
+	c.Set("session", userSession)
+	c.Set("user_id", subject)
+
+	return userSession, nil
+}
+
+// signedIn resolves the session like authenticate and loads its user when
+// IdentifyMiddleware did not. Users who must change their password are
+// refused everything but the requests that let them do so.
+func (h *AuthHandler) signedIn(c *gin.Context) (session.Session, error) {
+	userSession, err := h.authenticate(c)
+	if err != nil {
+		return session.Session{}, err
+	}
+
+	if _, identified := c.Get("must_change_password"); !identified {
+		var account user.User
+		if err := h.database.First(&account, userSession.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return session.Session{}, session.ErrSessionInvalid
+			}
+			return session.Session{}, err
+		}
+
+		identify(c, account)
+	}
+
+	if c.GetBool("must_change_password") && !passwordChangeRequest(c) {
+		return session.Session{}, errPasswordChangeRequired
+	}
+
+	return userSession, nil
 }
 
 func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenStr, err := c.Cookie("auth_token")
+		if _, err := h.signedIn(c); err != nil {
+			abortUnauthenticated(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AccessMiddleware guards the management routes. Requests need a session;
+// users whose role can write may change anything the route offers, other
+// users may only read. API keys only ingest request logs and are refused.
+func (h *AuthHandler) AccessMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := h.sessionRole(c)
+		if err != nil {
+			abortUnauthenticated(c, err)
+			return
+		}
+
+		if !role.CanWrite() && !readOnlyMethod(c.Request.Method) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "your role does not allow changes"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AdminMiddleware keeps a route to signed in administrators. API keys are
+// not accepted.
+func (h *AuthHandler) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := h.sessionRole(c)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication token required"})
+			abortUnauthenticated(c, err)
 			return
 		}
 
-		token, err := validateJwtToken(tokenStr, h.jwtSecret)
+		if role != user.RoleAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "administrator role required"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// sessionRole returns the role of the signed in user.
+func (h *AuthHandler) sessionRole(c *gin.Context) (user.Role, error) {
+	if _, err := h.signedIn(c); err != nil {
+		return "", err
+	}
+
+	return user.Role(c.GetString("role")), nil
+}
+
+func readOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func abortUnauthenticated(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errAuthenticationRequired):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication token required"})
+	case errors.Is(err, errInvalidToken):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authentication token"})
+	case errors.Is(err, session.ErrSessionInvalid):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session expired or revoked"})
+	case errors.Is(err, errTwoFactorRequired):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "two-factor authentication required, sign in again"})
+	case errors.Is(err, errPasswordChangeRequired):
+		abortPasswordChangeRequired(c)
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to validate session"})
+	}
+}
+
+// IdentifyMiddleware sets the signed in user on every request that carries a
+// token of an active session, so changes can be attributed to them. Unlike
+// AuthMiddleware it never rejects a request.
+func (h *AuthHandler) IdentifyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userSession, err := h.authenticate(c)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authentication token"})
+			c.Next()
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		c.Set("user_id", claims["sub"])
+		var account user.User
+		if err := h.database.First(&account, userSession.UserID).Error; err == nil {
+			identify(c, account)
+		}
+
+		c.Next()
+	}
+}
+
+func identify(c *gin.Context, account user.User) {
+	c.Set("username", account.Username)
+	c.Set("role", string(account.Role))
+	c.Set("must_change_password", account.MustChangePassword)
+}
+
+// PasswordChangeMiddleware holds users who must change their password to
+// changing it, checking who they are and signing out. It relies on
+// IdentifyMiddleware running first.
+func (h *AuthHandler) PasswordChangeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("must_change_password") && !passwordChangeRequest(c) {
+			abortPasswordChangeRequired(c)
+			return
+		}
 
 		c.Next()
 	}
 }
+
+// passwordChangeRequest reports whether the request is one a user who must
+// change their password may still make.
+func passwordChangeRequest(c *gin.Context) bool {
+	switch c.Request.Method + " " + c.Request.URL.Path {
+	case "PATCH /users", "GET /auth/me", "POST /auth/sign-out":
+		return true
+	}
+
+	return false
+}
+
+func abortPasswordChangeRequired(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "password change required", "data": gin.H{"must_change_password": true}})
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/apikey"
+	"github.com/mateusgcoelho/sentinel/engine/internal/session"
+	"github.com/mateusgcoelho/sentinel/engine/internal/user"
+	"gorm.io/gorm"
+)
+
+// newTestRouter serves the auth routes and a management route guarded like
+// the other packages guard theirs, behind the global IdentifyMiddleware.
+func newTestRouter(t *testing.T) (*AuthHandler, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	h := NewHandler(newTestDatabase(t), testJwtSecret, Settings{})
+
+	r := gin.New()
+	r.Use(h.IdentifyMiddleware())
+	h.SetupRoutes(r)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/monitors", h.AccessMiddleware(), ok)
+	r.POST("/monitors", h.AccessMiddleware(), ok)
+	r.GET("/keys", h.AdminMiddleware(), ok)
+
+	return h, r
+}
+
+// signIn opens a session for a new user with the role and returns its
+// cookie.
+func signIn(t *testing.T, database *gorm.DB, username string, role user.Role) *http.Cookie {
+	t.Helper()
+
+	account := user.User{Username: username, Role: role}
+	if err := database.Create(&account).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	userSession, err := session.Create(database, account.ID, "127.0.0.1", "test", false)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	token, err := NewJwtToken(fmt.Sprint(account.ID), userSession.ID, userSession.ExpiresAt, testJwtSecret)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	return &http.Cookie{Name: "auth_token", Value: token}
+}
+
+func serve(r *gin.Engine, method string, path string, prepare func(req *http.Request)) int {
+	req := httptest.NewRequest(method, path, nil)
+	if prepare != nil {
+		prepare(req)
+	}
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, req)
+
+	return recorder.Code
+}
+
+func withCookie(cookie *http.Cookie) func(req *http.Request) {
+	return func(req *http.Request) { req.AddCookie(cookie) }
+}
+
+func withApiKey(value string) func(req *http.Request) {
+	return func(req *http.Request) { req.Header.Set(apikey.HeaderName, value) }
+}
+
+func TestAccessMiddlewareRequiresSession(t *testing.T) {
+	h, r := newTestRouter(t)
+
+	// API keys only ingest request logs, even valid ones.
+	if err := h.database.Create(&apikey.ApiKeyConfig{Name: "ci", Value: "heim_valid"}).Error; err != nil {
+		t.Fatalf("failed to create API key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		prepare func(req *http.Request)
+		want    int
+	}{
+		{name: "anonymous read", method: http.MethodGet, want: http.StatusUnauthorized},
+		{name: "anonymous write", method: http.MethodPost, want: http.StatusUnauthorized},
+		{name: "invalid cookie", method: http.MethodPost, prepare: withCookie(&http.Cookie{Name: "auth_token", Value: "forged"}), want: http.StatusUnauthorized},
+		{name: "api key read", method: http.MethodGet, prepare: withApiKey("heim_valid"), want: http.StatusUnauthorized},
+		{name: "api key write", method: http.MethodPost, prepare: withApiKey("heim_valid"), want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		if got := serve(r, tt.method, "/monitors", tt.prepare); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAccessMiddlewareKeepsViewersToReading(t *testing.T) {
+	h, r := newTestRouter(t)
+
+	viewer := signIn(t, h.database, "viewer", user.RoleViewer)
+	admin := signIn(t, h.database, "admin", user.RoleAdmin)
+	unknown := signIn(t, h.database, "unknown", user.RoleViewer)
+
+	// The column defaults to ADMIN, so the empty role is stored afterwards.
+	if err := h.database.Model(&user.User{}).Where("username = ?", "unknown").UpdateColumn("role", "").Error; err != nil {
+		t.Fatalf("failed to clear role: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		cookie *http.Cookie
+		want   int
+	}{
+		{name: "viewer read", method: http.MethodGet, cookie: viewer, want: http.StatusOK},
+		{name: "viewer write", method: http.MethodPost, cookie: viewer, want: http.StatusForbidden},
+		{name: "empty role read", method: http.MethodGet, cookie: unknown, want: http.StatusOK},
+		{name: "empty role write", method: http.MethodPost, cookie: unknown, want: http.StatusForbidden},
+		{name: "admin write", method: http.MethodPost, cookie: admin, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		if got := serve(r, tt.method, "/monitors", withCookie(tt.cookie)); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAdminMiddlewareOnlyLetsAdministratorsIn(t *testing.T) {
+	h, r := newTestRouter(t)
+
+	if err := h.database.Create(&apikey.ApiKeyConfig{Name: "ci", Value: "heim_valid"}).Error; err != nil {
+		t.Fatalf("failed to create API key: %v", err)
+	}
+
+	viewer := signIn(t, h.database, "viewer", user.RoleViewer)
+	admin := signIn(t, h.database, "admin", user.RoleAdmin)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		prepare func(req *http.Request)
+		want    int
+	}{
+		{name: "anonymous", method: http.MethodGet, path: "/keys", want: http.StatusUnauthorized},
+		{name: "api key", method: http.MethodGet, path: "/keys", prepare: withApiKey("heim_valid"), want: http.StatusUnauthorized},
+		{name: "viewer", method: http.MethodGet, path: "/keys", prepare: withCookie(viewer), want: http.StatusForbidden},
+		{name: "admin", method: http.MethodGet, path: "/keys", prepare: withCookie(admin), want: http.StatusOK},
+		{name: "viewer lists lockouts", method: http.MethodGet, path: "/auth/lockouts", prepare: withCookie(viewer), want: http.StatusForbidden},
+		{name: "viewer unlocks", method: http.MethodDelete, path: "/auth/lockouts/1", prepare: withCookie(viewer), want: http.StatusForbidden},
+		{name: "admin lists lockouts", method: http.MethodGet, path: "/auth/lockouts", prepare: withCookie(admin), want: http.StatusOK},
+		{name: "admin unlocks unknown lockout", method: http.MethodDelete, path: "/auth/lockouts/1", prepare: withCookie(admin), want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		if got := serve(r, tt.method, tt.path, tt.prepare); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMiddlewaresEnforcePasswordChange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(newTestDatabase(t), testJwtSecret, Settings{})
+
+	// Without IdentifyMiddleware and PasswordChangeMiddleware in front, the
+	// route middlewares must refuse on their own.
+	r := gin.New()
+	h.SetupRoutes(r)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.PATCH("/users", h.AuthMiddleware(), ok)
+	r.GET("/monitors", h.AccessMiddleware(), ok)
+	r.GET("/keys", h.AdminMiddleware(), ok)
+
+	cookie := signIn(t, h.database, "admin", user.RoleAdmin)
+	if err := h.database.Model(&user.User{}).Where("username = ?", "admin").Update("must_change_password", true).Error; err != nil {
+		t.Fatalf("failed to flag user: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{method: http.MethodGet, path: "/auth/me", want: http.StatusOK},
+		{method: http.MethodPatch, path: "/users", want: http.StatusOK},
+		{method: http.MethodGet, path: "/auth/sessions", want: http.StatusForbidden},
+		{method: http.MethodPost, path: "/auth/2fa/recovery-codes", want: http.StatusForbidden},
+		{method: http.MethodGet, path: "/monitors", want: http.StatusForbidden},
+		{method: http.MethodGet, path: "/keys", want: http.StatusForbidden},
+		{method: http.MethodGet, path: "/auth/lockouts", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		if got := serve(r, tt.method, tt.path, withCookie(cookie)); got != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
@@ -10,10 +10,13 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func NewJwtToken(sub string, jwtSecret []byte) (string, error) {
+// NewJwtToken signs a token for a session. The jti is the session ID, which
+// is checked on every request so the token dies with its session.
+func NewJwtToken(sub string, sessionID string, expiresAt int64, jwtSecret []byte) (string, error) {
 	claims := jwt.MapClaims{
 		"sub": sub,
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"jti": sessionID,
+		"exp": expiresAt,
 		"iat": time.Now().Unix(),
 	}
 
@@ -22,6 +25,25 @@ func NewJwtToken(sub string, jwtSecret []byte) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
+// pendingTokenLifetime is how long a user has to provide the second factor
+// after the password.
+const pendingTokenLifetime = 5 * time.Minute
+
+// newPendingToken signs a token proving the password of sub was checked,
+// which only the two-factor step accepts. It has no session behind it.
+func newPendingToken(sub string, step TwoFactorStep, jwtSecret []byte) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":  sub,
+		"step": string(step),
+		"exp":  time.Now().Add(pendingTokenLifetime).Unix(),
+		"iat":  time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString(jwtSecret)
+}
+
 func randomJwtSecret() ([]byte, error) {
 	const secretLength = 32
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()-_=+[]{}|;:,.<>?/`~"
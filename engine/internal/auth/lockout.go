@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LockoutScope string
+
+const (
+	LockoutScopeUsername LockoutScope = "USERNAME"
+	LockoutScopeIP       LockoutScope = "IP"
+)
+
+// failureRetention is how long failed sign-in attempts are kept.
+const failureRetention = 30 * 24 * time.Hour
+
+// maxLockoutDoublings keeps the exponential lockout from overflowing; the
+// duration is capped well before it anyway.
+const maxLockoutDoublings = 20
+
+// SignInFailure is one failed sign-in attempt.
+type SignInFailure struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Username  string `gorm:"not null;index" json:"username"`
+	IP        string `gorm:"not null;index" json:"ip"`
+	CreatedAt int64  `gorm:"not null;index" json:"created_at"`
+}
+
+// Lockout tracks failed sign-ins for one username or IP. Failures counts the
+// attempts of the current window and Lockouts how many times in a row the key
+// was locked, which doubles the next lockout.
+type Lockout struct {
+	ID              uint         `gorm:"primaryKey" json:"id"`
+	Scope           LockoutScope `gorm:"not null;uniqueIndex:idx_lockouts_scope_key,priority:1" json:"scope"`
+	Key             string       `gorm:"not null;uniqueIndex:idx_lockouts_scope_key,priority:2" json:"key"`
+	Failures        int          `gorm:"not null;default:0" json:"failures"`
+	WindowStartedAt int64        `gorm:"not null;default:0" json:"window_started_at"`
+	Lockouts        int          `gorm:"not null;default:0" json:"lockouts"`
+	LockedUntil     int64        `gorm:"not null;default:0" json:"locked_until"`
+	LastFailureAt   int64        `gorm:"not null;default:0" json:"last_failure_at"`
+}
+
+// LockoutPolicy locks a key for BaseLockout once it reaches MaxFailures
+// within Window, doubling with every further lockout up to MaxLockout. A key
+// without failures for ResetAfter starts over.
+type LockoutPolicy struct {
+	MaxFailures int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	ResetAfter  time.Duration
+}
+
+// DefaultLockoutPolicies allow a handful of guesses per username, and more
+// per IP so several people behind the same address can still mistype.
+var DefaultLockoutPolicies = map[LockoutScope]LockoutPolicy{
+	LockoutScopeUsername: {
+		MaxFailures: 5,
+		Window:      15 * time.Minute,
+		BaseLockout: time.Minute,
+		MaxLockout:  24 * time.Hour,
+		ResetAfter:  24 * time.Hour,
+	},
+	LockoutScopeIP: {
+		MaxFailures: 20,
+		Window:      15 * time.Minute,
+		BaseLockout: time.Minute,
+		MaxLockout:  24 * time.Hour,
+		ResetAfter:  24 * time.Hour,
+	},
+}
+
+// Guard rate limits sign-ins per username and per IP.
+type Guard struct {
+	database *gorm.DB
+	clock    clock.Clock
+	policies map[LockoutScope]LockoutPolicy
+}
+
+func NewGuard(db *gorm.DB, clk clock.Clock, policies map[LockoutScope]LockoutPolicy) *Guard {
+	return &Guard{
+		database: db,
+		clock:    clk,
+		policies: policies,
+	}
+}
+
+// LockedUntil returns until when sign-ins from the IP or for the username are
+// refused, or zero when neither is locked.
+func (g *Guard) LockedUntil(ip string, username string) (int64, error) {
+	var lockedUntil int64
+	err := g.database.Model(&Lockout{}).
+		Select("COALESCE(MAX(locked_until), 0)").
+		Where("(scope = ? AND key = ?) OR (scope = ? AND key = ?)", LockoutScopeIP, ip, LockoutScopeUsername, usernameKey(username)).
+		Where("locked_until > ?", g.clock.Now().Unix()).
+		Scan(&lockedUntil).Error
+
+	return lockedUntil, err
+}
+
+// RecordFailure stores a failed attempt and counts it against the username
+// and the IP. It returns the lockouts the attempt triggered.
+func (g *Guard) RecordFailure(ip string, username string) ([]Lockout, error) {
+	var engaged []Lockout
+
+	err := g.database.Transaction(func(tx *gorm.DB) error {
+		now := g.clock.Now()
+
+		if err := tx.Create(&SignInFailure{Username: username, IP: ip, CreatedAt: now.Unix()}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("created_at < ?", now.Add(-failureRetention).Unix()).Delete(&SignInFailure{}).Error; err != nil {
+			return err
+		}
+
+		if err := g.pruneLockouts(tx, now); err != nil {
+			return err
+		}
+
+		for scope, key := range map[LockoutScope]string{LockoutScopeUsername: usernameKey(username), LockoutScopeIP: ip} {
+			lockout, locked, err := g.countFailure(tx, scope, key, now)
+			if err != nil {
+				return err
+			}
+			if locked {
+				engaged = append(engaged, lockout)
+			}
+		}
+
+		return nil
+	})
+
+	return engaged, err
+}
+
+// countFailure counts the failure against the key in place, so concurrent
+// failures cannot overwrite each other's count, and engages the lockout when
+// the count reaches the policy's maximum.
+func (g *Guard) countFailure(tx *gorm.DB, scope LockoutScope, key string, now time.Time) (Lockout, bool, error) {
+	policy := g.policies[scope]
+	lockout := Lockout{Scope: scope, Key: key}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&lockout).Error; err != nil {
+		return lockout, false, err
+	}
+
+	windowStartedBefore := now.Add(-policy.Window).Unix()
+	if err := tx.Model(&Lockout{}).Where("scope = ? AND key = ?", scope, key).Updates(map[string]any{
+		"lockouts":          gorm.Expr("CASE WHEN last_failure_at > 0 AND last_failure_at <= ? THEN 0 ELSE lockouts END", now.Add(-policy.ResetAfter).Unix()),
+		"failures":          gorm.Expr("CASE WHEN window_started_at <= ? THEN 1 ELSE failures + 1 END", windowStartedBefore),
+		"window_started_at": gorm.Expr("CASE WHEN window_started_at <= ? THEN ? ELSE window_started_at END", windowStartedBefore, now.Unix()),
+		"last_failure_at":   now.Unix(),
+	}).Error; err != nil {
+		return lockout, false, err
+	}
+
+	if err := tx.Where("scope = ? AND key = ?", scope, key).First(&lockout).Error; err != nil {
+		return lockout, false, err
+	}
+
+	if policy.MaxFailures <= 0 || lockout.Failures < policy.MaxFailures {
+		return lockout, false, nil
+	}
+
+	// Only the failure that still sees the full count engages the lockout.
+	lockedUntil := now.Add(lockoutDuration(policy, lockout.Lockouts+1)).Unix()
+	engaged := tx.Model(&Lockout{}).Where("id = ? AND failures >= ?", lockout.ID, policy.MaxFailures).Updates(map[string]any{
+		"lockouts":          gorm.Expr("lockouts + 1"),
+		"locked_until":      lockedUntil,
+		"failures":          0,
+		"window_started_at": now.Unix(),
+	})
+	if engaged.Error != nil || engaged.RowsAffected == 0 {
+		return lockout, false, engaged.Error
+	}
+
+	lockout.Lockouts++
+	lockout.LockedUntil = lockedUntil
+	lockout.Failures = 0
+	lockout.WindowStartedAt = now.Unix()
+
+	return lockout, true, nil
+}
+
+func lockoutDuration(policy LockoutPolicy, lockouts int) time.Duration {
+	duration := policy.BaseLockout << min(lockouts-1, maxLockoutDoublings)
+
+	return min(duration, policy.MaxLockout)
+}
+
+// pruneLockouts deletes the lockouts that have expired and that the next
+// failure would start over, which is what a missing row does too.
+func (g *Guard) pruneLockouts(tx *gorm.DB, now time.Time) error {
+	for scope, policy := range g.policies {
+		idleBefore := now.Add(-max(policy.Window, policy.ResetAfter)).Unix()
+
+		if err := tx.
+			Where("scope = ? AND locked_until <= ? AND last_failure_at <= ?", scope, now.Unix(), idleBefore).
+			Delete(&Lockout{}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the failures of a username after it signed in.
+// Failures of the IP are left to expire, so one valid account cannot be used
+// to keep guessing others.
+func (g *Guard) RecordSuccess(username string) error {
+	return g.database.Where("scope = ? AND key = ?", LockoutScopeUsername, usernameKey(username)).Delete(&Lockout{}).Error
+}
+
+// ActiveLockouts returns the usernames and IPs that are currently locked.
+func (g *Guard) ActiveLockouts() ([]Lockout, error) {
+	lockouts := []Lockout{}
+	err := g.database.
+		Where("locked_until > ?", g.clock.Now().Unix()).
+		Order("locked_until DESC").
+		Find(&lockouts).Error
+
+	return lockouts, err
+}
+
+// Unlock lifts a lockout and forgets its failures, reporting whether it
+// existed.
+func (g *Guard) Unlock(id uint) (bool, error) {
+	tx := g.database.Delete(&Lockout{}, id)
+
+	return tx.RowsAffected > 0, tx.Error
+}
+
+// usernameKey ignores case and surrounding spaces so variations of a username
+// count against the same lockout.
+func usernameKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"gorm.io/gorm"
+)
+
+const testIP = "203.0.113.7"
+
+var lockoutStart = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// testLockoutPolicies lock a username on the third failure within ten
+// minutes, for a minute doubling up to four. The IP never locks unless a
+// test asks for it.
+func testLockoutPolicies() map[LockoutScope]LockoutPolicy {
+	return map[LockoutScope]LockoutPolicy{
+		LockoutScopeUsername: {
+			MaxFailures: 3,
+			Window:      10 * time.Minute,
+			BaseLockout: time.Minute,
+			MaxLockout:  4 * time.Minute,
+			ResetAfter:  time.Hour,
+		},
+		LockoutScopeIP: {},
+	}
+}
+
+// testGuard is a Guard on a clock the test moves forward.
+type testGuard struct {
+	*Guard
+	now time.Time
+}
+
+func newTestGuard(t *testing.T, policies map[LockoutScope]LockoutPolicy) *testGuard {
+	t.Helper()
+
+	guard := &testGuard{now: lockoutStart}
+	guard.Guard = NewGuard(newTestDatabase(t), clock.Func(func() time.Time { return guard.now }), policies)
+
+	return guard
+}
+
+func (g *testGuard) advance(d time.Duration) {
+	g.now = g.now.Add(d)
+}
+
+func (g *testGuard) fail(t *testing.T, times int, username string) []Lockout {
+	t.Helper()
+
+	var engaged []Lockout
+	for range times {
+		lockouts, err := g.RecordFailure(testIP, username)
+		if err != nil {
+			t.Fatalf("RecordFailure failed: %v", err)
+		}
+		engaged = append(engaged, lockouts...)
+	}
+
+	return engaged
+}
+
+func (g *testGuard) lockedFor(t *testing.T, username string) time.Duration {
+	t.Helper()
+
+	lockedUntil, err := g.LockedUntil(testIP, username)
+	if err != nil {
+		t.Fatalf("LockedUntil failed: %v", err)
+	}
+
+	if lockedUntil == 0 {
+		return 0
+	}
+
+	return time.Unix(lockedUntil, 0).Sub(g.now)
+}
+
+func TestGuardLocksAfterMaxFailuresInWindow(t *testing.T) {
+	guard := newTestGuard(t, testLockoutPolicies())
+
+	if engaged := guard.fail(t, 2, "jane"); len(engaged) != 0 {
+		t.Fatalf("locked after 2 failures: %+v", engaged)
+	}
+
+	engaged := guard.fail(t, 1, "jane")
+	if len(engaged) != 1 || engaged[0].Scope != LockoutScopeUsername || engaged[0].Key != "jane" {
+		t.Fatalf("engaged = %+v, want the username locked", engaged)
+	}
+
+	if locked := guard.lockedFor(t, "jane"); locked != time.Minute {
+		t.Errorf("locked for %s, want 1m", locked)
+	}
+
+	// Variations of the username share the lockout.
+	if locked := guard.lockedFor(t, " JANE "); locked != time.Minute {
+		t.Errorf("variation locked for %s, want 1m", locked)
+	}
+
+	if locked := guard.lockedFor(t, "john"); locked != 0 {
+		t.Errorf("another username is locked for %s", locked)
+	}
+}
+
+func TestGuardStartsNewWindowAfterItEnds(t *testing.T) {
+	guard := newTestGuard(t, testLockoutPolicies())
+
+	guard.fail(t, 2, "jane")
+	guard.advance(10 * time.Minute)
+
+	if engaged := guard.fail(t, 2, "jane"); len(engaged) != 0 {
+		t.Fatalf("failures of an ended window were counted: %+v", engaged)
+	}
+
+	if engaged := guard.fail(t, 1, "jane"); len(engaged) != 1 {
+		t.Fatalf("expected the third failure of the new window to lock")
+	}
+}
+
+func TestGuardDoublesLockoutsUpToMax(t *testing.T) {
+	guard := newTestGuard(t, testLockoutPolicies())
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		guard.fail(t, 3, "jane")
+
+		if locked := guard.lockedFor(t, "jane"); locked != want {
+			t.Fatalf("locked for %s, want %s", locked, want)
+		}
+
+		guard.advance(want)
+
+		if locked := guard.lockedFor(t, "jane"); locked != 0 {
+			t.Fatalf("still locked for %s after the lockout ended", locked)
+		}
+	}
+}
+
+func TestGuardForgetsLockoutsAfterResetAfter(t *testing.T) {
+	guard := newTestGuard(t, testLockoutPolicies())
+
+	guard.fail(t, 3, "jane")
+	guard.advance(time.Minute)
+	guard.fail(t, 3, "jane")
+
+	if locked := guard.lockedFor(t, "jane"); locked != 2*time.Minute {
+		t.Fatalf("locked for %s, want 2m", locked)
+	}
+
+	guard.advance(time.Hour)
+	guard.fail(t, 3, "jane")
+
+	if locked := guard.lockedFor(t, "jane"); locked != time.Minute {
+		t.Errorf("locked for %s after an hour without failures, want 1m", locked)
+	}
+}
+
+func TestGuardRecordSuccessClearsUsernameOnly(t *testing.T) {
+	policies := testLockoutPolicies()
+	policies[LockoutScopeIP] = LockoutPolicy{MaxFailures: 4, Window: 10 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Minute, ResetAfter: time.Hour}
+	guard := newTestGuard(t, policies)
+
+	guard.fail(t, 2, "jane")
+
+	if err := guard.RecordSuccess("Jane"); err != nil {
+		t.Fatalf("RecordSuccess failed: %v", err)
+	}
+
+	if engaged := guard.fail(t, 1, "jane"); len(engaged) != 0 {
+		t.Fatalf("failures before the successful sign-in were counted: %+v", engaged)
+	}
+
+	// The IP kept counting: 2 before and 1 after the success, so the next
+	// failure locks it.
+	engaged := guard.fail(t, 1, "john")
+	if len(engaged) != 1 || engaged[0].Scope != LockoutScopeIP {
+		t.Fatalf("engaged = %+v, want the IP locked", engaged)
+	}
+}
+
+func TestGuardUnlock(t *testing.T) {
+	guard := newTestGuard(t, testLockoutPolicies())
+
+	guard.fail(t, 3, "jane")
+
+	lockouts, err := guard.ActiveLockouts()
+	if err != nil {
+		t.Fatalf("ActiveLockouts failed: %v", err)
+	}
+
+	if len(lockouts) != 1 {
+		t.Fatalf("got %d active lockouts, want 1", len(lockouts))
+	}
+
+	unlocked, err := guard.Unlock(lockouts[0].ID)
+	if err != nil || !unlocked {
+		t.Fatalf("Unlock = %t, %v", unlocked, err)
+	}
+
+	if locked := guard.lockedFor(t, "jane"); locked != 0 {
+		t.Errorf("still locked for %s after unlocking", locked)
+	}
+
+	// The failures went with the lockout.
+	if engaged := guard.fail(t, 2, "jane"); len(engaged) != 0 {
+		t.Errorf("failures from before the unlock were counted: %+v", engaged)
+	}
+
+	if unlocked, err := guard.Unlock(lockouts[0].ID); err != nil || unlocked {
+		t.Errorf("second Unlock = %t, %v, want false", unlocked, err)
+	}
+}
+
+func TestGuardCountsConcurrentFailures(t *testing.T) {
+	guard := newTestGuard(t, testLockoutPolicies())
+
+	guard.fail(t, 1, "jane")
+
+	// Another sign-in fails for the same username while this one is counted.
+	concurrent := false
+	guard.database.Callback().Update().Before("gorm:update").Register("test:concurrent", func(tx *gorm.DB) {
+		if concurrent || tx.Statement.Table != "lockouts" {
+			return
+		}
+		concurrent = true
+
+		tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+			Exec("UPDATE lockouts SET failures = failures + 1 WHERE scope = ? AND key = ?", LockoutScopeUsername, "jane")
+	})
+
+	if engaged := guard.fail(t, 1, "jane"); len(engaged) != 1 {
+		t.Fatalf("engaged = %+v, want the third failure to lock", engaged)
+	}
+}
+
+func TestGuardPrunesStaleLockouts(t *testing.T) {
+	guard := newTestGuard(t, testLockoutPolicies())
+
+	guard.fail(t, 3, "jane")
+	guard.fail(t, 1, "john")
+	guard.advance(time.Hour)
+	guard.fail(t, 1, "ann")
+
+	var keys []string
+	if err := guard.database.Model(&Lockout{}).Where("scope = ?", LockoutScopeUsername).Order("key ASC").Pluck("key", &keys).Error; err != nil {
+		t.Fatalf("failed to list lockouts: %v", err)
+	}
+
+	if !slices.Equal(keys, []string{"ann"}) {
+		t.Errorf("lockouts = %q, want only the recent one", keys)
+	}
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
+	"github.com/mateusgcoelho/sentinel/engine/internal/oidc"
+	"github.com/mateusgcoelho/sentinel/engine/internal/user"
+	"gorm.io/gorm"
+)
+
+const (
+	flowCookie   = "oidc_flow"
+	flowLifetime = 10 * time.Minute
+)
+
+// HandleSignInMethods tells the sign in page which methods to offer.
+func (h *AuthHandler) HandleSignInMethods(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"local": !h.settings.LocalSignInDisabled,
+		"oidc":  h.settings.Oidc != nil,
+	}})
+}
+
+// HandleOidcLogin sends the browser to the identity provider. The state,
+// nonce and PKCE verifier travel in a signed cookie until the callback.
+func (h *AuthHandler) HandleOidcLogin(c *gin.Context) {
+	if h.settings.Oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "single sign-on is not configured"})
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.NewVerifier()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start single sign-on"})
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := h.settings.Oidc.Provider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("[auth] failed to start single sign-on: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider is unavailable"})
+		return
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"step":     "OIDC",
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"exp":      time.Now().Add(flowLifetime).Unix(),
+	}).SignedString(h.jwtSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start single sign-on"})
+		return
+	}
+
+	c.SetCookie(
+		flowCookie,
+		token,
+		int(flowLifetime.Seconds()),
+		"/",
+		"",
+		false,
+		true,
+	)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// HandleOidcCallback finishes single sign-on: it exchanges the code, maps
+// the user's groups to a role, provisions the user on their first sign in
+// and opens a session.
+func (h *AuthHandler) HandleOidcCallback(c *gin.Context) {
+	if h.settings.Oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "single sign-on is not configured"})
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("identity provider refused the sign-in: %s", providerError)})
+		return
+	}
+
+	flow, err := h.readFlow(c)
+	if err != nil || c.Query("state") != flow["state"] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "single sign-on expired or was not started here, try again"})
+		return
+	}
+
+	c.SetCookie(flowCookie, "", -1, "/", "", false, true)
+
+	claims, err := h.settings.Oidc.Provider.Exchange(c.Request.Context(), c.Query("code"), flow["verifier"], flow["nonce"])
+	if err != nil {
+		log.Printf("[auth] single sign-on failed: %v", err)
+		if errors.Is(err, oidc.ErrInvalidToken) || errors.Is(err, oidc.ErrExchange) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "identity provider sign-in could not be verified"})
+			return
+		}
+
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider is unavailable"})
+		return
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "identity provider sign-in could not be verified"})
+		return
+	}
+
+	username := firstClaim(claims, "preferred_username", "email", "sub")
+	audit.SetSummary(c, nil, gin.H{"username": username, "subject": subject})
+
+	role := h.mapRole(oidc.StringList(claims, h.settings.Oidc.GroupsClaim))
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "your identity provider groups do not grant access to Sentinel"})
+		return
+	}
+
+	account, err := h.provisionUser(subject, username, role)
+	if err != nil {
+		if errors.Is(err, errUsernameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("a local user named %q already exists", username)})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to provision user"})
+		return
+	}
+
+	audit.SetTarget(c, "USER", account.ID)
+
+	// Second factors are up to the identity provider, so the session
+	// satisfies a two-factor requirement.
+	if err := h.beginSession(c, account, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, h.settings.Oidc.PostLoginURL)
+}
+
+func (h *AuthHandler) readFlow(c *gin.Context) (map[string]string, error) {
+	tokenStr, err := c.Cookie(flowCookie)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := validateJwtToken(tokenStr, h.jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if step, _ := claims["step"].(string); step != "OIDC" {
+		return nil, errInvalidToken
+	}
+
+	flow := map[string]string{}
+	for _, name := range []string{"state", "nonce", "verifier"} {
+		value, _ := claims[name].(string)
+		if value == "" {
+			return nil, errInvalidToken
+		}
+		flow[name] = value
+	}
+
+	return flow, nil
+}
+
+// mapRole returns the most privileged role the groups map to, falling back
+// to the default role.
+func (h *AuthHandler) mapRole(groups []string) user.Role {
+	role := h.settings.Oidc.DefaultRole
+
+	for _, group := range groups {
+		mapped, ok := h.settings.Oidc.RoleMapping[group]
+		if ok && (role == "" || slices.Index(user.Roles, mapped) > slices.Index(user.Roles, role)) {
+			role = mapped
+		}
+	}
+
+	return role
+}
+
+// provisionUser returns the user linked to the subject, creating it on the
+// first sign in. The role follows the identity provider on every sign in.
+// Local users are never linked by name, so an identity provider account
+// cannot take one over.
+func (h *AuthHandler) provisionUser(subject string, username string, role user.Role) (user.User, error) {
+	var account user.User
+
+	err := h.database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("oidc_subject = ?", subject).First(&account).Error
+		if err == nil {
+			return tx.Model(&account).UpdateColumn("role", role).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&user.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errUsernameTaken
+		}
+
+		account = user.User{
+			Username:    username,
+			Role:        role,
+			OidcSubject: &subject,
+		}
+		if err := tx.Create(&account).Error; err != nil {
+			return err
+		}
+
+		log.Printf("[auth] provisioned user %q with role %s through single sign-on", username, role)
+
+		return nil
+	})
+
+	return account, err
+}
+
+func firstClaim(claims jwt.MapClaims, names ...string) string {
+	for _, name := range names {
+		if value, _ := claims[name].(string); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
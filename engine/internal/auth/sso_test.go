@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/user"
+)
+
+func newSsoHandler(t *testing.T, defaultRole user.Role) *AuthHandler {
+	t.Helper()
+
+	return NewHandler(newTestDatabase(t), testJwtSecret, Settings{
+		Oidc: &OidcSettings{
+			GroupsClaim: "groups",
+			RoleMapping: map[string]user.Role{
+				"sentinel-admins":  user.RoleAdmin,
+				"sentinel-viewers": user.RoleViewer,
+			},
+			DefaultRole: defaultRole,
+		},
+	})
+}
+
+func TestMapRolePicksMostPrivilegedRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultRole user.Role
+		groups      []string
+		want        user.Role
+	}{
+		{name: "admin after viewer", groups: []string{"sentinel-viewers", "sentinel-admins"}, want: user.RoleAdmin},
+		{name: "admin before viewer", groups: []string{"sentinel-admins", "sentinel-viewers"}, want: user.RoleAdmin},
+		{name: "viewer", groups: []string{"engineering", "sentinel-viewers"}, want: user.RoleViewer},
+		{name: "unmapped without default", groups: []string{"engineering"}, want: ""},
+		{name: "unmapped with default", defaultRole: user.RoleViewer, groups: []string{"engineering"}, want: user.RoleViewer},
+		{name: "group above default", defaultRole: user.RoleViewer, groups: []string{"sentinel-admins"}, want: user.RoleAdmin},
+	}
+
+	for _, tt := range tests {
+		h := newSsoHandler(t, tt.defaultRole)
+
+		if got := h.mapRole(tt.groups); got != tt.want {
+			t.Errorf("%s: role = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProvisionUserCreatesAndUpdatesRole(t *testing.T) {
+	h := newSsoHandler(t, "")
+
+	created, err := h.provisionUser("subject-1", "jane", user.RoleViewer)
+	if err != nil {
+		t.Fatalf("first sign in failed: %v", err)
+	}
+
+	if created.ID == 0 || created.Role != user.RoleViewer || created.Password != "" {
+		t.Fatalf("provisioned user = %+v, want a viewer without a password", created)
+	}
+
+	// The username may change at the identity provider, the subject links
+	// the account.
+	again, err := h.provisionUser("subject-1", "jane.doe", user.RoleAdmin)
+	if err != nil {
+		t.Fatalf("second sign in failed: %v", err)
+	}
+
+	if again.ID != created.ID {
+		t.Errorf("second sign in provisioned user %d, want %d", again.ID, created.ID)
+	}
+
+	var stored user.User
+	if err := h.database.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("failed to reload user: %v", err)
+	}
+
+	if stored.Role != user.RoleAdmin {
+		t.Errorf("role = %s, want the identity provider's %s", stored.Role, user.RoleAdmin)
+	}
+
+	var count int64
+	h.database.Model(&user.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d users exist, want 1", count)
+	}
+}
+
+func TestProvisionUserRefusesLocalUsername(t *testing.T) {
+	h := newSsoHandler(t, "")
+
+	local := user.User{Username: "admin", Password: "hash", Role: user.RoleAdmin}
+	if err := h.database.Create(&local).Error; err != nil {
+		t.Fatalf("failed to create local user: %v", err)
+	}
+
+	if _, err := h.provisionUser("attacker", "admin", user.RoleViewer); !errors.Is(err, errUsernameTaken) {
+		t.Fatalf("expected errUsernameTaken, got %v", err)
+	}
+
+	var stored user.User
+	if err := h.database.First(&stored, local.ID).Error; err != nil {
+		t.Fatalf("failed to reload user: %v", err)
+	}
+
+	if stored.OidcSubject != nil || stored.Role != user.RoleAdmin {
+		t.Errorf("local user was taken over: %+v", stored)
+	}
+}
@@ -0,0 +1,446 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"github.com/mateusgcoelho/sentinel/engine/internal/password"
+	"github.com/mateusgcoelho/sentinel/engine/internal/totp"
+	"github.com/mateusgcoelho/sentinel/engine/internal/user"
+	"gorm.io/gorm"
+)
+
+const (
+	pendingCookie     = "mfa_token"
+	totpIssuer        = "Sentinel"
+	recoveryCodeCount = 10
+)
+
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+func (h *AuthHandler) HandleTwoFactorStatus(c *gin.Context) {
+	var account user.User
+	if err := h.database.First(&account, currentSession(c).UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	var remaining int64
+	if err := h.database.Model(&user.RecoveryCode{}).
+		Where("user_id = ? AND used_at = 0", account.ID).
+		Count(&remaining).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"enabled":                  account.TotpEnabled,
+		"required":                 h.settings.RequireTwoFactor,
+		"recovery_codes_remaining": remaining,
+	}})
+}
+
+// HandleVerifyTwoFactor completes a sign in that is waiting for a TOTP or
+// recovery code. Wrong codes count against the same lockouts as passwords.
+func (h *AuthHandler) HandleVerifyTwoFactor(c *gin.Context) {
+	var req VerifyTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	account, err := h.pendingUser(c, TwoFactorStepVerify)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "two-factor sign-in expired, sign in again"})
+		return
+	}
+
+	audit.SetTarget(c, "USER", account.ID)
+
+	if h.refuseLocked(c, account.Username) {
+		return
+	}
+
+	var valid bool
+	if req.RecoveryCode != "" {
+		valid, err = useRecoveryCode(h.database, account.ID, req.RecoveryCode)
+	} else {
+		valid, err = h.checkTotp(&account, req.Code)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify code"})
+		return
+	}
+
+	if !valid {
+		h.signInFailed(c, account.Username)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid two-factor code"})
+		return
+	}
+
+	if req.RecoveryCode != "" {
+		audit.SetSummary(c, nil, gin.H{"recovery_code": "used"})
+	}
+
+	if err := h.beginSession(c, account, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+		return
+	}
+
+	clearPendingCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{"message": "sign-in successful", "data": gin.H{"must_change_password": account.MustChangePassword}})
+}
+
+// HandleEnrollTwoFactor starts enrollment with a new secret. It is open to
+// signed in users and to users whose sign in requires them to enroll.
+func (h *AuthHandler) HandleEnrollTwoFactor(c *gin.Context) {
+	account, _, err := h.enrollingUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication token required"})
+		return
+	}
+
+	audit.SetTarget(c, "USER", account.ID)
+
+	if account.TotpEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+		return
+	}
+
+	if err := h.database.Model(&account).UpdateColumns(map[string]any{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(totpIssuer, account.Username, secret),
+	}})
+}
+
+// HandleConfirmTwoFactor enables two-factor once a code from the enrolled
+// secret checks out, and returns the recovery codes, which are never shown
+// again. Users enrolling during sign in get their session here.
+func (h *AuthHandler) HandleConfirmTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	account, signingIn, err := h.enrollingUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication token required"})
+		return
+	}
+
+	audit.SetTarget(c, "USER", account.ID)
+
+	if account.TotpEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is already enabled"})
+		return
+	}
+
+	if account.TotpSecret == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor enrollment was not started"})
+		return
+	}
+
+	valid, err := h.checkTotp(&account, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify code"})
+		return
+	}
+
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid two-factor code"})
+		return
+	}
+
+	var codes []string
+	err = h.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account).UpdateColumn("totp_enabled", true).Error; err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(tx, account.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enable two-factor authentication"})
+		return
+	}
+
+	if signingIn {
+		if err := h.beginSession(c, account, true); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
+			return
+		}
+
+		clearPendingCookie(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "two-factor authentication enabled",
+		"data":    gin.H{"recovery_codes": codes},
+	})
+}
+
+// HandleRegenerateRecoveryCodes replaces every recovery code, used or not.
+func (h *AuthHandler) HandleRegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var account user.User
+	if err := h.database.First(&account, currentSession(c).UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	audit.SetTarget(c, "USER", account.ID)
+
+	if !account.TotpEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is not enabled"})
+		return
+	}
+
+	valid, err := h.checkTotp(&account, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify code"})
+		return
+	}
+
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid two-factor code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(h.database, account.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"recovery_codes": codes}})
+}
+
+func (h *AuthHandler) HandleDisableTwoFactor(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	var account user.User
+	if err := h.database.First(&account, currentSession(c).UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	audit.SetTarget(c, "USER", account.ID)
+
+	if h.settings.RequireTwoFactor {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is required for every user"})
+		return
+	}
+
+	if !password.Compare(account.Password, req.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid password"})
+		return
+	}
+
+	err := h.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account).UpdateColumns(map[string]any{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", account.ID).Delete(&user.RecoveryCode{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// checkTotp accepts a code from the user's secret that is newer than the last
+// accepted one.
+func (h *AuthHandler) checkTotp(account *user.User, code string) (bool, error) {
+	step, valid := totp.Validate(account.TotpSecret, strings.TrimSpace(code), clock.System.Now())
+	if !valid || step <= account.TotpLastStep {
+		return false, nil
+	}
+
+	// The condition keeps two requests racing with the same code from both
+	// succeeding.
+	tx := h.database.Model(&user.User{}).
+		Where("id = ? AND totp_last_step < ?", account.ID, step).
+		UpdateColumn("totp_last_step", step)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+
+	account.TotpLastStep = step
+
+	return tx.RowsAffected > 0, nil
+}
+
+func (h *AuthHandler) setPendingCookie(c *gin.Context, account user.User, step TwoFactorStep) error {
+	token, err := newPendingToken(fmt.Sprint(account.ID), step, h.jwtSecret)
+	if err != nil {
+		return err
+	}
+
+	c.SetCookie(
+		pendingCookie,
+		token,
+		int(pendingTokenLifetime.Seconds()),
+		"/",
+		"",
+		false,
+		true,
+	)
+
+	return nil
+}
+
+func clearPendingCookie(c *gin.Context) {
+	c.SetCookie(
+		pendingCookie,
+		"",
+		-1,
+		"/",
+		"",
+		false,
+		true,
+	)
+}
+
+// pendingUser returns the user whose password was checked by a sign in that
+// is now waiting for the given two-factor step.
+func (h *AuthHandler) pendingUser(c *gin.Context, step TwoFactorStep) (user.User, error) {
+	var account user.User
+
+	tokenStr, err := c.Cookie(pendingCookie)
+	if err != nil {
+		return account, errAuthenticationRequired
+	}
+
+	token, err := validateJwtToken(tokenStr, h.jwtSecret)
+	if err != nil {
+		return account, errInvalidToken
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if claimed, _ := claims["step"].(string); claimed != string(step) {
+		return account, errInvalidToken
+	}
+
+	if err := h.database.First(&account, claims["sub"]).Error; err != nil {
+		return account, err
+	}
+
+	// A pending token outlives neither a change of enrollment nor one made
+	// by another sign in.
+	if account.TotpEnabled != (step == TwoFactorStepVerify) {
+		return account, errInvalidToken
+	}
+
+	return account, nil
+}
+
+// enrollingUser returns the signed in user, or the user whose sign in
+// requires enrolling first, in which case signingIn is set.
+func (h *AuthHandler) enrollingUser(c *gin.Context) (account user.User, signingIn bool, err error) {
+	if userSession, err := h.authenticate(c); err == nil {
+		err = h.database.First(&account, userSession.UserID).Error
+		return account, false, err
+	}
+
+	account, err = h.pendingUser(c, TwoFactorStepEnroll)
+	if err != nil {
+		return account, false, err
+	}
+
+	c.Set("user_id", fmt.Sprint(account.ID))
+	c.Set("username", account.Username)
+
+	return account, true, nil
+}
+
+// replaceRecoveryCodes issues a new set of recovery codes and returns them in
+// plain text. They carry enough randomness for a fast hash to be safe.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&user.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]user.RecoveryCode, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := recoveryEncoding.EncodeToString(b)[:10]
+		code = code[:5] + "-" + code[5:]
+
+		codes = append(codes, code)
+		records = append(records, user.RecoveryCode{UserID: userID, Hash: hashRecoveryCode(code)})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// useRecoveryCode spends a recovery code of the user and reports whether it
+// was valid and unused.
+func useRecoveryCode(database *gorm.DB, userID uint, code string) (bool, error) {
+	tx := database.Model(&user.RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at = 0", userID, hashRecoveryCode(code)).
+		UpdateColumn("used_at", clock.System.Now().Unix())
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+
+	return tx.RowsAffected > 0, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
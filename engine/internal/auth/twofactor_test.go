@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/totp"
+	"github.com/mateusgcoelho/sentinel/engine/internal/user"
+)
+
+var testJwtSecret = []byte("test-secret")
+
+// currentTotpCode computes the code an authenticator app shows right now,
+// following RFC 6238 independently of the totp package.
+func currentTotpCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("invalid secret: %v", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(time.Now().Unix()/30))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func createTotpUser(t *testing.T, h *AuthHandler) user.User {
+	t.Helper()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+
+	account := user.User{Username: "jane", Password: "hash", TotpSecret: secret, TotpEnabled: true}
+	if err := h.database.Create(&account).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return account
+}
+
+func TestCheckTotpRefusesSameStepTwice(t *testing.T) {
+	h := NewHandler(newTestDatabase(t), testJwtSecret, Settings{})
+	account := createTotpUser(t, h)
+
+	code := currentTotpCode(t, account.TotpSecret)
+
+	// A second request that loaded the user before the first one stored the
+	// step must be refused by the database as well.
+	stale := account
+
+	if valid, err := h.checkTotp(&account, code); err != nil || !valid {
+		t.Fatalf("first use = %t, %v, want accepted", valid, err)
+	}
+
+	if valid, err := h.checkTotp(&account, code); err != nil || valid {
+		t.Errorf("replay = %t, %v, want refused", valid, err)
+	}
+
+	if valid, err := h.checkTotp(&stale, code); err != nil || valid {
+		t.Errorf("replay with a stale user = %t, %v, want refused", valid, err)
+	}
+
+	var stored user.User
+	if err := h.database.First(&stored, account.ID).Error; err != nil {
+		t.Fatalf("failed to reload user: %v", err)
+	}
+
+	if stored.TotpLastStep == 0 || stored.TotpLastStep != account.TotpLastStep {
+		t.Errorf("totp_last_step = %d, want %d", stored.TotpLastStep, account.TotpLastStep)
+	}
+}
+
+func TestCheckTotpRefusesWrongCode(t *testing.T) {
+	h := NewHandler(newTestDatabase(t), testJwtSecret, Settings{})
+	account := createTotpUser(t, h)
+
+	value, _ := strconv.Atoi(currentTotpCode(t, account.TotpSecret))
+	wrong := fmt.Sprintf("%06d", (value+1)%1000000)
+
+	if valid, err := h.checkTotp(&account, wrong); err != nil || valid {
+		t.Errorf("wrong code = %t, %v, want refused", valid, err)
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	h := NewHandler(newTestDatabase(t), testJwtSecret, Settings{})
+	account := createTotpUser(t, h)
+
+	codes, err := replaceRecoveryCodes(h.database, account.ID)
+	if err != nil {
+		t.Fatalf("failed to issue recovery codes: %v", err)
+	}
+
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	if used, err := useRecoveryCode(h.database, account.ID, codes[0]); err != nil || !used {
+		t.Fatalf("first use = %t, %v, want accepted", used, err)
+	}
+
+	if used, err := useRecoveryCode(h.database, account.ID, codes[0]); err != nil || used {
+		t.Errorf("second use = %t, %v, want refused", used, err)
+	}
+
+	// Codes are typed by hand, so case, dashes and spaces do not matter.
+	retyped := strings.ToUpper(strings.ReplaceAll(codes[1], "-", " "))
+	if used, err := useRecoveryCode(h.database, account.ID, retyped); err != nil || !used {
+		t.Errorf("retyped code = %t, %v, want accepted", used, err)
+	}
+
+	if used, err := useRecoveryCode(h.database, account.ID+1, codes[2]); err != nil || used {
+		t.Errorf("code of another user = %t, %v, want refused", used, err)
+	}
+
+	if _, err := replaceRecoveryCodes(h.database, account.ID); err != nil {
+		t.Fatalf("failed to regenerate recovery codes: %v", err)
+	}
+
+	if used, err := useRecoveryCode(h.database, account.ID, codes[2]); err != nil || used {
+		t.Errorf("code from before regenerating = %t, %v, want refused", used, err)
+	}
+}
@@ -1,6 +1,65 @@
 package auth
 
+import (
+	"errors"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/oidc"
+	"github.com/mateusgcoelho/sentinel/engine/internal/user"
+)
+
+var (
+	errAuthenticationRequired = errors.New("authentication token required")
+	errInvalidToken           = errors.New("invalid authentication token")
+	errTwoFactorRequired      = errors.New("two-factor authentication required")
+	errPasswordChangeRequired = errors.New("password change required")
+	errUsernameTaken          = errors.New("username already taken")
+)
+
+// Settings are the sign-in options that apply to every user.
+type Settings struct {
+	// RequireTwoFactor makes users without TOTP enroll before they can get
+	// a session, and ends sessions that were not opened with a second
+	// factor.
+	RequireTwoFactor bool
+	// LocalSignInDisabled refuses username and password sign ins, leaving
+	// single sign-on as the only way in.
+	LocalSignInDisabled bool
+	// Oidc enables single sign-on when set.
+	Oidc *OidcSettings
+}
+
+type OidcSettings struct {
+	Provider    *oidc.Provider
+	GroupsClaim string
+	RoleMapping map[string]user.Role
+	// DefaultRole is given to users in none of the mapped groups. When empty
+	// they are refused.
+	DefaultRole  user.Role
+	PostLoginURL string
+}
+
+// TwoFactorStep tells the client what the sign in still needs.
+type TwoFactorStep string
+
+const (
+	TwoFactorStepVerify TwoFactorStep = "VERIFY"
+	TwoFactorStepEnroll TwoFactorStep = "ENROLL"
+)
+
 type SignInRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
+
+type VerifyTwoFactorRequest struct {
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+}
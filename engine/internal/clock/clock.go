@@ -0,0 +1,17 @@
+package clock
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts a plain function to the Clock interface, which makes it easy
+// to freeze or advance time when exercising time based logic.
+type Func func() time.Time
+
+func (f Func) Now() time.Time {
+	return f()
+}
+
+var System Clock = Func(time.Now)
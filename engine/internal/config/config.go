@@ -1,22 +1,67 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/mateusgcoelho/sentinel/engine/internal/auth"
+	"github.com/mateusgcoelho/sentinel/engine/internal/user"
 )
 
 const (
 	ErrMissingEnvVariables = "missing required environment variables"
 )
 
+// defaultAuditRetentionDays applies when AUDIT_RETENTION_DAYS is unset. Zero
+// keeps audit entries forever.
+const defaultAuditRetentionDays = 90
+
+// Config is the application configuration. Username and Password are the
+// first admin's; GeneratePassword replaces Password with a random one printed
+// once when the admin is created, and DefaultPassword tells that Password fell
+// back to the well-known default. TrustedProxies lists the addresses or CIDRs
+// whose X-Forwarded-For header is believed for the client IP; with none, the
+// IP is always the address of the connection.
 type Config struct {
-	Username      string
-	Password      string
-	JwtSecret     []byte
-	OriginAllowed string
+	Username            string
+	Password            string
+	GeneratePassword    bool
+	DefaultPassword     bool
+	JwtSecret           []byte
+	OriginAllowed       string
+	TrustedProxies      []string
+	ExecMonitorsEnabled bool
+	ExecAllowedPaths    []string
+	ResponseBodyLimit   int
+	AuditRetentionDays  int
+	RequireTwoFactor    bool
+	LocalSignInDisabled bool
+	Oidc                OidcConfig
+}
+
+// OidcConfig enables single sign-on when Issuer is set. RoleMapping maps
+// values of the groups claim to roles; users matching no group get
+// DefaultRole, or are refused when it is empty.
+type OidcConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	RoleMapping  map[string]user.Role
+	DefaultRole  user.Role
+	PostLoginURL string
+}
+
+func (c OidcConfig) Enabled() bool {
+	return c.Issuer != ""
 }
 
 func New() (Config, error) {
@@ -26,8 +71,19 @@ func New() (Config, error) {
 
 	rootUsername := os.Getenv("ROOT_USERNAME")
 	rootPassword := os.Getenv("ROOT_PASSWORD")
+	generatePassword := os.Getenv("ROOT_PASSWORD_GENERATE") == "true"
 	originAllowed := os.Getenv("CORS_ORIGIN_ALLOWED")
 	jwtSecretEnvironment := os.Getenv("JWT_SECRET")
+	execMonitorsEnabled := os.Getenv("EXEC_MONITORS_ENABLED") == "true"
+	requireTwoFactor := os.Getenv("REQUIRE_2FA") == "true"
+	localSignInDisabled := os.Getenv("LOCAL_SIGN_IN_DISABLED") == "true"
+	execAllowedPaths := splitList(os.Getenv("EXEC_ALLOWED_PATHS"))
+	trustedProxies := splitList(os.Getenv("TRUSTED_PROXIES"))
+	responseBodyLimit, _ := strconv.Atoi(os.Getenv("RESPONSE_BODY_LIMIT"))
+	auditRetentionDays, err := strconv.Atoi(os.Getenv("AUDIT_RETENTION_DAYS"))
+	if err != nil || auditRetentionDays < 0 {
+		auditRetentionDays = defaultAuditRetentionDays
+	}
 	var jwtSecret []byte
 
 	if jwtSecretEnvironment == "" {
@@ -47,14 +103,115 @@ func New() (Config, error) {
 		rootUsername = "admin"
 	}
 
-	if rootPassword == "" {
+	if rootPassword != "" && generatePassword {
+		return Config{}, errors.New("ROOT_PASSWORD and ROOT_PASSWORD_GENERATE cannot be used together")
+	}
+
+	defaultPassword := rootPassword == "" && !generatePassword
+	if defaultPassword {
 		rootPassword = "admin"
 	}
 
+	oidcConfig, err := loadOidcConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
+	if localSignInDisabled && !oidcConfig.Enabled() {
+		return Config{}, errors.New("LOCAL_SIGN_IN_DISABLED requires single sign-on to be configured with OIDC_ISSUER")
+	}
+
+	if oidcConfig.Enabled() {
+		log.Printf("[config] single sign-on enabled with %s", oidcConfig.Issuer)
+	}
+
+	if requireTwoFactor {
+		log.Println("[config] two-factor authentication required for every user")
+	}
+
+	if execMonitorsEnabled {
+		log.Printf("[config] EXEC monitors enabled, %d allowed executable paths", len(execAllowedPaths))
+	}
+
 	return Config{
-		Username:      rootUsername,
-		Password:      rootPassword,
-		JwtSecret:     jwtSecret,
-		OriginAllowed: originAllowed,
+		Username:            rootUsername,
+		Password:            rootPassword,
+		GeneratePassword:    generatePassword,
+		DefaultPassword:     defaultPassword,
+		JwtSecret:           jwtSecret,
+		OriginAllowed:       originAllowed,
+		TrustedProxies:      trustedProxies,
+		ExecMonitorsEnabled: execMonitorsEnabled,
+		ExecAllowedPaths:    execAllowedPaths,
+		ResponseBodyLimit:   responseBodyLimit,
+		AuditRetentionDays:  auditRetentionDays,
+		RequireTwoFactor:    requireTwoFactor,
+		LocalSignInDisabled: localSignInDisabled,
+		Oidc:                oidcConfig,
 	}, nil
 }
+
+func loadOidcConfig() (OidcConfig, error) {
+	oidcConfig := OidcConfig{
+		Issuer:       os.Getenv("OIDC_ISSUER"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+		RoleMapping:  map[string]user.Role{},
+		DefaultRole:  user.Role(strings.ToUpper(os.Getenv("OIDC_DEFAULT_ROLE"))),
+		PostLoginURL: os.Getenv("OIDC_POST_LOGIN_URL"),
+	}
+
+	if !oidcConfig.Enabled() {
+		return oidcConfig, nil
+	}
+
+	if oidcConfig.ClientID == "" || oidcConfig.RedirectURL == "" {
+		return oidcConfig, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+
+	if len(oidcConfig.Scopes) == 0 {
+		oidcConfig.Scopes = []string{"openid", "profile", "email"}
+	}
+
+	if oidcConfig.GroupsClaim == "" {
+		oidcConfig.GroupsClaim = "groups"
+	}
+
+	if oidcConfig.PostLoginURL == "" {
+		oidcConfig.PostLoginURL = "/"
+	}
+
+	// OIDC_ROLE_MAPPING is a list of group=ROLE pairs, e.g.
+	// "sentinel-admins=ADMIN,sentinel-viewers=VIEWER".
+	for _, pair := range splitList(os.Getenv("OIDC_ROLE_MAPPING")) {
+		group, rawRole, ok := strings.Cut(pair, "=")
+		role := user.Role(strings.ToUpper(strings.TrimSpace(rawRole)))
+		if !ok || !slices.Contains(user.Roles, role) {
+			return oidcConfig, fmt.Errorf("invalid OIDC_ROLE_MAPPING entry %q", pair)
+		}
+
+		oidcConfig.RoleMapping[strings.TrimSpace(group)] = role
+	}
+
+	if oidcConfig.DefaultRole != "" && !slices.Contains(user.Roles, oidcConfig.DefaultRole) {
+		return oidcConfig, fmt.Errorf("invalid OIDC_DEFAULT_ROLE %q", oidcConfig.DefaultRole)
+	}
+
+	return oidcConfig, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -1,15 +1,22 @@
 package database
 
 import (
+	"errors"
 	"log"
 	"os"
 
 	"github.com/mateusgcoelho/sentinel/engine/internal/apikey"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
+	"github.com/mateusgcoelho/sentinel/engine/internal/auth"
 	"github.com/mateusgcoelho/sentinel/engine/internal/config"
+	"github.com/mateusgcoelho/sentinel/engine/internal/escalation"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
 	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
 	"github.com/mateusgcoelho/sentinel/engine/internal/monitor"
 	"github.com/mateusgcoelho/sentinel/engine/internal/password"
 	"github.com/mateusgcoelho/sentinel/engine/internal/request"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"github.com/mateusgcoelho/sentinel/engine/internal/session"
 	"github.com/mateusgcoelho/sentinel/engine/internal/user"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -30,14 +37,34 @@ func OpenDatabaseConnection(appConfig config.Config) (*gorm.DB, error) {
 	if err := gormDb.AutoMigrate(
 		&monitor.MonitorConfig{},
 		&monitor.Attempt{},
+		&monitor.GroupMember{},
+		&monitor.MonitorTag{},
 		&integration.IntegrationConfig{},
 		&user.User{},
+		&user.RecoveryCode{},
 		&request.RequestLog{},
 		&apikey.ApiKeyConfig{},
+		&escalation.EscalationPolicy{},
+		&escalation.EscalationStep{},
+		&escalation.EscalationEvent{},
+		&secret.Secret{},
+		&history.Version{},
+		&audit.Entry{},
+		&session.Session{},
+		&auth.SignInFailure{},
+		&auth.Lockout{},
 	); err != nil {
 		return nil, err
 	}
 
+	if err := backfillAttemptHealth(gormDb); err != nil {
+		return nil, err
+	}
+
+	if err := backfillSlugs(gormDb); err != nil {
+		return nil, err
+	}
+
 	if err := createAdminUserIfNotExists(appConfig, gormDb); err != nil {
 		return nil, err
 	}
@@ -47,6 +74,61 @@ func OpenDatabaseConnection(appConfig config.Config) (*gorm.DB, error) {
 	return gormDb, nil
 }
 
+// backfillAttemptHealth derives the health state of monitors and attempts
+// recorded before the three-state model existed from their healthy flag.
+func backfillAttemptHealth(gormDb *gorm.DB) error {
+	if err := gormDb.Model(&monitor.MonitorConfig{}).
+		Where("healthy = ? AND health = ?", true, monitor.HealthStateDown).
+		Update("health", monitor.HealthStateUp).Error; err != nil {
+		return err
+	}
+
+	return gormDb.Model(&monitor.Attempt{}).
+		Where("healthy = ? AND health = ?", true, monitor.HealthStateDown).
+		Update("health", monitor.HealthStateUp).Error
+}
+
+// backfillSlugs gives monitors and integrations created before slugs existed
+// one derived from their name, so every row can be exported.
+func backfillSlugs(gormDb *gorm.DB) error {
+	var monitors []monitor.MonitorConfig
+	if err := gormDb.Where("slug IS NULL").Order("id ASC").Find(&monitors).Error; err != nil {
+		return err
+	}
+
+	for _, monitorConfig := range monitors {
+		value, err := monitor.UniqueSlug(gormDb, monitorConfig.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := gormDb.Model(&monitorConfig).UpdateColumn("slug", value).Error; err != nil {
+			return err
+		}
+	}
+
+	var integrations []integration.IntegrationConfig
+	if err := gormDb.Where("slug IS NULL").Order("id ASC").Find(&integrations).Error; err != nil {
+		return err
+	}
+
+	for _, item := range integrations {
+		value, err := integration.UniqueSlug(gormDb, item.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := gormDb.Model(&item).UpdateColumn("slug", value).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createAdminUserIfNotExists creates the first admin on an empty database.
+// Unless ROOT_PASSWORD was given, the admin must change its password before
+// doing anything else. A generated password is only ever printed here.
 func createAdminUserIfNotExists(appConfig config.Config, gormDb *gorm.DB) error {
 	var count int64
 	if err := gormDb.Model(&user.User{}).Count(&count).Error; err != nil {
@@ -54,23 +136,67 @@ func createAdminUserIfNotExists(appConfig config.Config, gormDb *gorm.DB) error
 	}
 
 	if count > 0 {
-		return nil
+		return flagDefaultAdminPassword(appConfig, gormDb)
+	}
+
+	plaintextPassword := appConfig.Password
+	if appConfig.GeneratePassword {
+		generated, err := password.RandomPlaintextPassword()
+		if err != nil {
+			return err
+		}
+
+		plaintextPassword = generated
 	}
 
-	hashedPassword, err := password.Hash(appConfig.Password)
+	hashedPassword, err := password.Hash(plaintextPassword)
 	if err != nil {
 		return err
 	}
 
 	user := user.User{
-		Username: appConfig.Username,
-		Password: hashedPassword,
+		Username:           appConfig.Username,
+		Password:           hashedPassword,
+		MustChangePassword: appConfig.GeneratePassword || appConfig.DefaultPassword,
 	}
 	if err := gormDb.Create(&user).Error; err != nil {
 		return err
 	}
 
-	log.Println("[database] created default admin user with username 'admin' and password 'admin'")
+	switch {
+	case appConfig.GeneratePassword:
+		log.Printf("[database] created admin user %q with generated password: %s", appConfig.Username, plaintextPassword)
+		log.Println("[database] this password is not shown again, it must be changed on first sign-in")
+	case appConfig.DefaultPassword:
+		log.Printf("[database] created admin user %q with the default password 'admin', it must be changed on first sign-in", appConfig.Username)
+	default:
+		log.Printf("[database] created admin user %q with the password from ROOT_PASSWORD", appConfig.Username)
+	}
 
 	return nil
 }
+
+// flagDefaultAdminPassword makes an admin created before password changes
+// were enforced change the default password it still has.
+func flagDefaultAdminPassword(appConfig config.Config, gormDb *gorm.DB) error {
+	if !appConfig.DefaultPassword {
+		return nil
+	}
+
+	var admin user.User
+	err := gormDb.Where("username = ? AND must_change_password = ?", appConfig.Username, false).First(&admin).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !password.Compare(admin.Password, appConfig.Password) {
+		return nil
+	}
+
+	log.Printf("[database] admin user %q still has the default password, it must be changed on next sign-in", admin.Username)
+
+	return gormDb.Model(&admin).UpdateColumn("must_change_password", true).Error
+}
@@ -0,0 +1,38 @@
+// Package dbtest opens throwaway databases for tests.
+package dbtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open returns an in-memory sqlite database private to the test, migrated
+// with the given models. It is closed when the test ends.
+func Open(tb testing.TB, models ...any) *gorm.DB {
+	tb.Helper()
+
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(tb.Name())
+	database, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		tb.Fatalf("failed to open database: %v", err)
+	}
+
+	if err := database.AutoMigrate(models...); err != nil {
+		tb.Fatalf("failed to migrate database: %v", err)
+	}
+
+	sqlDB, err := database.DB()
+	if err != nil {
+		tb.Fatalf("failed to get database handle: %v", err)
+	}
+	tb.Cleanup(func() { sqlDB.Close() })
+
+	return database
+}
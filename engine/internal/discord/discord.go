@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -32,7 +33,33 @@ type DiscordEmbedFooter struct {
 	Text string `json:"text"`
 }
 
-func SendAlertMessage(webhookURL, monitorName string, errorMessage string, failedAttempts int) error {
+func SendAlertMessage(webhookURL, monitorName string, errorMessage string, failedAttempts int, dependents []string) error {
+	fields := []DiscordEmbedField{
+		{
+			Name:   "Status",
+			Value:  "❌ Unhealthy",
+			Inline: true,
+		},
+		{
+			Name:   "Consecutive failures",
+			Value:  fmt.Sprintf("%d", failedAttempts),
+			Inline: true,
+		},
+		{
+			Name:   "Last error",
+			Value:  errorMessage,
+			Inline: false,
+		},
+	}
+
+	if len(dependents) > 0 {
+		fields = append(fields, DiscordEmbedField{
+			Name:   "Affected dependents",
+			Value:  strings.Join(dependents, ", "),
+			Inline: false,
+		})
+	}
+
 	payload := DiscordWebhookPayload{
 		Content: "@everyone",
 		Embeds: []DiscordEmbed{
@@ -40,23 +67,7 @@ func SendAlertMessage(webhookURL, monitorName string, errorMessage string, faile
 				Title:       "🚨 Ops... Look out!!",
 				Description: fmt.Sprintf("**%s** failed to respond!", monitorName),
 				Color:       15158332,
-				Fields: []DiscordEmbedField{
-					{
-						Name:   "Status",
-						Value:  "❌ Unhealthy",
-						Inline: true,
-					},
-					{
-						Name:   "Consecutive failures",
-						Value:  fmt.Sprintf("%d", failedAttempts),
-						Inline: true,
-					},
-					{
-						Name:   "Last error",
-						Value:  errorMessage,
-						Inline: false,
-					},
-				},
+				Fields:      fields,
 				Footer: &DiscordEmbedFooter{
 					Text: "🔧 Automatic monitor • Sentinel (JMCDynamics)",
 				},
@@ -95,6 +106,41 @@ func SendRecoverMessage(webhookURL, monitorName string, failedAttempts int) erro
 	return sendDiscordWebhook(webhookURL, payload)
 }
 
+func SendFlappingMessage(webhookURL, monitorName string, flapping bool, changeRate int) error {
+	title := "🔁 Flapping detected!"
+	description := fmt.Sprintf("**%s** keeps changing state. Individual alerts are paused until it settles.", monitorName)
+	color := 15105570
+	if !flapping {
+		title = "🧘 Flapping settled"
+		description = fmt.Sprintf("**%s** stopped flapping. Regular alerts are back on.", monitorName)
+		color = 3066993
+	}
+
+	payload := DiscordWebhookPayload{
+		Content: "@everyone",
+		Embeds: []DiscordEmbed{
+			{
+				Title:       title,
+				Description: description,
+				Color:       color,
+				Fields: []DiscordEmbedField{
+					{
+						Name:   "State change rate",
+						Value:  fmt.Sprintf("%d%%", changeRate),
+						Inline: true,
+					},
+				},
+				Footer: &DiscordEmbedFooter{
+					Text: "🔧 Automatic monitor • Sentinel (JMCDynamics)",
+				},
+				Timestamp: time.Now().Format(time.RFC3339),
+			},
+		},
+	}
+
+	return sendDiscordWebhook(webhookURL, payload)
+}
+
 func sendDiscordWebhook(webhookURL string, payload interface{}) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -0,0 +1,85 @@
+package escalation
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+)
+
+// Incident is the state of an ongoing outage as seen by the policy engine.
+// LastNotified holds, per step position, the last time that step was notified.
+type Incident struct {
+	StartedAt      time.Time
+	AcknowledgedAt time.Time
+	LastNotified   map[int]time.Time
+}
+
+type Action struct {
+	Step EscalationStep
+	Kind EventKind
+}
+
+type Engine struct {
+	clock clock.Clock
+}
+
+func NewEngine(c clock.Clock) *Engine {
+	return &Engine{
+		clock: c,
+	}
+}
+
+func (i Incident) Acknowledged() bool {
+	return !i.AcknowledgedAt.IsZero()
+}
+
+// Evaluate returns the notifications that are due for the incident right now.
+// Acknowledging an incident stops both escalation to further steps and
+// repeated notifications of the steps already reached.
+func (e *Engine) Evaluate(policy EscalationPolicy, incident Incident) []Action {
+	now := e.clock.Now()
+
+	if incident.Acknowledged() {
+		return nil
+	}
+
+	steps := SortedSteps(policy)
+
+	var actions []Action
+
+	for i, step := range steps {
+		last, notified := incident.LastNotified[step.Position]
+
+		if !notified {
+			if now.Sub(incident.StartedAt) < step.DelayDuration() {
+				continue
+			}
+
+			kind := EventKindEscalate
+			if i == 0 {
+				kind = EventKindNotify
+			}
+
+			actions = append(actions, Action{Step: step, Kind: kind})
+			continue
+		}
+
+		if step.RepeatInterval > 0 && now.Sub(last) >= step.RepeatDuration() {
+			actions = append(actions, Action{Step: step, Kind: EventKindRepeat})
+		}
+	}
+
+	return actions
+}
+
+func SortedSteps(policy EscalationPolicy) []EscalationStep {
+	steps := make([]EscalationStep, len(policy.Steps))
+	copy(steps, policy.Steps)
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Position < steps[j].Position
+	})
+
+	return steps
+}
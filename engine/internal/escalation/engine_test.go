@@ -0,0 +1,108 @@
+package escalation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+)
+
+var incidentStart = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// testPolicy notifies at once and every 5 minutes, then escalates after 10
+// minutes to a step that does not repeat. Steps are out of order on purpose.
+func testPolicy() EscalationPolicy {
+	return EscalationPolicy{
+		Steps: []EscalationStep{
+			{Position: 1, Delay: 600},
+			{Position: 0, Delay: 0, RepeatInterval: 300},
+		},
+	}
+}
+
+func evaluateAt(policy EscalationPolicy, incident Incident, elapsed time.Duration) []Action {
+	engine := NewEngine(clock.Func(func() time.Time {
+		return incidentStart.Add(elapsed)
+	}))
+
+	return engine.Evaluate(policy, incident)
+}
+
+func assertActions(t *testing.T, actions []Action, want ...Action) {
+	t.Helper()
+
+	if len(actions) != len(want) {
+		t.Fatalf("got %d actions %+v, want %d", len(actions), actions, len(want))
+	}
+
+	for i := range want {
+		if actions[i].Step.Position != want[i].Step.Position || actions[i].Kind != want[i].Kind {
+			t.Errorf("action %d = step %d %s, want step %d %s", i, actions[i].Step.Position, actions[i].Kind, want[i].Step.Position, want[i].Kind)
+		}
+	}
+}
+
+func action(position int, kind EventKind) Action {
+	return Action{Step: EscalationStep{Position: position}, Kind: kind}
+}
+
+func TestEvaluateNotifiesFirstStepImmediately(t *testing.T) {
+	incident := Incident{StartedAt: incidentStart}
+
+	assertActions(t, evaluateAt(testPolicy(), incident, 0), action(0, EventKindNotify))
+}
+
+func TestEvaluateRepeatsAfterInterval(t *testing.T) {
+	incident := Incident{
+		StartedAt:    incidentStart,
+		LastNotified: map[int]time.Time{0: incidentStart},
+	}
+
+	assertActions(t, evaluateAt(testPolicy(), incident, 299*time.Second))
+	assertActions(t, evaluateAt(testPolicy(), incident, 300*time.Second), action(0, EventKindRepeat))
+
+	incident.LastNotified[0] = incidentStart.Add(300 * time.Second)
+
+	assertActions(t, evaluateAt(testPolicy(), incident, 599*time.Second))
+}
+
+func TestEvaluateEscalatesAfterStepDelay(t *testing.T) {
+	incident := Incident{
+		StartedAt:    incidentStart,
+		LastNotified: map[int]time.Time{0: incidentStart.Add(300 * time.Second)},
+	}
+
+	assertActions(t, evaluateAt(testPolicy(), incident, 599*time.Second))
+	assertActions(t, evaluateAt(testPolicy(), incident, 600*time.Second),
+		action(0, EventKindRepeat),
+		action(1, EventKindEscalate),
+	)
+
+	// A step without a repeat interval is notified once.
+	incident.LastNotified[0] = incidentStart.Add(600 * time.Second)
+	incident.LastNotified[1] = incidentStart.Add(600 * time.Second)
+
+	assertActions(t, evaluateAt(testPolicy(), incident, 899*time.Second))
+	assertActions(t, evaluateAt(testPolicy(), incident, 900*time.Second), action(0, EventKindRepeat))
+}
+
+func TestEvaluateReachesOverdueStepsTogether(t *testing.T) {
+	incident := Incident{StartedAt: incidentStart}
+
+	assertActions(t, evaluateAt(testPolicy(), incident, time.Hour),
+		action(0, EventKindNotify),
+		action(1, EventKindEscalate),
+	)
+}
+
+func TestEvaluateStopsOnceAcknowledged(t *testing.T) {
+	incident := Incident{
+		StartedAt:      incidentStart,
+		AcknowledgedAt: incidentStart.Add(time.Minute),
+		LastNotified:   map[int]time.Time{0: incidentStart},
+	}
+
+	for _, elapsed := range []time.Duration{time.Minute, 5 * time.Minute, 10 * time.Minute, time.Hour} {
+		assertActions(t, evaluateAt(testPolicy(), incident, elapsed))
+	}
+}
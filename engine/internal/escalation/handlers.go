@@ -0,0 +1,199 @@
+package escalation
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIntegrationNotFound = errors.New("one or more integrations not found")
+)
+
+type EscalationHandler struct {
+	database *gorm.DB
+
+	accessMiddleware gin.HandlerFunc
+}
+
+func NewHandler(db *gorm.DB, accessMiddleware gin.HandlerFunc) *EscalationHandler {
+	return &EscalationHandler{
+		database:         db,
+		accessMiddleware: accessMiddleware,
+	}
+}
+
+func (h *EscalationHandler) SetupRoutes(r *gin.Engine) {
+	policies := r.Group("/escalation-policies")
+	{
+		policies.POST("", audit.Action("escalation_policy.create"), h.accessMiddleware, h.HandleCreatePolicy)
+		policies.GET("", h.accessMiddleware, h.HandleListPolicies)
+		policies.GET("/:id", h.accessMiddleware, h.HandleGetPolicyDetails)
+		policies.PUT("/:id", audit.Action("escalation_policy.update"), h.accessMiddleware, h.HandleUpdatePolicy)
+	}
+}
+
+func (h *EscalationHandler) HandleCreatePolicy(c *gin.Context) {
+	var req CreateEscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	steps, err := h.buildSteps(req.Steps)
+	if err != nil {
+		if errors.Is(err, ErrIntegrationNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve integrations"})
+		return
+	}
+
+	policy := EscalationPolicy{
+		Name:  req.Name,
+		Steps: steps,
+	}
+
+	if err := h.database.Create(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to create escalation policy"})
+		return
+	}
+
+	audit.SetTarget(c, "ESCALATION_POLICY", policy.ID)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "escalation policy created successfully", "data": policy})
+}
+
+func (h *EscalationHandler) HandleListPolicies(c *gin.Context) {
+	var policies []EscalationPolicy
+
+	if err := h.database.
+		Preload("Steps", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Steps.Integrations").
+		Order("created_at DESC").
+		Find(&policies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to list escalation policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": policies})
+}
+
+func (h *EscalationHandler) HandleGetPolicyDetails(c *gin.Context) {
+	var policy EscalationPolicy
+	if err := h.database.
+		Preload("Steps", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Steps.Integrations").
+		First(&policy, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "escalation policy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": policy})
+}
+
+func (h *EscalationHandler) HandleUpdatePolicy(c *gin.Context) {
+	var req UpdateEscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	var policy EscalationPolicy
+	if err := h.database.First(&policy, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "escalation policy not found"})
+		return
+	}
+
+	if req.Name != nil {
+		policy.Name = *req.Name
+	}
+
+	var steps []EscalationStep
+	if req.Steps != nil {
+		built, err := h.buildSteps(*req.Steps)
+		if err != nil {
+			if errors.Is(err, ErrIntegrationNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+				return
+			}
+
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve integrations"})
+			return
+		}
+		steps = built
+	}
+
+	err := h.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&policy).Error; err != nil {
+			return err
+		}
+
+		if req.Steps == nil {
+			return nil
+		}
+
+		var oldSteps []EscalationStep
+		if err := tx.Where("escalation_policy_id = ?", policy.ID).Find(&oldSteps).Error; err != nil {
+			return err
+		}
+
+		for i := range oldSteps {
+			if err := tx.Model(&oldSteps[i]).Association("Integrations").Clear(); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("escalation_policy_id = ?", policy.ID).Delete(&EscalationStep{}).Error; err != nil {
+			return err
+		}
+
+		for i := range steps {
+			steps[i].EscalationPolicyID = policy.ID
+		}
+
+		return tx.Create(&steps).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to update escalation policy"})
+		return
+	}
+
+	if req.Steps != nil {
+		policy.Steps = steps
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "escalation policy updated successfully", "data": policy})
+}
+
+func (h *EscalationHandler) buildSteps(reqSteps []CreateEscalationStepRequest) ([]EscalationStep, error) {
+	steps := make([]EscalationStep, 0, len(reqSteps))
+
+	for i, reqStep := range reqSteps {
+		var integrations []integration.IntegrationConfig
+		if err := h.database.
+			Where("id IN ?", reqStep.IntegrationIdList).
+			Find(&integrations).Error; err != nil {
+			return nil, err
+		}
+
+		if len(integrations) != len(reqStep.IntegrationIdList) {
+			return nil, ErrIntegrationNotFound
+		}
+
+		steps = append(steps, EscalationStep{
+			Position:       i,
+			Delay:          reqStep.Delay,
+			RepeatInterval: reqStep.RepeatInterval,
+			Integrations:   integrations,
+		})
+	}
+
+	return steps, nil
+}
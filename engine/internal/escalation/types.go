@@ -0,0 +1,71 @@
+package escalation
+
+import (
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+)
+
+type EventKind string
+
+const (
+	EventKindNotify      EventKind = "NOTIFY"
+	EventKindRepeat      EventKind = "REPEAT"
+	EventKindEscalate    EventKind = "ESCALATE"
+	EventKindAcknowledge EventKind = "ACKNOWLEDGE"
+	EventKindRecover     EventKind = "RECOVER"
+)
+
+type EscalationPolicy struct {
+	ID        uint             `gorm:"primaryKey" json:"id"`
+	Name      string           `gorm:"not null" json:"name"`
+	Steps     []EscalationStep `gorm:"constraint:OnDelete:CASCADE;" json:"steps"`
+	CreatedAt int64            `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt int64            `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// EscalationStep is reached once the incident has been open for Delay seconds
+// without being acknowledged. A positive RepeatInterval re-notifies the step's
+// integrations every RepeatInterval seconds while the monitor is still down.
+type EscalationStep struct {
+	ID                 uint                            `gorm:"primaryKey" json:"id"`
+	EscalationPolicyID uint                            `gorm:"not null;index" json:"escalation_policy_id"`
+	Position           int                             `gorm:"not null" json:"position"`
+	Delay              int                             `gorm:"not null" json:"delay"`
+	RepeatInterval     int                             `gorm:"not null" json:"repeat_interval"`
+	Integrations       []integration.IntegrationConfig `gorm:"many2many:escalation_step_integrations;" json:"integrations"`
+}
+
+type EscalationEvent struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	MonitorConfigID    uint      `gorm:"not null;index" json:"monitor_config_id"`
+	EscalationPolicyID *uint     `json:"escalation_policy_id"`
+	IncidentStartedAt  int64     `gorm:"not null;index" json:"incident_started_at"`
+	StepPosition       int       `gorm:"not null" json:"step_position"`
+	Kind               EventKind `gorm:"not null" json:"kind"`
+	CreatedAt          int64     `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type CreateEscalationStepRequest struct {
+	Delay             int    `json:"delay" binding:"min=0"`
+	RepeatInterval    int    `json:"repeat_interval" binding:"min=0"`
+	IntegrationIdList []uint `json:"integration_id_list" binding:"required,min=1"`
+}
+
+type CreateEscalationPolicyRequest struct {
+	Name  string                        `json:"name" binding:"required"`
+	Steps []CreateEscalationStepRequest `json:"steps" binding:"required,min=1,dive"`
+}
+
+type UpdateEscalationPolicyRequest struct {
+	Name  *string                        `json:"name"`
+	Steps *[]CreateEscalationStepRequest `json:"steps" binding:"omitempty,min=1,dive"`
+}
+
+func (s EscalationStep) DelayDuration() time.Duration {
+	return time.Duration(s.Delay) * time.Second
+}
+
+func (s EscalationStep) RepeatDuration() time.Duration {
+	return time.Duration(s.RepeatInterval) * time.Second
+}
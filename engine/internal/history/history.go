@@ -0,0 +1,271 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type EntityType string
+
+const (
+	EntityTypeMonitor     EntityType = "MONITOR"
+	EntityTypeIntegration EntityType = "INTEGRATION"
+)
+
+type Action string
+
+const (
+	// ActionBaseline holds the state an entity had when its history started,
+	// so the first recorded change can still be rolled back.
+	ActionBaseline Action = "BASELINE"
+	ActionCreate   Action = "CREATE"
+	ActionUpdate   Action = "UPDATE"
+	ActionDelete   Action = "DELETE"
+)
+
+var ErrVersionNotFound = errors.New("version not found")
+
+// Version is one recorded state of a monitor or integration. Snapshot is the
+// configuration after the change, or the last one for deletions, and Changes
+// the fields that differ from the previous state.
+type Version struct {
+	ID         uint                             `gorm:"primaryKey" json:"id"`
+	EntityType EntityType                       `gorm:"not null;uniqueIndex:idx_versions_entity_version,priority:1" json:"entity_type"`
+	EntityID   uint                             `gorm:"not null;uniqueIndex:idx_versions_entity_version,priority:2" json:"entity_id"`
+	Version    int                              `gorm:"not null;uniqueIndex:idx_versions_entity_version,priority:3" json:"version"`
+	Action     Action                           `gorm:"not null" json:"action"`
+	UserID     *uint                            `json:"user_id"`
+	Username   string                           `gorm:"not null;default:''" json:"username"`
+	Source     string                           `gorm:"not null;default:''" json:"source"`
+	Snapshot   datatypes.JSON                   `gorm:"type:json" json:"snapshot"`
+	Changes    datatypes.JSONSlice[FieldChange] `gorm:"type:json" json:"changes"`
+	CreatedAt  int64                            `gorm:"autoCreateTime" json:"created_at"`
+}
+
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+const recordedKey = "history_versions"
+
+// Author is who made a change and through which route. The user is empty for
+// anonymous requests.
+type Author struct {
+	UserID   *uint
+	Username string
+	Source   string
+
+	recorded func(Version)
+}
+
+// AuthorFromContext reads the user set by the auth middlewares and uses the
+// matched route as the source. Versions recorded with the author are also
+// collected on the context, see Recorded.
+func AuthorFromContext(c *gin.Context) Author {
+	author := Author{
+		Username: c.GetString("username"),
+		Source:   c.Request.Method + " " + c.FullPath(),
+		recorded: func(version Version) {
+			c.Set(recordedKey, append(Recorded(c), version))
+		},
+	}
+
+	if userID, err := strconv.ParseUint(c.GetString("user_id"), 10, 64); err == nil {
+		id := uint(userID)
+		author.UserID = &id
+	}
+
+	return author
+}
+
+// Recorded returns the versions recorded during the request. They may belong
+// to a transaction that was rolled back if the request failed.
+func Recorded(c *gin.Context) []Version {
+	versions, _ := c.Get(recordedKey)
+	recorded, _ := versions.([]Version)
+
+	return recorded
+}
+
+// RecordChanges records a version for every entity in ids from the snapshots
+// taken before and after a change. An entity only present after the change
+// was created, one only present before it was deleted.
+func RecordChanges[T any](tx *gorm.DB, entityType EntityType, ids []uint, before map[uint]T, after map[uint]T, author Author) error {
+	for _, id := range ids {
+		previous, existed := before[id]
+		current, exists := after[id]
+
+		var err error
+		switch {
+		case existed && exists:
+			err = Record(tx, entityType, id, author, previous, current)
+		case exists:
+			err = Record(tx, entityType, id, author, nil, current)
+		case existed:
+			err = Record(tx, entityType, id, author, previous, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Record stores a new version of an entity. A nil before means the entity
+// was created and a nil after that it was deleted. Updates that change no
+// field are not recorded.
+func Record(tx *gorm.DB, entityType EntityType, entityID uint, author Author, before any, after any) error {
+	action := ActionUpdate
+	switch {
+	case before == nil:
+		action = ActionCreate
+	case after == nil:
+		action = ActionDelete
+	}
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	if action == ActionUpdate && len(changes) == 0 {
+		return nil
+	}
+
+	var latest Version
+	if err := tx.
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("version DESC").
+		Limit(1).
+		Find(&latest).Error; err != nil {
+		return err
+	}
+
+	number := latest.Version
+
+	// Entities created before history existed get their prior state recorded
+	// first.
+	if number == 0 && before != nil {
+		number++
+		if err := createVersion(tx, entityType, entityID, number, ActionBaseline, Author{}, before, nil); err != nil {
+			return err
+		}
+	}
+
+	snapshot := after
+	if action == ActionDelete {
+		snapshot = before
+	}
+
+	return createVersion(tx, entityType, entityID, number+1, action, author, snapshot, changes)
+}
+
+func createVersion(tx *gorm.DB, entityType EntityType, entityID uint, number int, action Action, author Author, snapshot any, changes []FieldChange) error {
+	document, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	version := Version{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Version:    number,
+		Action:     action,
+		UserID:     author.UserID,
+		Username:   author.Username,
+		Source:     author.Source,
+		Snapshot:   document,
+		Changes:    changes,
+	}
+	if err := tx.Create(&version).Error; err != nil {
+		return err
+	}
+
+	if author.recorded != nil && action != ActionBaseline {
+		author.recorded(version)
+	}
+
+	return nil
+}
+
+// List returns the versions of an entity, newest first.
+func List(database *gorm.DB, entityType EntityType, entityID uint) ([]Version, error) {
+	versions := []Version{}
+	err := database.
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("version DESC").
+		Find(&versions).Error
+
+	return versions, err
+}
+
+// Find returns one version of an entity.
+func Find(database *gorm.DB, entityType EntityType, entityID uint, number int) (Version, error) {
+	var version Version
+	err := database.
+		Where("entity_type = ? AND entity_id = ? AND version = ?", entityType, entityID, number).
+		First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return version, ErrVersionNotFound
+	}
+
+	return version, err
+}
+
+// Diff compares two values through their JSON form and returns the fields
+// that differ, sorted by name.
+func Diff(before any, after any) ([]FieldChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var changes []FieldChange
+	for _, name := range names {
+		if !reflect.DeepEqual(beforeFields[name], afterFields[name]) {
+			changes = append(changes, FieldChange{Field: name, Before: beforeFields[name], After: afterFields[name]})
+		}
+	}
+
+	return changes, nil
+}
+
+func jsonFields(value any) (map[string]any, error) {
+	document, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(document, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
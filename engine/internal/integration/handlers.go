@@ -2,26 +2,34 @@ package integration
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/slug"
 	"gorm.io/gorm"
 )
 
 type IntegrationHandler struct {
 	database *gorm.DB
+
+	accessMiddleware gin.HandlerFunc
 }
 
-func NewHandler(db *gorm.DB) *IntegrationHandler {
+func NewHandler(db *gorm.DB, accessMiddleware gin.HandlerFunc) *IntegrationHandler {
 	return &IntegrationHandler{
-		database: db,
+		database:         db,
+		accessMiddleware: accessMiddleware,
 	}
 }
 
 func (h *IntegrationHandler) SetupRoutes(r *gin.Engine) {
 	integrations := r.Group("/integrations")
 	{
-		integrations.POST("", h.HandleCreateIntegration)
-		integrations.GET("", h.HandleListIntegrations)
+		integrations.POST("", audit.Action("integration.create"), h.accessMiddleware, h.HandleCreateIntegration)
+		integrations.GET("", h.accessMiddleware, h.HandleListIntegrations)
+		integrations.GET("/:id/versions", h.accessMiddleware, h.HandleListIntegrationVersions)
 	}
 }
 
@@ -32,13 +40,46 @@ func (h *IntegrationHandler) HandleCreateIntegration(c *gin.Context) {
 		return
 	}
 
+	integrationSlug := req.Slug
+	if integrationSlug == "" {
+		generated, err := UniqueSlug(h.database, req.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to generate integration slug"})
+			return
+		}
+		integrationSlug = generated
+	} else {
+		if !slug.Valid(integrationSlug) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "slug must contain only lowercase letters, digits and dashes"})
+			return
+		}
+
+		var count int64
+		if err := h.database.Model(&IntegrationConfig{}).Where("slug = ?", integrationSlug).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to check integration slug"})
+			return
+		}
+
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"message": "slug is already in use"})
+			return
+		}
+	}
+
 	integration := IntegrationConfig{
+		Slug: &integrationSlug,
 		Name: req.Name,
 		Type: req.Type,
 		URL:  req.URL,
 	}
 
-	if err := h.database.Create(&integration).Error; err != nil {
+	if err := h.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&integration).Error; err != nil {
+			return err
+		}
+
+		return RecordVersions(tx, []uint{integration.ID}, nil, history.AuthorFromContext(c))
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to create integration"})
 		return
 	}
@@ -75,3 +116,28 @@ func (h *IntegrationHandler) HandleListIntegrations(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": integrations})
 }
+
+// HandleListIntegrationVersions lists the recorded versions of an
+// integration, including the ones of integrations that were deleted.
+func (h *IntegrationHandler) HandleListIntegrationVersions(c *gin.Context) {
+	integrationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "integration not found"})
+		return
+	}
+
+	versions, err := history.List(h.database, history.EntityTypeIntegration, uint(integrationID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to list integration versions"})
+		return
+	}
+
+	if len(versions) == 0 {
+		if err := h.database.First(&IntegrationConfig{}, integrationID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"message": "integration not found"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": versions})
+}
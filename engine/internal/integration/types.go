@@ -1,5 +1,15 @@
 package integration
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"github.com/mateusgcoelho/sentinel/engine/internal/slug"
+	"gorm.io/gorm"
+)
+
 type IntegrationType string
 
 const (
@@ -9,6 +19,7 @@ const (
 
 type IntegrationConfig struct {
 	ID        uint            `gorm:"primaryKey" json:"id"`
+	Slug      *string         `gorm:"uniqueIndex" json:"slug"`
 	Name      string          `gorm:"not null" json:"name"`
 	Type      IntegrationType `gorm:"not null" json:"type"`
 	URL       string          `gorm:"not null" json:"url"`
@@ -17,7 +28,79 @@ type IntegrationConfig struct {
 }
 
 type CreateIntegrationConfigRequest struct {
+	Slug string          `json:"slug"`
 	Name string          `json:"name" binding:"required"`
 	Type IntegrationType `json:"type" binding:"required,oneof=SLACK DISCORD"`
 	URL  string          `json:"url" binding:"required,url"`
 }
+
+// UniqueSlug derives a free slug from the integration name.
+func UniqueSlug(database *gorm.DB, name string) (string, error) {
+	return slug.Unique(slug.Make(name, "integration"), func(candidate string) (bool, error) {
+		var count int64
+		err := database.Model(&IntegrationConfig{}).Where("slug = ?", candidate).Count(&count).Error
+		return count > 0, err
+	})
+}
+
+// Snapshot is the configuration of an integration kept in its history and
+// in manifests. Webhook URLs are only kept when they are secret references,
+// other URLs are replaced by their SHA-256 digest so changing them still
+// records a version without storing the credentials.
+type Snapshot struct {
+	Slug      string          `json:"slug"`
+	Name      string          `json:"name"`
+	Type      IntegrationType `json:"type"`
+	URL       string          `json:"url,omitempty"`
+	URLDigest string          `json:"url_digest,omitempty"`
+}
+
+func (i IntegrationConfig) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Name: i.Name,
+		Type: i.Type,
+	}
+
+	if i.Slug != nil {
+		snapshot.Slug = *i.Slug
+	}
+
+	if secret.IsReference(i.URL) {
+		snapshot.URL = i.URL
+	} else if i.URL != "" {
+		sum := sha256.Sum256([]byte(i.URL))
+		snapshot.URLDigest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	return snapshot
+}
+
+// Snapshots returns the snapshot of every existing integration in ids.
+func Snapshots(database *gorm.DB, ids []uint) (map[uint]Snapshot, error) {
+	snapshots := make(map[uint]Snapshot, len(ids))
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+
+	var integrations []IntegrationConfig
+	if err := database.Where("id IN ?", ids).Find(&integrations).Error; err != nil {
+		return nil, err
+	}
+
+	for _, item := range integrations {
+		snapshots[item.ID] = item.Snapshot()
+	}
+
+	return snapshots, nil
+}
+
+// RecordVersions records the change made to the integrations in ids, given
+// their snapshots from before the change.
+func RecordVersions(tx *gorm.DB, ids []uint, before map[uint]Snapshot, author history.Author) error {
+	after, err := Snapshots(tx, ids)
+	if err != nil {
+		return err
+	}
+
+	return history.RecordChanges(tx, history.EntityTypeIntegration, ids, before, after, author)
+}
@@ -0,0 +1,43 @@
+package integration
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+)
+
+func TestSnapshotRecordsWebhookChangesWithoutTheURL(t *testing.T) {
+	before := IntegrationConfig{Name: "Slack", Type: IntegrationTypeSlack, URL: "https://hooks.slack.com/services/T000/B000/old"}
+	after := before
+	after.URL = "https://hooks.slack.com/services/T000/B000/new"
+
+	changes, err := history.Diff(before.Snapshot(), after.Snapshot())
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Field != "url_digest" {
+		t.Fatalf("changes = %+v, want a url_digest change", changes)
+	}
+
+	document, err := json.Marshal(after.Snapshot())
+	if err != nil {
+		t.Fatalf("failed to marshal snapshot: %v", err)
+	}
+
+	if strings.Contains(string(document), "B000") {
+		t.Errorf("snapshot leaks the webhook URL: %s", document)
+	}
+}
+
+func TestSnapshotKeepsSecretReferences(t *testing.T) {
+	item := IntegrationConfig{Name: "Slack", Type: IntegrationTypeSlack, URL: secret.Reference("slack-webhook")}
+
+	snapshot := item.Snapshot()
+	if snapshot.URL != item.URL || snapshot.URLDigest != "" {
+		t.Errorf("snapshot = %+v, want the reference kept as is", snapshot)
+	}
+}
@@ -0,0 +1,107 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPath = errors.New("invalid json path")
+	ErrNotFound    = errors.New("json path not found")
+)
+
+// Lookup resolves a small JSONPath subset against a decoded JSON document:
+// the root "$", dotted keys ("$.data.token"), quoted keys ("$['a.b']") and
+// array indexes ("$.items[0].id"). Filters and wildcards are not supported.
+func Lookup(document any, path string) (any, error) {
+	tokens, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := document
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+			}
+			current = value
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+	}
+
+	return current, nil
+}
+
+// Stringify renders a resolved value the way it would be substituted into a
+// request: strings as-is, everything else in its JSON form.
+func Stringify(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(bytes)
+	}
+}
+
+func parse(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("%w: %q must start with $", ErrInvalidPath, path)
+	}
+
+	var tokens []string
+	rest := path[1:]
+
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+			}
+			tokens = append(tokens, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+			}
+			token := strings.Trim(rest[1:end], `'"`)
+			if token == "" {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+			}
+			tokens = append(tokens, token)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+		}
+	}
+
+	return tokens, nil
+}
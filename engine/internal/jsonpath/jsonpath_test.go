@@ -0,0 +1,90 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testDocument = `{
+	"data": {"token": "abc", "expires_in": 3600, "active": true, "scopes": null},
+	"a.b": "dotted",
+	"items": [{"id": 1}, {"id": 2, "tags": ["x", "y"]}],
+	"0": "zero"
+}`
+
+func TestLookup(t *testing.T) {
+	var document any
+	if err := json.Unmarshal([]byte(testDocument), &document); err != nil {
+		t.Fatalf("failed to decode document: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want any
+		err  error
+	}{
+		{path: "$.data.token", want: "abc"},
+		{path: "$.data.expires_in", want: float64(3600)},
+		{path: "$.data.scopes", want: nil},
+		{path: "$['a.b']", want: "dotted"},
+		{path: `$["data"]["token"]`, want: "abc"},
+		{path: "$['0']", want: "zero"},
+		{path: "$.items[0].id", want: float64(1)},
+		{path: "$.items[1].tags[1]", want: "y"},
+		{path: "$.items.1.id", want: float64(2)},
+		{path: "$.data", want: map[string]any{"token": "abc", "expires_in": float64(3600), "active": true, "scopes": nil}},
+		{path: "$.missing", err: ErrNotFound},
+		{path: "$.data.token.length", err: ErrNotFound},
+		{path: "$.items[2]", err: ErrNotFound},
+		{path: "$.items[-1]", err: ErrNotFound},
+		{path: "$.items[first]", err: ErrNotFound},
+		{path: "$.a.b", err: ErrNotFound},
+		{path: "data.token", err: ErrInvalidPath},
+		{path: "", err: ErrInvalidPath},
+		{path: "$.", err: ErrInvalidPath},
+		{path: "$..token", err: ErrInvalidPath},
+		{path: "$data", err: ErrInvalidPath},
+		{path: "$.items[0", err: ErrInvalidPath},
+		{path: "$.items[]", err: ErrInvalidPath},
+		{path: "$['']", err: ErrInvalidPath},
+	}
+
+	for _, tt := range tests {
+		got, err := Lookup(document, tt.path)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: err = %v, want %v", tt.path, err, tt.err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: value = %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	if got, err := Lookup(document, "$"); err != nil || !reflect.DeepEqual(got, document) {
+		t.Errorf("$: value = %v, err = %v, want the whole document", got, err)
+	}
+}
+
+func TestStringify(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{value: "abc", want: "abc"},
+		{value: nil, want: "null"},
+		{value: float64(3600), want: "3600"},
+		{value: 1.5, want: "1.5"},
+		{value: true, want: "true"},
+		{value: []any{"x", float64(1)}, want: `["x",1]`},
+		{value: map[string]any{"id": float64(2)}, want: `{"id":2}`},
+	}
+
+	for _, tt := range tests {
+		if got := Stringify(tt.value); got != tt.want {
+			t.Errorf("%#v: got %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"errors"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"github.com/mateusgcoelho/sentinel/engine/internal/escalation"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"gorm.io/gorm"
+)
+
+type BulkAction string
+
+const (
+	BulkActionEnable             BulkAction = "ENABLE"
+	BulkActionDisable            BulkAction = "DISABLE"
+	BulkActionDelete             BulkAction = "DELETE"
+	BulkActionAssignIntegrations BulkAction = "ASSIGN_INTEGRATIONS"
+)
+
+var (
+	ErrIntegrationsRequired = errors.New("at least one integration is required")
+	ErrIntegrationNotFound  = errors.New("one or more integrations not found")
+)
+
+type BulkActionRequest struct {
+	Action            BulkAction    `json:"action" binding:"required,oneof=ENABLE DISABLE DELETE ASSIGN_INTEGRATIONS"`
+	Filter            MonitorFilter `json:"filter"`
+	IntegrationIdList []uint        `json:"integration_id_list"`
+}
+
+// applyBulkAction runs the action on every monitor matching the filter inside
+// a single transaction and returns the IDs of the affected monitors.
+func applyBulkAction(database *gorm.DB, req BulkActionRequest, author history.Author) ([]uint, error) {
+	if req.Filter.IsEmpty() {
+		return nil, ErrEmptyFilter
+	}
+
+	var monitorIDs []uint
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := req.Filter.Apply(tx.Model(&MonitorConfig{})).
+			Order("monitor_configs.id ASC").
+			Pluck("monitor_configs.id", &monitorIDs).Error; err != nil {
+			return err
+		}
+
+		if len(monitorIDs) == 0 {
+			return nil
+		}
+
+		before, err := snapshotMonitors(tx, monitorIDs)
+		if err != nil {
+			return err
+		}
+
+		if err := runBulkAction(tx, req, monitorIDs); err != nil {
+			return err
+		}
+
+		return recordMonitorVersions(tx, monitorIDs, before, author)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return monitorIDs, nil
+}
+
+func runBulkAction(tx *gorm.DB, req BulkActionRequest, monitorIDs []uint) error {
+	switch req.Action {
+	case BulkActionEnable:
+		return enableMonitors(tx, monitorIDs)
+	case BulkActionDisable:
+		return tx.Model(&MonitorConfig{}).
+			Where("id IN ?", monitorIDs).
+			UpdateColumns(map[string]any{
+				"enabled":       false,
+				"running":       false,
+				"running_since": 0,
+				"healthy":       false,
+				"health":        HealthStateDown,
+				"flapping":      false,
+			}).Error
+	case BulkActionDelete:
+		return deleteMonitors(tx, monitorIDs)
+	case BulkActionAssignIntegrations:
+		return assignIntegrations(tx, monitorIDs, req.IntegrationIdList)
+	}
+
+	return nil
+}
+
+// enableMonitors re-enables monitors and, like the update endpoint, starts a
+// fresh period for heartbeat monitors so they are not reported as missed.
+func enableMonitors(tx *gorm.DB, monitorIDs []uint) error {
+	if err := tx.Model(&MonitorConfig{}).
+		Where("id IN ? AND enabled = ?", monitorIDs, false).
+		Where("type = ?", MonitorTypeHeartbeat).
+		UpdateColumn("last_run", clock.System.Now().Unix()).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&MonitorConfig{}).
+		Where("id IN ?", monitorIDs).
+		UpdateColumn("enabled", true).Error
+}
+
+func assignIntegrations(tx *gorm.DB, monitorIDs []uint, integrationIDs []uint) error {
+	integrationIDs = uniqueIDs(integrationIDs)
+	if len(integrationIDs) == 0 {
+		return ErrIntegrationsRequired
+	}
+
+	var integrations []integration.IntegrationConfig
+	if err := tx.Where("id IN ?", integrationIDs).Find(&integrations).Error; err != nil {
+		return err
+	}
+
+	if len(integrations) != len(integrationIDs) {
+		return ErrIntegrationNotFound
+	}
+
+	for _, monitorID := range monitorIDs {
+		monitorConfig := MonitorConfig{ID: monitorID}
+		if err := tx.Model(&monitorConfig).Association("Integrations").Append(&integrations); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteMonitors removes the monitors together with their history and every
+// relation pointing at them.
+func deleteMonitors(tx *gorm.DB, monitorIDs []uint) error {
+	if err := tx.Exec("DELETE FROM monitor_config_integrations WHERE monitor_config_id IN ?", monitorIDs).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("monitor_config_id IN ? OR parent_id IN ?", monitorIDs, monitorIDs).Delete(&MonitorDependency{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("group_id IN ? OR member_id IN ?", monitorIDs, monitorIDs).Delete(&GroupMember{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("monitor_config_id IN ?", monitorIDs).Delete(&MonitorTag{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("monitor_config_id IN ?", monitorIDs).Delete(&escalation.EscalationEvent{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("monitor_config_id IN ?", monitorIDs).Delete(&Attempt{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("id IN ?", monitorIDs).Delete(&MonitorConfig{}).Error
+}
@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+const defaultResponseBodyLimit = 4096
+
+type capturedBody struct {
+	Snippet   string
+	Size      int64
+	Hash      string
+	Truncated bool
+}
+
+// responseBodyLimit is the number of bytes of a response kept on the attempt.
+func responseBodyLimit(monitorConfig MonitorConfig) int {
+	if monitorConfig.ResponseBodyLimit > 0 {
+		return monitorConfig.ResponseBodyLimit
+	}
+
+	if executionSettings.ResponseBodyLimit > 0 {
+		return executionSettings.ResponseBodyLimit
+	}
+
+	return defaultResponseBodyLimit
+}
+
+// captureBody streams the whole body to compute its size and SHA-256 hash,
+// but only keeps the first limit bytes in memory.
+func captureBody(reader io.Reader, limit int) (capturedBody, error) {
+	hasher := sha256.New()
+	snippet := &limitedBuffer{limit: limit}
+
+	size, err := io.Copy(io.MultiWriter(hasher, snippet), reader)
+
+	return capturedBody{
+		Snippet:   strings.ToValidUTF8(snippet.buffer.String(), ""),
+		Size:      size,
+		Hash:      hex.EncodeToString(hasher.Sum(nil)),
+		Truncated: snippet.truncated,
+	}, err
+}
+
+// truncateResponse bounds what is stored for probes that do not stream their
+// body through captureBody, such as command output or transaction bodies.
+func truncateResponse(response string, limit int) (string, bool) {
+	if len(response) <= limit {
+		return response, false
+	}
+
+	return strings.ToValidUTF8(response[:limit], ""), true
+}
@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var csvColumns = []string{"name", "url", "method", "interval"}
+
+// parseMonitorCSV maps a name,url,method,interval CSV onto an import. The
+// header line is optional, method defaults to GET and interval to 60
+// seconds. The monitor type follows the URL scheme.
+func parseMonitorCSV(body []byte) (importSource, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var source importSource
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return importSource{}, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), csvColumns[0]) {
+			continue
+		}
+
+		column := func(i int) string {
+			if i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := source.addRow(ImportRow{
+			Row:    line,
+			Kind:   ChangeKindMonitor,
+			Name:   column(0),
+			Status: ImportRowImported,
+		})
+
+		for i := len(csvColumns); i < len(record); i++ {
+			if column(i) != "" {
+				row.unsupported(fmt.Sprintf("column %d", i+1))
+			}
+		}
+
+		entry, err := csvMonitor(column(0), column(1), column(2), column(3))
+		if err != nil {
+			row.skip("%v", err)
+			continue
+		}
+
+		source.monitors = append(source.monitors, importedMonitor{
+			row:      row,
+			sourceID: strconv.Itoa(line),
+			entry:    entry,
+		})
+	}
+
+	return source, nil
+}
+
+func csvMonitor(name string, rawURL string, method string, rawInterval string) (MonitorManifest, error) {
+	if name == "" {
+		return MonitorManifest{}, errors.New("name is required")
+	}
+
+	interval := defaultImportInterval
+	if rawInterval != "" {
+		parsed, err := strconv.Atoi(rawInterval)
+		if err != nil || parsed <= 0 {
+			return MonitorManifest{}, fmt.Errorf("invalid interval %q", rawInterval)
+		}
+		interval = parsed
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Host == "" {
+		return MonitorManifest{}, fmt.Errorf("invalid url %q", rawURL)
+	}
+
+	entry := MonitorManifest{
+		Name:      name,
+		URL:       rawURL,
+		Interval:  interval,
+		Threshold: 1,
+		Timeout:   importTimeout(interval),
+	}
+
+	switch strings.ToLower(parsedURL.Scheme) {
+	case "http", "https":
+		entry.Type = MonitorTypeHTTP
+		entry.Method = strings.ToUpper(method)
+		if entry.Method == "" {
+			entry.Method = "GET"
+		}
+	case "ws", "wss":
+		entry.Type = MonitorTypeWebsocket
+	case "grpc", "grpcs":
+		entry.Type = MonitorTypeGRPC
+	default:
+		return MonitorManifest{}, fmt.Errorf("url scheme %q is not supported", parsedURL.Scheme)
+	}
+
+	if entry.Type != MonitorTypeHTTP && method != "" {
+		return MonitorManifest{}, fmt.Errorf("method is only supported for http urls")
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,29 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/database/dbtest"
+	"github.com/mateusgcoelho/sentinel/engine/internal/escalation"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"gorm.io/gorm"
+)
+
+// newTestDatabase opens a test database with the tables the monitor package
+// touches.
+func newTestDatabase(tb testing.TB) *gorm.DB {
+	return dbtest.Open(tb,
+		&MonitorConfig{},
+		&Attempt{},
+		&GroupMember{},
+		&MonitorTag{},
+		&integration.IntegrationConfig{},
+		&escalation.EscalationPolicy{},
+		&escalation.EscalationStep{},
+		&escalation.EscalationEvent{},
+		&secret.Secret{},
+		&history.Version{},
+	)
+}
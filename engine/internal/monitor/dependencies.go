@@ -0,0 +1,176 @@
+package monitor
+
+import (
+	"errors"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrParentNotFound  = errors.New("one or more parent monitors not found")
+	ErrDependencyCycle = errors.New("monitor dependencies would create a cycle")
+)
+
+type MonitorDependency struct {
+	MonitorConfigID uint `gorm:"primaryKey"`
+	ParentID        uint `gorm:"primaryKey"`
+}
+
+func (MonitorDependency) TableName() string {
+	return "monitor_config_dependencies"
+}
+
+// loadDependencyGraph returns, for every monitor with dependencies, the list
+// of its parents.
+func loadDependencyGraph(database *gorm.DB) (map[uint][]uint, error) {
+	var dependencies []MonitorDependency
+	if err := database.Find(&dependencies).Error; err != nil {
+		return nil, err
+	}
+
+	graph := map[uint][]uint{}
+	for _, dependency := range dependencies {
+		graph[dependency.MonitorConfigID] = append(graph[dependency.MonitorConfigID], dependency.ParentID)
+	}
+
+	return graph, nil
+}
+
+// createsCycle reports whether adding edges from monitorID to targetIDs would
+// let the monitor reach itself by following the edges of graph. It is used
+// for both parent dependencies and group membership.
+func createsCycle(graph map[uint][]uint, monitorID uint, targetIDs []uint) bool {
+	visited := map[uint]bool{}
+	stack := append([]uint{}, targetIDs...)
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current == monitorID {
+			return true
+		}
+
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		stack = append(stack, graph[current]...)
+	}
+
+	return false
+}
+
+func validateDependencies(database *gorm.DB, monitorID uint, parentIDs []uint) error {
+	if len(parentIDs) == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := database.Model(&MonitorConfig{}).Where("id IN ?", parentIDs).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if int(count) != len(uniqueIDs(parentIDs)) {
+		return ErrParentNotFound
+	}
+
+	graph, err := loadDependencyGraph(database)
+	if err != nil {
+		return err
+	}
+
+	if createsCycle(graph, monitorID, parentIDs) {
+		return ErrDependencyCycle
+	}
+
+	return nil
+}
+
+// blockingParent returns the first direct parent that is down with an open
+// incident, if any, so failures caused by it can be attributed instead of
+// alerted. DOWN alone is also the state of parents that never ran.
+func blockingParent(database *gorm.DB, monitorID uint) (*MonitorConfig, error) {
+	var parents []MonitorConfig
+	if err := database.
+		Joins("JOIN monitor_config_dependencies ON monitor_config_dependencies.parent_id = monitor_configs.id").
+		Where("monitor_config_dependencies.monitor_config_id = ?", monitorID).
+		Where("monitor_configs.enabled = ? AND monitor_configs.health = ?", true, HealthStateDown).
+		Where("monitor_configs.incident_started_at > 0").
+		Order("monitor_configs.id ASC").
+		Limit(1).
+		Find(&parents).Error; err != nil {
+		return nil, err
+	}
+
+	if len(parents) == 0 {
+		return nil, nil
+	}
+
+	return &parents[0], nil
+}
+
+// affectedDependents lists the names of every enabled monitor that depends on
+// monitorID, directly or through other monitors.
+func affectedDependents(database *gorm.DB, monitorID uint) ([]string, error) {
+	graph, err := loadDependencyGraph(database)
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[uint][]uint{}
+	for child, parents := range graph {
+		for _, parent := range parents {
+			children[parent] = append(children[parent], child)
+		}
+	}
+
+	visited := map[uint]bool{monitorID: true}
+	queue := append([]uint{}, children[monitorID]...)
+	var dependentIDs []uint
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		dependentIDs = append(dependentIDs, current)
+
+		queue = append(queue, children[current]...)
+	}
+
+	if len(dependentIDs) == 0 {
+		return nil, nil
+	}
+
+	var names []string
+	if err := database.Model(&MonitorConfig{}).
+		Where("id IN ? AND enabled = ?", dependentIDs, true).
+		Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := map[uint]bool{}
+	var unique []uint
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	return unique
+}
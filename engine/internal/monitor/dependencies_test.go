@@ -0,0 +1,40 @@
+package monitor
+
+import "testing"
+
+func TestBlockingParentRequiresAnOpenIncident(t *testing.T) {
+	database := newTestDatabase(t)
+
+	// A parent created a moment ago is DOWN by default without having run.
+	parent := MonitorConfig{Name: "database", Type: MonitorTypeHTTP, Interval: 60, Threshold: 1, Enabled: true, Health: HealthStateDown}
+	if err := database.Create(&parent).Error; err != nil {
+		t.Fatalf("failed to create parent: %v", err)
+	}
+
+	child := MonitorConfig{Name: "api", Type: MonitorTypeHTTP, Interval: 60, Threshold: 1, Enabled: true, Parents: []MonitorConfig{parent}}
+	if err := database.Create(&child).Error; err != nil {
+		t.Fatalf("failed to create child: %v", err)
+	}
+
+	blockedBy, err := blockingParent(database, child.ID)
+	if err != nil {
+		t.Fatalf("blockingParent failed: %v", err)
+	}
+
+	if blockedBy != nil {
+		t.Fatalf("blocked by %q, which never ran", blockedBy.Name)
+	}
+
+	if err := database.Model(&parent).UpdateColumns(map[string]any{"last_run": 1700000000, "incident_started_at": 1700000000}).Error; err != nil {
+		t.Fatalf("failed to open incident: %v", err)
+	}
+
+	blockedBy, err = blockingParent(database, child.ID)
+	if err != nil {
+		t.Fatalf("blockingParent failed: %v", err)
+	}
+
+	if blockedBy == nil || blockedBy.ID != parent.ID {
+		t.Errorf("blocked by %+v, want the parent with an open incident", blockedBy)
+	}
+}
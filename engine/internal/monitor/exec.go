@@ -0,0 +1,187 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const execOutputLimit = 4096
+
+// execWaitDelay bounds how long a finished or killed command may keep its
+// output open, for example through a child it left running in background.
+const execWaitDelay = time.Second
+
+var (
+	ErrExecDisabled         = errors.New("EXEC monitors are disabled")
+	ErrExecNotAllowed       = errors.New("command is not in the list of allowed executables")
+	ErrExecInvalidCommand   = errors.New("command must be an absolute path")
+	ErrExecReservedVariable = errors.New("environment may not set PATH or dynamic loader variables")
+)
+
+// validateExecCommand only accepts absolute paths listed in the allowlist.
+// Entries ending with a slash allow every executable inside that directory.
+func validateExecCommand(command string) error {
+	if !executionSettings.ExecEnabled {
+		return ErrExecDisabled
+	}
+
+	if !filepath.IsAbs(command) {
+		return ErrExecInvalidCommand
+	}
+
+	command = filepath.Clean(command)
+
+	for _, allowed := range executionSettings.ExecAllowedPaths {
+		if strings.HasSuffix(allowed, "/") {
+			if filepath.Dir(command) == filepath.Clean(allowed) {
+				return nil
+			}
+			continue
+		}
+
+		if command == filepath.Clean(allowed) {
+			return nil
+		}
+	}
+
+	return ErrExecNotAllowed
+}
+
+// validateExecEnvironment refuses variables that change which code an allowed
+// executable runs: PATH and the LD_ and DYLD_ dynamic loader variables.
+func validateExecEnvironment(environment []string) error {
+	for _, variable := range environment {
+		name, _, _ := strings.Cut(variable, "=")
+
+		upper := strings.ToUpper(name)
+		if upper == "PATH" || strings.HasPrefix(upper, "LD_") || strings.HasPrefix(upper, "DYLD_") {
+			return fmt.Errorf("%w: %s", ErrExecReservedVariable, name)
+		}
+	}
+
+	return nil
+}
+
+// executeCommand runs the configured command and maps its exit code like a
+// Nagios plugin: 0 is up, 1 is degraded and anything else is down. The
+// process only sees PATH and the monitor environment, never the server's.
+func executeCommand(monitorConfig MonitorConfig) (ExecutionResponse, error) {
+	if err := validateExecCommand(monitorConfig.Command); err != nil {
+		return ExecutionResponse{}, err
+	}
+
+	if err := validateExecEnvironment(monitorConfig.Environment); err != nil {
+		return ExecutionResponse{}, err
+	}
+
+	timeout := time.Duration(monitorConfig.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, monitorConfig.Command, monitorConfig.Arguments...)
+	// The server's PATH goes last so it wins over anything the monitor sets.
+	cmd.Env = slices.Concat(monitorConfig.Environment, []string{"PATH=" + os.Getenv("PATH")})
+	cmd.WaitDelay = execWaitDelay
+
+	stdout := &limitedBuffer{limit: execOutputLimit}
+	stderr := &limitedBuffer{limit: execOutputLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	startedAt := time.Now()
+	err := cmd.Run()
+
+	executionResponse := ExecutionResponse{
+		ResponseBody: formatCommandOutput(stdout, stderr),
+		ResponseTime: time.Since(startedAt),
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return executionResponse, ErrDeadlineExceeded
+	}
+
+	// The command exited cleanly but left its output open; what it wrote
+	// until then is kept.
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	}
+
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return executionResponse, fmt.Errorf("%w: %v", ErrCommandFailed, err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	executionResponse.StatusCode = exitCode
+
+	switch exitCode {
+	case 0:
+		return executionResponse, nil
+	case 1:
+		executionResponse.Degraded = true
+		return executionResponse, nil
+	default:
+		return executionResponse, fmt.Errorf("%w: exited with code %d: %s", ErrCommandFailed, exitCode, executionResponse.ResponseBody)
+	}
+}
+
+func formatCommandOutput(stdout, stderr *limitedBuffer) string {
+	output := strings.TrimRight(stdout.String(), "\n")
+
+	if stderr.Len() > 0 {
+		if output != "" {
+			output += "\n"
+		}
+		output += "[stderr]\n" + strings.TrimRight(stderr.String(), "\n")
+	}
+
+	return output
+}
+
+// limitedBuffer keeps the first limit bytes written to it and silently drops
+// the rest, so a chatty command cannot exhaust memory.
+type limitedBuffer struct {
+	buffer    bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buffer.Len()
+	if remaining <= 0 {
+		b.truncated = len(p) > 0 || b.truncated
+		return len(p), nil
+	}
+
+	if len(p) > remaining {
+		b.buffer.Write(p[:remaining])
+		b.truncated = true
+		return len(p), nil
+	}
+
+	b.buffer.Write(p)
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Len() int {
+	return b.buffer.Len()
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buffer.String() + "... (truncated)"
+	}
+
+	return b.buffer.String()
+}
@@ -0,0 +1,210 @@
+package monitor
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// allowExec enables EXEC monitors for the test with the given allowlist.
+func allowExec(t *testing.T, allowedPaths ...string) {
+	t.Helper()
+
+	previous := executionSettings
+	executionSettings.ExecEnabled = true
+	executionSettings.ExecAllowedPaths = allowedPaths
+	t.Cleanup(func() { executionSettings = previous })
+}
+
+func shellMonitor(script string, timeout int, environment ...string) MonitorConfig {
+	return MonitorConfig{
+		Type:        MonitorTypeExec,
+		Command:     "/bin/sh",
+		Arguments:   []string{"-c", script},
+		Environment: environment,
+		Timeout:     timeout,
+	}
+}
+
+func TestValidateExecCommand(t *testing.T) {
+	allowExec(t, "/usr/local/bin/check_disk", "/opt/checks/")
+
+	tests := []struct {
+		command string
+		want    error
+	}{
+		{command: "/usr/local/bin/check_disk", want: nil},
+		{command: "/usr/local/bin/../bin/check_disk", want: nil},
+		{command: "/opt/checks/check_http", want: nil},
+		{command: "/opt/checks/nested/check_http", want: ErrExecNotAllowed},
+		{command: "/opt/checks/../../bin/sh", want: ErrExecNotAllowed},
+		{command: "/usr/local/bin/check_load", want: ErrExecNotAllowed},
+		{command: "check_disk", want: ErrExecInvalidCommand},
+		{command: "./opt/checks/check_http", want: ErrExecInvalidCommand},
+	}
+
+	for _, tt := range tests {
+		if err := validateExecCommand(tt.command); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.command, err, tt.want)
+		}
+	}
+
+	executionSettings.ExecEnabled = false
+	if err := validateExecCommand("/usr/local/bin/check_disk"); !errors.Is(err, ErrExecDisabled) {
+		t.Errorf("disabled: err = %v, want %v", err, ErrExecDisabled)
+	}
+}
+
+func TestValidateExecEnvironment(t *testing.T) {
+	tests := []struct {
+		variable string
+		valid    bool
+	}{
+		{variable: "TARGET=db-1", valid: true},
+		{variable: "LOAD_LIMIT=4", valid: true},
+		{variable: "PATH=/tmp/evil", valid: false},
+		{variable: "path=/tmp/evil", valid: false},
+		{variable: "LD_PRELOAD=/tmp/evil.so", valid: false},
+		{variable: "LD_LIBRARY_PATH=/tmp", valid: false},
+		{variable: "DYLD_INSERT_LIBRARIES=/tmp/evil.dylib", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := validateExecEnvironment([]string{"TARGET=db-1", tt.variable})
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: err = %v, want valid %t", tt.variable, err, tt.valid)
+		}
+
+		if err != nil && !errors.Is(err, ErrExecReservedVariable) {
+			t.Errorf("%s: err = %v, want %v", tt.variable, err, ErrExecReservedVariable)
+		}
+	}
+}
+
+func TestCreateRequestRefusesReservedVariables(t *testing.T) {
+	allowExec(t, "/bin/sh")
+
+	req := CreateMonitorConfigRequest{
+		Type:        MonitorTypeExec,
+		Command:     "/bin/sh",
+		Environment: []string{"LD_PRELOAD=/tmp/evil.so"},
+		Timeout:     5,
+	}
+
+	if err := req.Validate(); !errors.Is(err, ErrExecReservedVariable) {
+		t.Errorf("err = %v, want %v", err, ErrExecReservedVariable)
+	}
+}
+
+func TestExecuteCommandMapsExitCodes(t *testing.T) {
+	allowExec(t, "/bin/sh")
+
+	tests := []struct {
+		exitCode int
+		degraded bool
+		failed   bool
+	}{
+		{exitCode: 0},
+		{exitCode: 1, degraded: true},
+		{exitCode: 2, failed: true},
+		{exitCode: 3, failed: true},
+	}
+
+	for _, tt := range tests {
+		response, err := executeCommand(shellMonitor("echo checked; exit "+strconv.Itoa(tt.exitCode), 5))
+
+		if failed := errors.Is(err, ErrCommandFailed); failed != tt.failed || (err != nil && !failed) {
+			t.Errorf("exit %d: err = %v, want failed %t", tt.exitCode, err, tt.failed)
+		}
+
+		if response.StatusCode != tt.exitCode || response.Degraded != tt.degraded {
+			t.Errorf("exit %d: status code %d, degraded %t", tt.exitCode, response.StatusCode, response.Degraded)
+		}
+
+		if response.ResponseBody != "checked" {
+			t.Errorf("exit %d: response body = %q", tt.exitCode, response.ResponseBody)
+		}
+	}
+}
+
+func TestExecuteCommandEnvironment(t *testing.T) {
+	allowExec(t, "/bin/sh")
+	t.Setenv("SENTINEL_SERVER_ONLY", "leaked")
+
+	response, err := executeCommand(shellMonitor(`echo "$TARGET|$SENTINEL_SERVER_ONLY"; test -n "$PATH"`, 5, "TARGET=db-1"))
+	if err != nil {
+		t.Fatalf("expected the command to succeed, got %v", err)
+	}
+
+	if response.ResponseBody != "db-1|" {
+		t.Errorf("response body = %q, want only the monitor environment", response.ResponseBody)
+	}
+
+	// Stored monitors predating the check are refused when they run.
+	if _, err := executeCommand(shellMonitor("true", 5, "LD_PRELOAD=/tmp/evil.so")); !errors.Is(err, ErrExecReservedVariable) {
+		t.Errorf("err = %v, want %v", err, ErrExecReservedVariable)
+	}
+}
+
+func TestExecuteCommandTimeout(t *testing.T) {
+	allowExec(t, "/bin/sh")
+
+	startedAt := time.Now()
+
+	_, err := executeCommand(shellMonitor("sleep 30", 1))
+	if !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, ErrDeadlineExceeded)
+	}
+
+	if elapsed := time.Since(startedAt); elapsed > 1*time.Second+2*execWaitDelay {
+		t.Errorf("returned after %s", elapsed)
+	}
+}
+
+func TestExecuteCommandDoesNotWaitForBackgroundChildren(t *testing.T) {
+	allowExec(t, "/bin/sh")
+
+	startedAt := time.Now()
+
+	// The sleep inherits stdout and keeps it open after the shell exits.
+	response, err := executeCommand(shellMonitor("echo started; sleep 30 & exit 0", 60))
+	if err != nil {
+		t.Fatalf("expected the command to succeed, got %v", err)
+	}
+
+	if elapsed := time.Since(startedAt); elapsed > 2*execWaitDelay+time.Second {
+		t.Errorf("returned after %s", elapsed)
+	}
+
+	if response.ResponseBody != "started" {
+		t.Errorf("response body = %q", response.ResponseBody)
+	}
+}
+
+func TestExecuteCommandTruncatesOutput(t *testing.T) {
+	allowExec(t, "/bin/sh")
+
+	script := "printf '%*s' 10000 '' | tr ' ' a; printf '%*s' 10000 '' | tr ' ' b >&2"
+
+	response, err := executeCommand(shellMonitor(script, 5))
+	if err != nil {
+		t.Fatalf("expected the command to succeed, got %v", err)
+	}
+
+	stdout, stderr, found := strings.Cut(response.ResponseBody, "\n[stderr]\n")
+	if !found {
+		t.Fatalf("response body has no stderr section: %.80q", response.ResponseBody)
+	}
+
+	want := strings.Repeat("a", execOutputLimit) + "... (truncated)"
+	if stdout != want {
+		t.Errorf("stdout has %d bytes, want %d", len(stdout), len(want))
+	}
+
+	want = strings.Repeat("b", execOutputLimit) + "... (truncated)"
+	if stderr != want {
+		t.Errorf("stderr has %d bytes, want %d", len(stderr), len(want))
+	}
+}
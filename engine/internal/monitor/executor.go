@@ -3,14 +3,12 @@ package monitor
 import (
 	"context"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"runtime/debug"
 	"time"
 
-	"github.com/mateusgcoelho/sentinel/engine/internal/discord"
-	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
-	"github.com/mateusgcoelho/sentinel/engine/internal/slack"
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
 	"gorm.io/gorm"
 )
 
@@ -18,107 +16,302 @@ var (
 	ErrDeadlineExceeded = fmt.Errorf("request timeout exceeded")
 )
 
-func ExecuteMonitor(database *gorm.DB, monitorConfig MonitorConfig) {
+// ExecuteMonitor runs a monitor claimed by the caller with claimMonitor and
+// records its result. monitorConfig.RunningSince must hold the claim. The
+// running flag is always released, even when recording fails or the probe
+// panics. The stored attempt is returned, or nil when it could not be saved.
+func ExecuteMonitor(database *gorm.DB, monitorConfig MonitorConfig) *Attempt {
 	logPrefix := fmt.Sprintf("[execute-monitor id=%d name=%s]", monitorConfig.ID, monitorConfig.Name)
 
-	log.Printf("%s executing monitor...", logPrefix)
+	defer releaseMonitor(database, logPrefix, monitorConfig.ID, monitorConfig.RunningSince)
 
-	executionResponse, err := executeHttpRequestToEndpoint(monitorConfig)
+	return runMonitor(database, logPrefix, monitorConfig, monitorConfig.RunningSince)
+}
 
-	isHealthy := err == nil && (executionResponse.StatusCode >= 200 && executionResponse.StatusCode < 300)
-	response := executionResponse.ResponseBody
-	if err != nil {
-		response = err.Error()
+// claimMonitor marks an idle, enabled monitor as running so no other
+// execution starts until it is released. The returned running_since
+// identifies the claim, and is zero when the monitor was not claimed.
+func claimMonitor(database *gorm.DB, monitorID uint) (int64, error) {
+	claimedAt := clock.System.Now().Unix()
+
+	tx := database.Model(&MonitorConfig{}).
+		Where("id = ? AND running = ? AND enabled = ?", monitorID, false, true).
+		UpdateColumns(map[string]any{"running": true, "running_since": claimedAt})
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return 0, nil
 	}
 
-	log.Printf("%s execution completed. healthy: %t", logPrefix, isHealthy)
+	return claimedAt, nil
+}
+
+// runMonitor probes the monitor and records the result. claimedAt is the
+// running_since of the caller's claim, or zero for unclaimed executions,
+// which leave the running flag alone.
+func runMonitor(database *gorm.DB, logPrefix string, monitorConfig MonitorConfig, claimedAt int64) (attempt *Attempt) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("%s panic during execution: %v\n%s", logPrefix, recovered, debug.Stack())
+
+			attempt = recordCheckerError(database, logPrefix, monitorConfig, fmt.Sprintf("panic during execution: %v", recovered))
+		}
+	}()
+
+	log.Printf("%s executing monitor...", logPrefix)
+
+	executionResponse, err := executeProbe(database, monitorConfig)
+
+	return recordExecution(database, logPrefix, monitorConfig, claimedAt, executionResponse, err)
+}
 
+// releaseMonitor clears the running flag if the claim is still the caller's.
+// After the watchdog reset a stuck execution the monitor may have been
+// claimed again, and that newer execution must keep running alone.
+func releaseMonitor(database *gorm.DB, logPrefix string, monitorID uint, claimedAt int64) {
+	if err := database.Model(&MonitorConfig{}).
+		Where("id = ? AND running = ? AND running_since = ?", monitorID, true, claimedAt).
+		UpdateColumns(map[string]any{"running": false, "running_since": 0}).Error; err != nil {
+		log.Printf("%s failed to release running flag: %v", logPrefix, err)
+	}
+}
+
+// recordCheckerError stores a failed attempt caused by the engine itself
+// rather than by the monitored service. It is kept out of the threshold and
+// incident machinery so an engine bug never pages anyone.
+func recordCheckerError(database *gorm.DB, logPrefix string, monitorConfig MonitorConfig, message string) *Attempt {
 	attempt := Attempt{
 		MonitorConfigID: monitorConfig.ID,
-		Healthy:         isHealthy,
-		StatusCode:      executionResponse.StatusCode,
-		Response:        response,
+		Healthy:         false,
+		Health:          HealthStateDown,
+		FailureReason:   FailureReasonCheckerError,
+		Response:        message,
 	}
+
 	if err := database.Create(&attempt).Error; err != nil {
-		log.Printf("%s failed to log attempt: %v", logPrefix, err)
-		return
+		log.Printf("%s failed to log checker error attempt: %v", logPrefix, err)
+		return nil
 	}
 
+	return &attempt
+}
+
+// executeProbe runs the probe of a saved monitor, with its secret references
+// expanded.
+func executeProbe(database *gorm.DB, monitorConfig MonitorConfig) (ExecutionResponse, error) {
+	monitorConfig, err := expandSecrets(database, monitorConfig)
+	if err != nil {
+		return ExecutionResponse{}, err
+	}
+
+	return probe(database, monitorConfig)
+}
+
+func probe(database *gorm.DB, monitorConfig MonitorConfig) (ExecutionResponse, error) {
+	switch monitorConfig.Type {
+	case MonitorTypeHeartbeat:
+		return ExecutionResponse{}, ErrHeartbeatMissed
+	case MonitorTypeGroup:
+		return executeGroup(database, monitorConfig)
+	case MonitorTypeTransaction:
+		return executeTransaction(monitorConfig)
+	case MonitorTypeExec:
+		return executeCommand(monitorConfig)
+	case MonitorTypeGRPC:
+		return executeGrpcHealthCheck(monitorConfig)
+	case MonitorTypeWebsocket:
+		return executeWebsocketCheck(monitorConfig)
+	case MonitorTypeSSE:
+		return executeSSECheck(monitorConfig)
+	default:
+		return executeHttpRequestToEndpoint(monitorConfig)
+	}
+}
+
+// newAttempt turns a probe result into an unsaved attempt.
+func newAttempt(monitorConfig MonitorConfig, executionResponse ExecutionResponse, err error) Attempt {
+	health := evaluateHealth(monitorConfig, executionResponse, err)
+	response, truncated := truncateResponse(executionResponse.ResponseBody, responseBodyLimit(monitorConfig))
+	if err != nil {
+		response = err.Error()
+		truncated = false
+	}
+
+	return Attempt{
+		MonitorConfigID:   monitorConfig.ID,
+		Healthy:           health != HealthStateDown,
+		Health:            health,
+		StatusCode:        executionResponse.StatusCode,
+		ResponseTime:      executionResponse.ResponseTime.Milliseconds(),
+		FailureReason:     classifyFailure(health, executionResponse, err),
+		Response:          response,
+		ResponseSize:      executionResponse.BodySize,
+		ResponseHash:      executionResponse.BodyHash,
+		ResponseTruncated: truncated || executionResponse.BodyTruncated,
+		FinalURL:          executionResponse.FinalURL,
+		Steps:             executionResponse.Steps,
+	}
+}
+
+// recordExecution stores the attempt and runs it through the threshold,
+// flapping and incident machinery shared by every monitor type. A claimed
+// execution, with a non-zero claimedAt, releases its claim with the status
+// update, which only applies while it still owns the claim.
+func recordExecution(database *gorm.DB, logPrefix string, monitorConfig MonitorConfig, claimedAt int64, executionResponse ExecutionResponse, err error) *Attempt {
+	attempt := newAttempt(monitorConfig, executionResponse, err)
+	health := attempt.Health
+	isHealthy := attempt.Healthy
+	failureReason := attempt.FailureReason
+	response, _ := attempt.Response.(string)
+
+	if failureReason != "" {
+		log.Printf("%s execution completed. health: %s, reason: %s", logPrefix, health, failureReason)
+	} else {
+		log.Printf("%s execution completed. health: %s", logPrefix, health)
+	}
+
+	var blockedBy *MonitorConfig
 	if !isHealthy {
-		monitorConfig.FailedAttempts += 1
+		parent, err := blockingParent(database, monitorConfig.ID)
+		if err != nil {
+			log.Printf("%s failed to check parent monitors: %v", logPrefix, err)
+		}
+		blockedBy = parent
 	}
 
-	if monitorConfig.FailedAttempts >= monitorConfig.Threshold && isHealthy {
-		log.Printf("%s monitor has recovered after %d failed attempts", logPrefix, monitorConfig.FailedAttempts)
+	if blockedBy != nil {
+		attempt.BlockedByID = &blockedBy.ID
+	}
+	if err := database.Create(&attempt).Error; err != nil {
+		log.Printf("%s failed to log attempt: %v", logPrefix, err)
+		return nil
+	}
 
-		monitorConfig.FailedAttempts = 0
+	columns := map[string]any{
+		"last_run": gorm.Expr("strftime('%s','now')"),
+		"healthy":  isHealthy,
+		"health":   health,
+	}
 
-		for _, item := range monitorConfig.Integrations {
-			if item.Type == integration.IntegrationTypeDiscord {
-				if err := discord.SendRecoverMessage(item.URL, monitorConfig.Name, monitorConfig.FailedAttempts); err != nil {
-					log.Printf("%s failed to send recovery alert via integration [%s]: %v", logPrefix, item.Name, err)
-				} else {
-					log.Printf("%s recovery alert sent successfully via integration [%s]", logPrefix, item.Name)
-				}
-				continue
-			}
+	flapping := monitorConfig.Flapping
+	if monitorConfig.FlapWindow > 1 && monitorConfig.FlapThreshold > 0 {
+		states, err := recentHealthStates(database, monitorConfig.ID, monitorConfig.FlapWindow)
+		if err != nil {
+			log.Printf("%s failed to load recent health states: %v", logPrefix, err)
+		} else {
+			var changeRate int
+			flapping, changeRate = detectFlapping(states, monitorConfig.FlapWindow, monitorConfig.Flapping, monitorConfig.FlapThreshold)
 
-			if item.Type == integration.IntegrationTypeSlack {
-				if err := slack.SendRecoverMessage(item.URL, monitorConfig.Name, monitorConfig.FailedAttempts); err != nil {
-					log.Printf("%s failed to send recovery alert via integration [%s]: %v", logPrefix, item.Name, err)
-				} else {
-					log.Printf("%s recovery alert sent successfully via integration [%s]", logPrefix, item.Name)
-				}
-				continue
+			if flapping != monitorConfig.Flapping {
+				log.Printf("%s flapping changed to %t (change rate %d%%)", logPrefix, flapping, changeRate)
+
+				sendFlappingMessage(database, logPrefix, flappingIntegrations(monitorConfig), monitorConfig.Name, flapping, changeRate)
 			}
 		}
+	} else {
+		flapping = false
 	}
+	columns["flapping"] = flapping
 
-	if monitorConfig.FailedAttempts > monitorConfig.Threshold*3 && !isHealthy {
-		monitorConfig.FailedAttempts = 1
+	if isHealthy {
+		monitorConfig.FailedAttempts = 0
+	} else {
+		monitorConfig.FailedAttempts += 1
 	}
 
-	if monitorConfig.FailedAttempts == monitorConfig.Threshold && !isHealthy {
-		log.Printf("%s monitor failed after %d attempts", logPrefix, monitorConfig.FailedAttempts)
+	// While flapping, incidents are neither opened nor closed so the flapping
+	// notification replaces the alert/recover pairs. Regular alerting resumes
+	// from the current state once the monitor settles.
+	if !flapping {
+		if isHealthy && monitorConfig.IncidentStartedAt > 0 {
+			log.Printf("%s monitor has recovered", logPrefix)
 
-		for _, item := range monitorConfig.Integrations {
-			if item.Type == integration.IntegrationTypeDiscord {
-				if err := discord.SendAlertMessage(item.URL, monitorConfig.Name, executionResponse.ResponseBody, monitorConfig.FailedAttempts); err != nil {
-					log.Printf("%s failed to send recovery alert via integration [%s]: %v", logPrefix, item.Name, err)
-				} else {
-					log.Printf("%s recovery alert sent successfully via integration [%s]", logPrefix, item.Name)
-				}
-				continue
-			}
+			recoverIncident(database, logPrefix, monitorConfig)
 
-			if item.Type == integration.IntegrationTypeSlack {
-				if err := slack.SendAlertMessage(item.URL, monitorConfig.Name, executionResponse.ResponseBody, monitorConfig.FailedAttempts); err != nil {
-					log.Printf("%s failed to send recovery alert via integration [%s]: %v", logPrefix, item.Name, err)
-				} else {
-					log.Printf("%s recovery alert sent successfully via integration [%s]", logPrefix, item.Name)
-				}
-				continue
+			columns["incident_started_at"] = 0
+			columns["acknowledged_at"] = 0
+		}
+
+		if !isHealthy && blockedBy != nil {
+			log.Printf("%s failure blocked by parent monitor [%s], alert suppressed", logPrefix, blockedBy.Name)
+		}
+
+		if !isHealthy && blockedBy == nil && monitorConfig.FailedAttempts >= monitorConfig.Threshold {
+			if monitorConfig.IncidentStartedAt == 0 {
+				log.Printf("%s monitor failed after %d attempts", logPrefix, monitorConfig.FailedAttempts)
+
+				monitorConfig.IncidentStartedAt = clock.System.Now().Unix()
+				columns["incident_started_at"] = monitorConfig.IncidentStartedAt
 			}
+
+			escalateIncident(database, logPrefix, monitorConfig, alertDetail(failureReason, response))
 		}
 	}
 
-	tx := database.Model(&MonitorConfig{}).
-		Where("id = ? AND enabled = ?", monitorConfig.ID, true).
-		UpdateColumns(map[string]any{
-			"last_run":        gorm.Expr("strftime('%s','now')"),
-			"healthy":         isHealthy,
-			"running":         false,
-			"failed_attempts": monitorConfig.FailedAttempts,
-		})
+	columns["failed_attempts"] = monitorConfig.FailedAttempts
+
+	query := database.Model(&MonitorConfig{}).
+		Where("id = ? AND enabled = ?", monitorConfig.ID, true)
+	if claimedAt > 0 {
+		columns["running"] = false
+		columns["running_since"] = 0
+		query = query.Where("running = ? AND running_since = ?", true, claimedAt)
+	}
+
+	tx := query.UpdateColumns(columns)
 	if tx.Error != nil {
 		log.Printf("%s failed to update monitor status: %v", logPrefix, tx.Error)
-		return
+		return &attempt
+	}
+
+	if tx.RowsAffected == 0 {
+		log.Printf("%s monitor was disabled or its execution reset meanwhile, status not updated", logPrefix)
+		return &attempt
+	}
+
+	// A first result counts as a change even when it matches the initial
+	// health, as groups wait for their members to report.
+	if health != monitorConfig.Health || monitorConfig.LastRun == 0 {
+		refreshGroups(database, monitorConfig.ID)
 	}
+
+	return &attempt
 }
 
 type ExecutionResponse struct {
-	StatusCode   int
-	ResponseBody string
+	StatusCode    int
+	ResponseBody  string
+	ResponseTime  time.Duration
+	Degraded      bool
+	FinalURL      string
+	BodySize      int64
+	BodyHash      string
+	BodyTruncated bool
+	Steps         []StepResult
+}
+
+// evaluateHealth maps a probe result onto the three-state health model. A
+// successful but slow response is reported as degraded instead of down.
+func evaluateHealth(monitorConfig MonitorConfig, executionResponse ExecutionResponse, err error) HealthState {
+	if err != nil {
+		return HealthStateDown
+	}
+
+	if monitorConfig.Type == MonitorTypeHTTP && (executionResponse.StatusCode < 200 || executionResponse.StatusCode >= 300) {
+		return HealthStateDown
+	}
+
+	if executionResponse.Degraded {
+		return HealthStateDegraded
+	}
+
+	if monitorConfig.DegradedResponseTime > 0 && executionResponse.ResponseTime.Milliseconds() > int64(monitorConfig.DegradedResponseTime) {
+		return HealthStateDegraded
+	}
+
+	return HealthStateUp
 }
 
 func executeHttpRequestToEndpoint(monitorConfig MonitorConfig) (response ExecutionResponse, err error) {
@@ -135,28 +328,42 @@ func executeHttpRequestToEndpoint(monitorConfig MonitorConfig) (response Executi
 		return ExecutionResponse{}, fmt.Errorf("failed to create http request: %v", err)
 	}
 
-	client := &http.Client{}
+	for key, value := range monitorConfig.Headers.Data() {
+		req.Header.Set(key, value)
+	}
+
+	client, closeClient, err := newHttpClient(monitorConfig)
+	if err != nil {
+		return ExecutionResponse{}, err
+	}
+	defer closeClient()
+
+	startedAt := time.Now()
+
 	resp, err := client.Do(req)
 	if err != nil {
 		if err == context.DeadlineExceeded || ctx.Err() == context.DeadlineExceeded {
-			return ExecutionResponse{}, ErrDeadlineExceeded
+			return ExecutionResponse{ResponseTime: time.Since(startedAt)}, ErrDeadlineExceeded
 		}
 
-		return ExecutionResponse{}, fmt.Errorf("failed to execute http request: %v", err)
+		return ExecutionResponse{ResponseTime: time.Since(startedAt)}, fmt.Errorf("failed to execute http request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	var responseBody string
+	body, err := captureBody(resp.Body, responseBodyLimit(monitorConfig))
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	responseBody := body.Snippet
 	if err != nil {
 		responseBody = fmt.Sprintf("failed to read response body: %v", err)
-	} else {
-		responseBody = string(bodyBytes)
 	}
 
 	return ExecutionResponse{
-		StatusCode:   resp.StatusCode,
-		ResponseBody: responseBody,
+		StatusCode:    resp.StatusCode,
+		ResponseBody:  responseBody,
+		ResponseTime:  time.Since(startedAt),
+		FinalURL:      resp.Request.URL.String(),
+		BodySize:      body.Size,
+		BodyHash:      body.Hash,
+		BodyTruncated: body.Truncated,
 	}, nil
 }
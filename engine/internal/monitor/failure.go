@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+)
+
+// FailureReason is a stable code describing why an attempt failed, so that
+// alerts and API clients do not have to parse free-text error messages.
+type FailureReason string
+
+const (
+	FailureReasonDNS               FailureReason = "dns_error"
+	FailureReasonConnectionRefused FailureReason = "connection_refused"
+	FailureReasonConnectionReset   FailureReason = "connection_reset"
+	FailureReasonTimeout           FailureReason = "timeout"
+	FailureReasonTLS               FailureReason = "tls_error"
+	FailureReasonAssertion         FailureReason = "assertion_failed"
+	FailureReasonHttpStatus        FailureReason = "http_status"
+	FailureReasonNotServing        FailureReason = "not_serving"
+	FailureReasonCommand           FailureReason = "command_failed"
+	FailureReasonHeartbeatMissed   FailureReason = "heartbeat_missed"
+	FailureReasonHeartbeatFailed   FailureReason = "heartbeat_failed"
+	FailureReasonGroup             FailureReason = "group_unhealthy"
+	FailureReasonInvalidConfig     FailureReason = "invalid_config"
+	FailureReasonCheckerError      FailureReason = "checker_error"
+	FailureReasonUnknown           FailureReason = "unknown"
+)
+
+var (
+	ErrUnexpectedStatus = errors.New("unexpected status code")
+	ErrNotServing       = errors.New("service is not serving")
+	ErrCommandFailed    = errors.New("command failed")
+)
+
+// classifyFailure maps a probe result onto a FailureReason. Network causes
+// are checked first so that, for example, a transaction step that timed out
+// is reported as a timeout rather than a generic step failure.
+func classifyFailure(health HealthState, executionResponse ExecutionResponse, err error) FailureReason {
+	if health != HealthStateDown {
+		return ""
+	}
+
+	if err == nil {
+		return FailureReasonHttpStatus
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return FailureReasonDNS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, ErrDeadlineExceeded) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return FailureReasonTimeout
+	}
+
+	if isTLSError(err) {
+		return FailureReasonTLS
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailureReasonConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return FailureReasonConnectionReset
+	case errors.Is(err, ErrAssertionFailed), errors.Is(err, ErrUnexpectedMessage), errors.Is(err, ErrNoEvent):
+		return FailureReasonAssertion
+	case errors.Is(err, ErrUnexpectedStatus):
+		return FailureReasonHttpStatus
+	case errors.Is(err, ErrNotServing):
+		return FailureReasonNotServing
+	case errors.Is(err, ErrCommandFailed):
+		return FailureReasonCommand
+	case errors.Is(err, ErrHeartbeatMissed):
+		return FailureReasonHeartbeatMissed
+	case errors.Is(err, ErrHeartbeatFailed):
+		return FailureReasonHeartbeatFailed
+	case errors.Is(err, ErrGroupUnhealthy):
+		return FailureReasonGroup
+	case errors.Is(err, ErrInvalidHttpOptions),
+		errors.Is(err, ErrExecDisabled),
+		errors.Is(err, ErrExecNotAllowed),
+		errors.Is(err, ErrExecInvalidCommand),
+		errors.Is(err, secret.ErrSecretNotFound):
+		return FailureReasonInvalidConfig
+	}
+
+	return FailureReasonUnknown
+}
+
+func isTLSError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	var recordHeaderErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	if errors.As(err, &verificationErr) ||
+		errors.As(err, &recordHeaderErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) {
+		return true
+	}
+
+	// Handshake failures from the HTTP/3 stack are not typed.
+	return strings.Contains(err.Error(), "tls: ")
+}
+
+// alertDetail is the message sent with alerts, prefixed by the classified
+// reason and short enough for chat integrations.
+func alertDetail(reason FailureReason, response string) string {
+	const maxLength = 500
+
+	if len(response) > maxLength {
+		response = response[:maxLength] + "..."
+	}
+
+	if response == "" {
+		return string(reason)
+	}
+
+	return fmt.Sprintf("[%s] %s", reason, response)
+}
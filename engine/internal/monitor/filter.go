@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmptyFilter = errors.New("filter must select monitors by at least one field")
+)
+
+type MonitorTag struct {
+	ID              uint   `gorm:"primaryKey" json:"-"`
+	MonitorConfigID uint   `gorm:"not null;index" json:"-"`
+	Key             string `gorm:"not null;index:idx_monitor_tags_key_value" json:"key"`
+	Value           string `gorm:"not null;default:'';index:idx_monitor_tags_key_value" json:"value"`
+}
+
+type TagRequest struct {
+	Key   string `json:"key" binding:"required,excludes=:"`
+	Value string `json:"value"`
+}
+
+// MonitorFilter selects monitors for the list and bulk endpoints. Tags are
+// written as key:value, or as a bare key to match any value, and all of them
+// have to match. A folder also matches its subfolders.
+type MonitorFilter struct {
+	Search  string      `form:"search" json:"search"`
+	Tags    []string    `form:"tag" json:"tags"`
+	Folder  string      `form:"folder" json:"folder"`
+	Health  HealthState `form:"health" json:"health" binding:"omitempty,oneof=UP DEGRADED DOWN"`
+	Enabled *bool       `form:"enabled" json:"enabled"`
+	Type    MonitorType `form:"type" json:"type" binding:"omitempty,oneof=HTTP HEARTBEAT GROUP TRANSACTION EXEC GRPC WEBSOCKET SSE"`
+}
+
+type MonitorListQuery struct {
+	MonitorFilter
+	Sort    string `form:"sort" binding:"omitempty,oneof=name type health folder last_run created_at updated_at"`
+	Order   string `form:"order" binding:"omitempty,oneof=asc desc"`
+	Page    int    `form:"page" binding:"omitempty,min=1"`
+	PerPage int    `form:"per_page" binding:"omitempty,min=1,max=500"`
+	// Slots and Resolution shape the status bar; a resolution of zero uses
+	// each monitor's interval as the slot width.
+	Slots      int `form:"slots" binding:"omitempty,min=1,max=500"`
+	Resolution int `form:"resolution" binding:"omitempty,min=1,max=86400"`
+}
+
+func (f MonitorFilter) IsEmpty() bool {
+	return f.Search == "" && len(f.Tags) == 0 && f.Folder == "" && f.Health == "" && f.Enabled == nil && f.Type == ""
+}
+
+func (f MonitorFilter) Apply(query *gorm.DB) *gorm.DB {
+	if f.Search != "" {
+		pattern := "%" + escapeLike(f.Search) + "%"
+		query = query.Where("(monitor_configs.name LIKE ? ESCAPE '\\' OR monitor_configs.url LIKE ? ESCAPE '\\')", pattern, pattern)
+	}
+
+	for _, tag := range f.Tags {
+		key, value, hasValue := strings.Cut(tag, ":")
+
+		if hasValue {
+			query = query.Where("EXISTS (SELECT 1 FROM monitor_tags WHERE monitor_tags.monitor_config_id = monitor_configs.id AND monitor_tags.key = ? AND monitor_tags.value = ?)", key, value)
+		} else {
+			query = query.Where("EXISTS (SELECT 1 FROM monitor_tags WHERE monitor_tags.monitor_config_id = monitor_configs.id AND monitor_tags.key = ?)", key)
+		}
+	}
+
+	if f.Folder != "" {
+		folder := strings.Trim(f.Folder, "/")
+		query = query.Where("(monitor_configs.folder = ? OR monitor_configs.folder LIKE ? ESCAPE '\\')", folder, escapeLike(folder)+"/%")
+	}
+
+	if f.Health != "" {
+		query = query.Where("monitor_configs.health = ?", f.Health)
+	}
+
+	if f.Enabled != nil {
+		query = query.Where("monitor_configs.enabled = ?", *f.Enabled)
+	}
+
+	if f.Type != "" {
+		query = query.Where("monitor_configs.type = ?", f.Type)
+	}
+
+	return query
+}
+
+// orderClause keeps the historical enabled-first ordering when no sort field
+// is requested. Sort fields are validated by the binding tags.
+func (q MonitorListQuery) orderClause() string {
+	if q.Sort == "" {
+		return "monitor_configs.enabled DESC, monitor_configs.id ASC"
+	}
+
+	order := "ASC"
+	if q.Order == "desc" {
+		order = "DESC"
+	}
+
+	return fmt.Sprintf("monitor_configs.%s %s, monitor_configs.id ASC", q.Sort, order)
+}
+
+func buildTags(reqTags []TagRequest) []MonitorTag {
+	seen := map[string]bool{}
+	tags := make([]MonitorTag, 0, len(reqTags))
+
+	for _, reqTag := range reqTags {
+		key := strings.TrimSpace(reqTag.Key)
+		value := strings.TrimSpace(reqTag.Value)
+
+		if seen[key+":"+value] {
+			continue
+		}
+		seen[key+":"+value] = true
+
+		tags = append(tags, MonitorTag{Key: key, Value: value})
+	}
+
+	return tags
+}
+
+func normalizeFolder(folder string) string {
+	parts := strings.Split(folder, "/")
+	cleaned := parts[:0]
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			cleaned = append(cleaned, part)
+		}
+	}
+
+	return strings.Join(cleaned, "/")
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(value)
+}
@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"github.com/mateusgcoelho/sentinel/engine/internal/escalation"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"gorm.io/gorm"
+)
+
+// stateChangeRate returns the percentage of consecutive samples whose health
+// state differs from the previous one. States are ordered oldest first.
+func stateChangeRate(states []HealthState) int {
+	if len(states) < 2 {
+		return 0
+	}
+
+	changes := 0
+	for i := 1; i < len(states); i++ {
+		if states[i] != states[i-1] {
+			changes++
+		}
+	}
+
+	return changes * 100 / (len(states) - 1)
+}
+
+// detectFlapping applies a simple hysteresis: a monitor starts flapping once
+// the change rate over a full window reaches the threshold and only settles
+// when it drops below half of it, so a rate hovering around the threshold
+// does not toggle. The state is kept until a full window exists, which the
+// prune worker leaves in place whatever the interval.
+func detectFlapping(states []HealthState, window int, wasFlapping bool, threshold int) (bool, int) {
+	if len(states) < window {
+		return wasFlapping, 0
+	}
+
+	rate := stateChangeRate(states)
+
+	if wasFlapping {
+		return rate >= threshold/2, rate
+	}
+
+	return rate >= threshold, rate
+}
+
+func recentHealthStates(database *gorm.DB, monitorConfigID uint, window int) ([]HealthState, error) {
+	var states []HealthState
+	if err := database.Model(&Attempt{}).
+		Where("monitor_config_id = ?", monitorConfigID).
+		Order("id DESC").
+		Limit(window).
+		Pluck("health", &states).Error; err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(states)-1; i < j; i, j = i+1, j-1 {
+		states[i], states[j] = states[j], states[i]
+	}
+
+	return states, nil
+}
+
+func flappingIntegrations(monitorConfig MonitorConfig) []integration.IntegrationConfig {
+	steps := escalation.SortedSteps(escalationPolicyFor(monitorConfig))
+	if len(steps) == 0 {
+		return monitorConfig.Integrations
+	}
+
+	return steps[0].Integrations
+}
@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"testing"
+)
+
+const (
+	up       = HealthStateUp
+	down     = HealthStateDown
+	degraded = HealthStateDegraded
+)
+
+func TestStateChangeRate(t *testing.T) {
+	tests := []struct {
+		states []HealthState
+		want   int
+	}{
+		{states: nil, want: 0},
+		{states: []HealthState{down}, want: 0},
+		{states: []HealthState{up, up, up, up, up}, want: 0},
+		{states: []HealthState{up, down, up, down, up}, want: 100},
+		{states: []HealthState{up, up, down, down, up}, want: 50},
+		{states: []HealthState{up, degraded, degraded, down}, want: 66},
+	}
+
+	for _, tt := range tests {
+		if got := stateChangeRate(tt.states); got != tt.want {
+			t.Errorf("%v: rate = %d, want %d", tt.states, got, tt.want)
+		}
+	}
+}
+
+func TestDetectFlappingHysteresis(t *testing.T) {
+	tests := []struct {
+		name        string
+		states      []HealthState
+		wasFlapping bool
+		want        bool
+	}{
+		{name: "short window keeps state", states: []HealthState{up, down, up}, wasFlapping: false, want: false},
+		{name: "short window keeps flapping", states: []HealthState{up, down, up}, wasFlapping: true, want: true},
+		{name: "starts at threshold", states: []HealthState{up, up, down, down, up}, wasFlapping: false, want: true},
+		{name: "stays below threshold", states: []HealthState{up, up, up, down, down}, wasFlapping: false, want: false},
+		{name: "keeps flapping above half", states: []HealthState{up, up, up, down, down}, wasFlapping: true, want: true},
+		{name: "settles below half", states: []HealthState{up, up, up, up, up}, wasFlapping: true, want: false},
+	}
+
+	for _, tt := range tests {
+		if got, _ := detectFlapping(tt.states, 5, tt.wasFlapping, 50); got != tt.want {
+			t.Errorf("%s: flapping = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPruneAttemptsKeepsFlapWindow(t *testing.T) {
+	database := newTestDatabase(t)
+
+	slow := MonitorConfig{Name: "slow", Type: MonitorTypeHTTP, Interval: 600, Threshold: 1, FlapWindow: 3, FlapThreshold: 50}
+	steady := MonitorConfig{Name: "steady", Type: MonitorTypeHTTP, Interval: 600, Threshold: 1}
+	if err := database.Create(&[]*MonitorConfig{&slow, &steady}).Error; err != nil {
+		t.Fatalf("failed to create monitors: %v", err)
+	}
+
+	// Every attempt is older than the cutoff, one per interval.
+	for i := range 5 {
+		for _, monitorConfig := range []MonitorConfig{slow, steady} {
+			attempt := Attempt{MonitorConfigID: monitorConfig.ID, Health: up, CreatedAt: int64(1000 + i*600)}
+			if err := database.Create(&attempt).Error; err != nil {
+				t.Fatalf("failed to create attempt: %v", err)
+			}
+		}
+	}
+
+	if _, err := pruneAttempts(database, 10000); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+
+	states, err := recentHealthStates(database, slow.ID, slow.FlapWindow)
+	if err != nil {
+		t.Fatalf("failed to load states: %v", err)
+	}
+
+	if len(states) != slow.FlapWindow {
+		t.Errorf("slow monitor kept %d attempts, want a full window of %d", len(states), slow.FlapWindow)
+	}
+
+	var steadyAttempts int64
+	if err := database.Model(&Attempt{}).Where("monitor_config_id = ?", steady.ID).Count(&steadyAttempts).Error; err != nil {
+		t.Fatalf("failed to count attempts: %v", err)
+	}
+
+	if steadyAttempts != 0 {
+		t.Errorf("monitor without flapping detection kept %d attempts", steadyAttempts)
+	}
+
+	var oldest int64
+	if err := database.Model(&Attempt{}).Select("MIN(created_at)").Where("monitor_config_id = ?", slow.ID).Scan(&oldest).Error; err != nil {
+		t.Fatalf("failed to load oldest attempt: %v", err)
+	}
+
+	if oldest != 1000+2*600 {
+		t.Errorf("oldest kept attempt created at %d, want the newest ones kept", oldest)
+	}
+}
@@ -0,0 +1,371 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type AggregationRule string
+
+const (
+	AggregationRuleAll      AggregationRule = "ALL"
+	AggregationRuleAtLeast  AggregationRule = "AT_LEAST"
+	AggregationRuleWeighted AggregationRule = "WEIGHTED"
+)
+
+var (
+	ErrGroupUnhealthy   = errors.New("group aggregation rule not satisfied")
+	ErrMemberNotFound   = errors.New("one or more member monitors not found")
+	ErrMembershipCycle  = errors.New("group membership would create a cycle")
+	ErrInvalidAggregate = errors.New("invalid aggregation rule for the given members")
+)
+
+type GroupMember struct {
+	ID       uint           `gorm:"primaryKey" json:"id"`
+	GroupID  uint           `gorm:"not null;index" json:"group_id"`
+	MemberID uint           `gorm:"not null;index" json:"member_id"`
+	Weight   int            `gorm:"not null;default:1" json:"weight"`
+	Member   *MonitorConfig `gorm:"foreignKey:MemberID" json:"member,omitempty"`
+}
+
+type GroupMemberRequest struct {
+	MonitorID uint `json:"monitor_id" binding:"required"`
+	Weight    int  `json:"weight" binding:"omitempty,min=1"`
+}
+
+type memberState struct {
+	Name   string
+	Health HealthState
+	Weight int
+}
+
+// aggregateHealth applies the group rule to its members. Degraded members
+// count as healthy for the rule, but make the group degraded when it passes.
+// For the weighted rule, minimum is the percentage of the total weight that
+// has to be healthy.
+func aggregateHealth(rule AggregationRule, minimum int, members []memberState) (HealthState, string) {
+	if len(members) == 0 {
+		return HealthStateDown, "group has no enabled members"
+	}
+
+	var healthy, totalWeight, healthyWeight int
+	var down, degraded []string
+
+	for _, member := range members {
+		totalWeight += member.Weight
+
+		switch member.Health {
+		case HealthStateDown:
+			down = append(down, member.Name)
+			continue
+		case HealthStateDegraded:
+			degraded = append(degraded, member.Name)
+		}
+
+		healthy++
+		healthyWeight += member.Weight
+	}
+
+	var satisfied bool
+	switch rule {
+	case AggregationRuleAtLeast:
+		satisfied = healthy >= minimum
+	case AggregationRuleWeighted:
+		satisfied = totalWeight > 0 && healthyWeight*100/totalWeight >= minimum
+	default:
+		satisfied = len(down) == 0
+	}
+
+	summary := fmt.Sprintf("%d/%d members healthy", healthy, len(members))
+	if len(down) > 0 {
+		summary += fmt.Sprintf(" (down: %s)", strings.Join(down, ", "))
+	}
+	if len(degraded) > 0 {
+		summary += fmt.Sprintf(" (degraded: %s)", strings.Join(degraded, ", "))
+	}
+
+	if !satisfied {
+		return HealthStateDown, summary
+	}
+
+	if len(down) > 0 || len(degraded) > 0 {
+		return HealthStateDegraded, summary
+	}
+
+	return HealthStateUp, summary
+}
+
+func validateAggregation(rule AggregationRule, minimum int, memberCount int) error {
+	if memberCount == 0 {
+		return fmt.Errorf("%w: at least one member is required", ErrInvalidAggregate)
+	}
+
+	switch rule {
+	case AggregationRuleAll:
+		return nil
+	case AggregationRuleAtLeast:
+		if minimum < 1 || minimum > memberCount {
+			return fmt.Errorf("%w: minimum must be between 1 and %d", ErrInvalidAggregate, memberCount)
+		}
+	case AggregationRuleWeighted:
+		if minimum < 1 || minimum > 100 {
+			return fmt.Errorf("%w: minimum must be a percentage between 1 and 100", ErrInvalidAggregate)
+		}
+	default:
+		return fmt.Errorf("%w: unknown rule %q", ErrInvalidAggregate, rule)
+	}
+
+	return nil
+}
+
+func buildGroupMembers(database *gorm.DB, groupID uint, reqMembers []GroupMemberRequest) ([]GroupMember, error) {
+	memberIDs := make([]uint, 0, len(reqMembers))
+	for _, reqMember := range reqMembers {
+		memberIDs = append(memberIDs, reqMember.MonitorID)
+	}
+
+	if len(uniqueIDs(memberIDs)) != len(memberIDs) {
+		return nil, fmt.Errorf("%w: members must be unique", ErrInvalidAggregate)
+	}
+
+	var count int64
+	if err := database.Model(&MonitorConfig{}).Where("id IN ?", memberIDs).Count(&count).Error; err != nil {
+		return nil, err
+	}
+
+	if int(count) != len(memberIDs) {
+		return nil, ErrMemberNotFound
+	}
+
+	graph, err := loadMembershipGraph(database)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupID != 0 && createsCycle(graph, groupID, memberIDs) {
+		return nil, ErrMembershipCycle
+	}
+
+	members := make([]GroupMember, 0, len(reqMembers))
+	for _, reqMember := range reqMembers {
+		weight := reqMember.Weight
+		if weight == 0 {
+			weight = 1
+		}
+
+		members = append(members, GroupMember{
+			GroupID:  groupID,
+			MemberID: reqMember.MonitorID,
+			Weight:   weight,
+		})
+	}
+
+	return members, nil
+}
+
+// loadMembershipGraph maps every group to the monitors it contains.
+func loadMembershipGraph(database *gorm.DB) (map[uint][]uint, error) {
+	var members []GroupMember
+	if err := database.Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	graph := map[uint][]uint{}
+	for _, member := range members {
+		graph[member.GroupID] = append(graph[member.GroupID], member.MemberID)
+	}
+
+	return graph, nil
+}
+
+func executeGroup(database *gorm.DB, monitorConfig MonitorConfig) (ExecutionResponse, error) {
+	var members []GroupMember
+	if err := database.
+		Where("group_id = ?", monitorConfig.ID).
+		Preload("Member").
+		Find(&members).Error; err != nil {
+		return ExecutionResponse{}, fmt.Errorf("failed to load group members: %v", err)
+	}
+
+	states := make([]memberState, 0, len(members))
+	pending := 0
+	for _, member := range members {
+		if member.Member == nil || !member.Member.Enabled {
+			continue
+		}
+
+		// Members that never ran still carry their initial state, counting
+		// them would raise a false alert right after the group is created.
+		if member.Member.LastRun == 0 {
+			pending++
+			continue
+		}
+
+		states = append(states, memberState{
+			Name:   member.Member.Name,
+			Health: member.Member.Health,
+			Weight: member.Weight,
+		})
+	}
+
+	if len(states) == 0 && pending > 0 {
+		return ExecutionResponse{
+			ResponseBody: "waiting for members to report",
+			Degraded:     true,
+		}, nil
+	}
+
+	health, summary := aggregateHealth(monitorConfig.AggregationRule, monitorConfig.AggregationMinimum, states)
+
+	executionResponse := ExecutionResponse{
+		ResponseBody: summary,
+		Degraded:     health == HealthStateDegraded,
+	}
+
+	if health == HealthStateDown {
+		return executionResponse, fmt.Errorf("%w: %s", ErrGroupUnhealthy, summary)
+	}
+
+	return executionResponse, nil
+}
+
+// refreshGroup re-evaluates the group. Groups are never polled by the
+// worker, they are evaluated when a member's state or the group's
+// configuration changes. The group is marked for a refresh first, so when it
+// is busy the worker evaluates it once it is released and the change is not
+// lost.
+func refreshGroup(database *gorm.DB, groupID uint) {
+	if err := database.Model(&MonitorConfig{}).
+		Where("id = ? AND type = ?", groupID, MonitorTypeGroup).
+		UpdateColumn("refresh_pending", true).Error; err != nil {
+		log.Printf("[group] failed to mark group %d for a refresh: %v", groupID, err)
+		return
+	}
+
+	evaluateGroup(database, groupID)
+}
+
+// evaluateGroup claims the group like the worker claims a monitor and
+// evaluates it from a fresh read, so its failed attempts and incident state
+// are current. The pending refresh is cleared before the members are read,
+// so changes made meanwhile mark the group again. Busy groups are skipped.
+func evaluateGroup(database *gorm.DB, groupID uint) {
+	claimedAt, err := claimMonitor(database, groupID)
+	if err != nil {
+		log.Printf("[group] failed to claim group %d: %v", groupID, err)
+		return
+	}
+
+	if claimedAt == 0 {
+		return
+	}
+
+	if err := database.Model(&MonitorConfig{}).Where("id = ?", groupID).UpdateColumn("refresh_pending", false).Error; err != nil {
+		log.Printf("[group] failed to clear the refresh of group %d: %v", groupID, err)
+		releaseMonitor(database, "[group]", groupID, claimedAt)
+		return
+	}
+
+	var group MonitorConfig
+	if err := database.
+		Preload("Integrations").
+		Preload("EscalationPolicy.Steps.Integrations").
+		First(&group, groupID).Error; err != nil {
+		log.Printf("[group] failed to load group %d: %v", groupID, err)
+		releaseMonitor(database, "[group]", groupID, claimedAt)
+		return
+	}
+
+	ExecuteMonitor(database, group)
+}
+
+// refreshPendingGroups evaluates the groups whose refresh found them busy.
+func refreshPendingGroups(database *gorm.DB) error {
+	var groupIDs []uint
+	if err := database.Model(&MonitorConfig{}).
+		Where("type = ? AND enabled = ? AND running = ? AND refresh_pending = ?", MonitorTypeGroup, true, false, true).
+		Pluck("id", &groupIDs).Error; err != nil {
+		return err
+	}
+
+	for _, groupID := range groupIDs {
+		evaluateGroup(database, groupID)
+	}
+
+	return nil
+}
+
+// refreshGroups re-evaluates every enabled group containing one of the
+// monitors right away, so groups follow member state changes.
+func refreshGroups(database *gorm.DB, memberIDs ...uint) {
+	var groupIDs []uint
+	if err := database.Model(&GroupMember{}).
+		Joins("JOIN monitor_configs ON monitor_configs.id = group_members.group_id").
+		Where("group_members.member_id IN ? AND monitor_configs.enabled = ?", memberIDs, true).
+		Distinct().
+		Pluck("group_members.group_id", &groupIDs).Error; err != nil {
+		log.Printf("[group] failed to load groups of monitors %v: %v", memberIDs, err)
+		return
+	}
+
+	for _, groupID := range groupIDs {
+		refreshGroup(database, groupID)
+	}
+}
+
+// refreshAllGroups re-evaluates every enabled group, after changes too broad
+// to follow member by member such as imports and bulk actions.
+func refreshAllGroups(database *gorm.DB) {
+	var groupIDs []uint
+	if err := database.Model(&MonitorConfig{}).
+		Where("type = ? AND enabled = ?", MonitorTypeGroup, true).
+		Pluck("id", &groupIDs).Error; err != nil {
+		log.Printf("[group] failed to load groups: %v", err)
+		return
+	}
+
+	for _, groupID := range groupIDs {
+		refreshGroup(database, groupID)
+	}
+}
+
+// escalateGroupIncidents keeps open group incidents escalating between member
+// changes, as repeats and later steps are due on time rather than on state
+// changes. The groups are claimed but not re-evaluated.
+func escalateGroupIncidents(database *gorm.DB) error {
+	var groupIDs []uint
+	if err := database.Model(&MonitorConfig{}).
+		Where("type = ? AND enabled = ? AND running = ? AND flapping = ?", MonitorTypeGroup, true, false, false).
+		Where("incident_started_at > 0 AND acknowledged_at = 0").
+		Pluck("id", &groupIDs).Error; err != nil {
+		return err
+	}
+
+	for _, groupID := range groupIDs {
+		claimedAt, err := claimMonitor(database, groupID)
+		if err != nil || claimedAt == 0 {
+			continue
+		}
+
+		var group MonitorConfig
+		if err := database.
+			Preload("Integrations").
+			Preload("EscalationPolicy.Steps.Integrations").
+			First(&group, groupID).Error; err == nil && group.IncidentStartedAt > 0 {
+			var lastAttempt Attempt
+			database.Where("monitor_config_id = ?", groupID).Order("id DESC").Limit(1).Find(&lastAttempt)
+			response, _ := lastAttempt.Response.(string)
+
+			logPrefix := fmt.Sprintf("[execute-monitor id=%d name=%s]", group.ID, group.Name)
+			escalateIncident(database, logPrefix, group, alertDetail(lastAttempt.FailureReason, response))
+		}
+
+		releaseMonitor(database, "[group]", groupID, claimedAt)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestAggregateHealth(t *testing.T) {
+	members := []memberState{
+		{Name: "api", Health: HealthStateUp, Weight: 6},
+		{Name: "worker", Health: HealthStateDegraded, Weight: 3},
+		{Name: "cron", Health: HealthStateDown, Weight: 1},
+	}
+
+	tests := []struct {
+		name    string
+		rule    AggregationRule
+		minimum int
+		members []memberState
+		want    HealthState
+	}{
+		{name: "all up", rule: AggregationRuleAll, members: members[:1], want: HealthStateUp},
+		{name: "all with a degraded member", rule: AggregationRuleAll, members: members[:2], want: HealthStateDegraded},
+		{name: "all with a down member", rule: AggregationRuleAll, members: members, want: HealthStateDown},
+		{name: "at least reached", rule: AggregationRuleAtLeast, minimum: 2, members: members, want: HealthStateDegraded},
+		{name: "at least missed", rule: AggregationRuleAtLeast, minimum: 3, members: members, want: HealthStateDown},
+		{name: "weighted reached", rule: AggregationRuleWeighted, minimum: 90, members: members, want: HealthStateDegraded},
+		{name: "weighted missed", rule: AggregationRuleWeighted, minimum: 91, members: members, want: HealthStateDown},
+		{name: "weighted all up", rule: AggregationRuleWeighted, minimum: 100, members: members[:1], want: HealthStateUp},
+		{name: "no members", rule: AggregationRuleAtLeast, minimum: 1, want: HealthStateDown},
+	}
+
+	for _, tt := range tests {
+		if got, summary := aggregateHealth(tt.rule, tt.minimum, tt.members); got != tt.want {
+			t.Errorf("%s: health = %s (%s), want %s", tt.name, got, summary, tt.want)
+		}
+	}
+
+	_, summary := aggregateHealth(AggregationRuleAll, 0, members)
+	if want := "2/3 members healthy (down: cron) (degraded: worker)"; summary != want {
+		t.Errorf("summary = %q, want %q", summary, want)
+	}
+}
+
+func TestRefreshGroupLeavesBusyGroupsToTheWorker(t *testing.T) {
+	database := newTestDatabase(t)
+
+	member := MonitorConfig{Name: "api", Type: MonitorTypeHTTP, Interval: 60, Threshold: 1, Enabled: true, Health: HealthStateUp, Healthy: true, LastRun: 1700000000}
+	if err := database.Create(&member).Error; err != nil {
+		t.Fatalf("failed to create member: %v", err)
+	}
+
+	// The group is being evaluated when the member changes.
+	group := MonitorConfig{Name: "platform", Type: MonitorTypeGroup, Interval: 60, Threshold: 1, Enabled: true, Running: true, RunningSince: 1700000000,
+		Members: []GroupMember{{MemberID: member.ID, Weight: 1}}}
+	if err := database.Create(&group).Error; err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+
+	refreshGroup(database, group.ID)
+
+	if attempts := groupAttempts(t, database, group.ID); len(attempts) != 0 {
+		t.Fatalf("busy group was evaluated: %+v", attempts)
+	}
+
+	releaseMonitor(database, "[test]", group.ID, group.RunningSince)
+
+	if err := refreshPendingGroups(database); err != nil {
+		t.Fatalf("failed to refresh pending groups: %v", err)
+	}
+
+	attempts := groupAttempts(t, database, group.ID)
+	if len(attempts) != 1 || attempts[0].Health != HealthStateUp {
+		t.Fatalf("attempts = %+v, want the group evaluated once it was released", attempts)
+	}
+
+	var stored MonitorConfig
+	if err := database.First(&stored, group.ID).Error; err != nil {
+		t.Fatalf("failed to reload group: %v", err)
+	}
+
+	if stored.RefreshPending || stored.Running {
+		t.Errorf("refresh pending = %t, running = %t, want the group settled", stored.RefreshPending, stored.Running)
+	}
+
+	// Nothing is pending anymore, the worker leaves the group alone.
+	if err := refreshPendingGroups(database); err != nil {
+		t.Fatalf("failed to refresh pending groups: %v", err)
+	}
+
+	if attempts := groupAttempts(t, database, group.ID); len(attempts) != 1 {
+		t.Errorf("got %d attempts, want the group evaluated only once", len(attempts))
+	}
+}
+
+func groupAttempts(t *testing.T, database *gorm.DB, groupID uint) []Attempt {
+	t.Helper()
+
+	var attempts []Attempt
+	if err := database.Where("monitor_config_id = ?", groupID).Find(&attempts).Error; err != nil {
+		t.Fatalf("failed to load attempts: %v", err)
+	}
+
+	return attempts
+}
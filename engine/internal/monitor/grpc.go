@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcTarget splits a monitor URL such as grpc://host:port (plaintext) or
+// grpcs://host:port (TLS) into the dial target and whether TLS is used.
+func grpcTarget(rawURL string) (string, bool, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false, err
+	}
+
+	if parsed.Host == "" {
+		return "", false, fmt.Errorf("missing host in %q", rawURL)
+	}
+
+	switch parsed.Scheme {
+	case "grpc":
+		return parsed.Host, false, nil
+	case "grpcs":
+		return parsed.Host, true, nil
+	default:
+		return "", false, fmt.Errorf("unsupported scheme %q, use grpc:// or grpcs://", parsed.Scheme)
+	}
+}
+
+// executeGrpcHealthCheck calls grpc.health.v1.Health/Check. Only SERVING is
+// healthy, NOT_SERVING and UNKNOWN answers are reported as down.
+func executeGrpcHealthCheck(monitorConfig MonitorConfig) (ExecutionResponse, error) {
+	target, useTLS, err := grpcTarget(monitorConfig.URL)
+	if err != nil {
+		return ExecutionResponse{}, fmt.Errorf("invalid grpc target: %v", err)
+	}
+
+	transportCredentials := insecure.NewCredentials()
+	if useTLS {
+		transportCredentials = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return ExecutionResponse{}, fmt.Errorf("failed to create grpc client: %v", err)
+	}
+	defer conn.Close()
+
+	timeout := time.Duration(monitorConfig.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if headers := monitorConfig.Headers.Data(); len(headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(headers))
+	}
+
+	startedAt := time.Now()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: monitorConfig.GrpcService,
+	})
+	if err != nil {
+		executionResponse := ExecutionResponse{
+			StatusCode:   int(status.Code(err)),
+			ResponseTime: time.Since(startedAt),
+		}
+
+		if ctx.Err() == context.DeadlineExceeded {
+			return executionResponse, ErrDeadlineExceeded
+		}
+
+		return executionResponse, fmt.Errorf("failed to execute grpc health check: %v", status.Convert(err).Message())
+	}
+
+	executionResponse := ExecutionResponse{
+		ResponseBody: resp.GetStatus().String(),
+		ResponseTime: time.Since(startedAt),
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return executionResponse, fmt.Errorf("%w: grpc service is %s", ErrNotServing, resp.GetStatus())
+	}
+
+	return executionResponse, nil
+}
@@ -0,0 +1,173 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"gorm.io/datatypes"
+)
+
+// startHealthServer serves the standard health service on a localhost port
+// and returns its grpc:// URL. The interceptor sees every call first.
+func startHealthServer(t *testing.T, interceptor grpc.UnaryServerInterceptor) (*health.Server, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	var options []grpc.ServerOption
+	if interceptor != nil {
+		options = append(options, grpc.UnaryInterceptor(interceptor))
+	}
+
+	server := grpc.NewServer(options...)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return healthServer, "grpc://" + listener.Addr().String()
+}
+
+func grpcMonitor(url string, service string) MonitorConfig {
+	return MonitorConfig{
+		Type:        MonitorTypeGRPC,
+		URL:         url,
+		GrpcService: service,
+		Timeout:     1,
+		Headers:     datatypes.NewJSONType(map[string]string{}),
+	}
+}
+
+func TestGrpcHealthCheckServing(t *testing.T) {
+	healthServer, url := startHealthServer(t, nil)
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+
+	response, err := executeGrpcHealthCheck(grpcMonitor(url, "orders"))
+	if err != nil {
+		t.Fatalf("expected a healthy check, got %v", err)
+	}
+
+	if response.ResponseBody != "SERVING" {
+		t.Errorf("response body = %q, want SERVING", response.ResponseBody)
+	}
+}
+
+func TestGrpcHealthCheckNotServing(t *testing.T) {
+	healthServer, url := startHealthServer(t, nil)
+
+	for _, servingStatus := range []healthpb.HealthCheckResponse_ServingStatus{
+		healthpb.HealthCheckResponse_NOT_SERVING,
+		healthpb.HealthCheckResponse_SERVICE_UNKNOWN,
+	} {
+		healthServer.SetServingStatus("orders", servingStatus)
+
+		response, err := executeGrpcHealthCheck(grpcMonitor(url, "orders"))
+		if !errors.Is(err, ErrNotServing) {
+			t.Fatalf("%s: expected ErrNotServing, got %v", servingStatus, err)
+		}
+
+		if response.ResponseBody != servingStatus.String() {
+			t.Errorf("%s: response body = %q", servingStatus, response.ResponseBody)
+		}
+
+		if reason := classifyFailure(HealthStateDown, response, err); reason != FailureReasonNotServing {
+			t.Errorf("%s: failure reason = %s, want %s", servingStatus, reason, FailureReasonNotServing)
+		}
+	}
+}
+
+func TestGrpcHealthCheckUnregisteredService(t *testing.T) {
+	_, url := startHealthServer(t, nil)
+
+	response, err := executeGrpcHealthCheck(grpcMonitor(url, "missing"))
+	if err == nil {
+		t.Fatal("expected an unregistered service to fail")
+	}
+
+	if response.StatusCode != int(codes.NotFound) {
+		t.Errorf("status code = %d, want %d", response.StatusCode, codes.NotFound)
+	}
+}
+
+func TestGrpcHealthCheckSendsHeadersAsMetadata(t *testing.T) {
+	received := make(chan metadata.MD, 1)
+
+	healthServer, url := startHealthServer(t, func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		received <- md
+		return handler(ctx, req)
+	})
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+
+	monitorConfig := grpcMonitor(url, "")
+	monitorConfig.Headers = datatypes.NewJSONType(map[string]string{"Authorization": "Bearer token", "x-tenant": "acme"})
+
+	if _, err := executeGrpcHealthCheck(monitorConfig); err != nil {
+		t.Fatalf("expected a healthy check, got %v", err)
+	}
+
+	md := <-received
+	if got := md.Get("authorization"); len(got) != 1 || got[0] != "Bearer token" {
+		t.Errorf("authorization metadata = %v", got)
+	}
+	if got := md.Get("x-tenant"); len(got) != 1 || got[0] != "acme" {
+		t.Errorf("x-tenant metadata = %v", got)
+	}
+}
+
+func TestGrpcHealthCheckDeadline(t *testing.T) {
+	_, url := startHealthServer(t, func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	response, err := executeGrpcHealthCheck(grpcMonitor(url, ""))
+	if !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("expected ErrDeadlineExceeded, got %v", err)
+	}
+
+	if response.StatusCode != int(codes.DeadlineExceeded) {
+		t.Errorf("status code = %d, want %d", response.StatusCode, codes.DeadlineExceeded)
+	}
+
+	if reason := classifyFailure(HealthStateDown, response, err); reason != FailureReasonTimeout {
+		t.Errorf("failure reason = %s, want %s", reason, FailureReasonTimeout)
+	}
+}
+
+func TestGrpcTargetSchemes(t *testing.T) {
+	tests := []struct {
+		url     string
+		target  string
+		useTLS  bool
+		wantErr bool
+	}{
+		{url: "grpc://localhost:50051", target: "localhost:50051"},
+		{url: "grpcs://api.example.com:443", target: "api.example.com:443", useTLS: true},
+		{url: "http://localhost:50051", wantErr: true},
+		{url: "grpc://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		target, useTLS, err := grpcTarget(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %t", tt.url, err, tt.wantErr)
+			continue
+		}
+
+		if target != tt.target || useTLS != tt.useTLS {
+			t.Errorf("%s: got %q tls=%t, want %q tls=%t", tt.url, target, useTLS, tt.target, tt.useTLS)
+		}
+	}
+}
@@ -1,35 +1,75 @@
 package monitor
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"github.com/mateusgcoelho/sentinel/engine/internal/escalation"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
 	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/pagination"
+	"github.com/mateusgcoelho/sentinel/engine/internal/slug"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 type MonitorHandler struct {
 	database *gorm.DB
+
+	accessMiddleware gin.HandlerFunc
+	adminMiddleware  gin.HandlerFunc
 }
 
-func NewHandler(db *gorm.DB) *MonitorHandler {
+func NewHandler(db *gorm.DB, accessMiddleware gin.HandlerFunc, adminMiddleware gin.HandlerFunc) *MonitorHandler {
 	return &MonitorHandler{
-		database: db,
+		database:         db,
+		accessMiddleware: accessMiddleware,
+		adminMiddleware:  adminMiddleware,
 	}
 }
 
 func (h *MonitorHandler) SetupRoutes(r *gin.Engine) {
 	monitors := r.Group("/monitors")
 	{
-		monitors.POST("", h.HandleCreateMonitor)
-		monitors.GET("", h.HandleListMonitors)
-		monitors.PUT("/:id", h.HandleUpdateMonitor)
-		monitors.GET("/:id", h.HandleGetMonitorDetails)
+		monitors.POST("", audit.Action("monitor.create"), h.accessMiddleware, h.HandleCreateMonitor)
+		monitors.GET("", h.accessMiddleware, h.HandleListMonitors)
+		monitors.POST("/test", h.accessMiddleware, h.HandleTestMonitor)
+		monitors.POST("/bulk", audit.Action("monitor.bulk"), h.accessMiddleware, h.HandleBulkAction)
+		monitors.POST("/openapi", audit.Action("monitor.create_openapi"), h.accessMiddleware, h.HandleCreateOpenAPIMonitors)
+		monitors.POST("/openapi/preview", h.accessMiddleware, h.HandlePreviewOpenAPIMonitors)
+		monitors.PUT("/:id", audit.Action("monitor.update"), h.accessMiddleware, h.HandleUpdateMonitor)
+		monitors.GET("/:id", h.accessMiddleware, h.HandleGetMonitorDetails)
+		monitors.POST("/:id/run", audit.Action("monitor.run"), h.accessMiddleware, h.HandleRunMonitor)
+		monitors.POST("/:id/acknowledge", audit.Action("monitor.acknowledge"), h.accessMiddleware, h.HandleAcknowledgeMonitor)
+		monitors.GET("/:id/escalations", h.accessMiddleware, h.HandleListEscalationEvents)
+		monitors.GET("/:id/versions", h.accessMiddleware, h.HandleListMonitorVersions)
+		monitors.POST("/:id/versions/:version/rollback", audit.Action("monitor.rollback"), h.accessMiddleware, h.HandleRollbackMonitor)
+	}
+
+	heartbeats := r.Group("/heartbeat")
+	{
+		heartbeats.POST("/:token", h.HandleHeartbeatPing)
+		heartbeats.POST("/:token/:kind", h.HandleHeartbeatPing)
+	}
+
+	config := r.Group("/config")
+	{
+		config.GET("/export", h.accessMiddleware, h.HandleExportConfig)
+		config.POST("/import", audit.Action("config.import"), h.adminMiddleware, h.HandleImportConfig)
+		config.POST("/import/uptime-kuma", audit.Action("config.import_uptime_kuma"), h.adminMiddleware, h.HandleImportUptimeKuma)
+		config.POST("/import/csv", audit.Action("config.import_csv"), h.adminMiddleware, h.HandleImportCSV)
 	}
 
 	events := r.Group("/events")
 	{
-		events.GET("", h.HandleListAttempts)
+		events.GET("", h.accessMiddleware, h.HandleListAttempts)
 	}
 }
 
@@ -51,7 +91,7 @@ func (h *MonitorHandler) HandleListAttempts(c *gin.Context) {
 
 func (h *MonitorHandler) HandleGetMonitorDetails(c *gin.Context) {
 	var monitor MonitorConfig
-	if err := h.database.Preload("Integrations").First(&monitor, c.Param("id")).Error; err != nil {
+	if err := h.database.Preload("Integrations").Preload("EscalationPolicy").Preload("Parents").Preload("Members.Member").Preload("Tags").First(&monitor, c.Param("id")).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"message": "monitor not found"})
 		return
 	}
@@ -66,11 +106,22 @@ func (h *MonitorHandler) HandleCreateMonitor(c *gin.Context) {
 		return
 	}
 
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
 	if len(req.IntegrationIdList) <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "at least one integration is required"})
 		return
 	}
 
+	monitorSlug, err := resolveMonitorSlug(h.database, req.Slug, req.Name, 0)
+	if err != nil {
+		respondSlugError(c, err)
+		return
+	}
+
 	var integrations []integration.IntegrationConfig
 	if err := h.database.
 		Where("id IN ?", req.IntegrationIdList).
@@ -84,55 +135,219 @@ func (h *MonitorHandler) HandleCreateMonitor(c *gin.Context) {
 		return
 	}
 
-	monitor := MonitorConfig{
-		Name:         req.Name,
-		URL:          req.URL,
-		Method:       req.Method,
-		Interval:     req.Interval,
-		Threshold:    req.Threshold,
-		Timeout:      req.Timeout,
-		Healthy:      false,
-		Running:      false,
-		Integrations: integrations,
+	if req.EscalationPolicyID != nil {
+		if err := h.database.First(&escalation.EscalationPolicy{}, *req.EscalationPolicyID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "escalation policy not found"})
+			return
+		}
+	}
+
+	var parents []MonitorConfig
+	if len(req.ParentIdList) > 0 {
+		if err := validateDependencies(h.database, 0, req.ParentIdList); err != nil {
+			respondDependencyError(c, err)
+			return
+		}
+
+		if err := h.database.Where("id IN ?", req.ParentIdList).Find(&parents).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve parent monitors"})
+			return
+		}
 	}
 
-	if err := h.database.Create(&monitor).Error; err != nil {
+	var members []GroupMember
+	if req.MonitorType() == MonitorTypeGroup {
+		built, err := buildGroupMembers(h.database, 0, req.MemberList)
+		if err != nil {
+			respondGroupError(c, err)
+			return
+		}
+		members = built
+	}
+
+	monitor := req.monitorConfig()
+	monitor.Slug = &monitorSlug
+	monitor.Integrations = integrations
+	monitor.Parents = parents
+	monitor.Members = members
+
+	if err := validateHttpClientOptions(monitor); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if monitor.Type == MonitorTypeHeartbeat {
+		token, err := generateHeartbeatToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to generate heartbeat token"})
+			return
+		}
+
+		monitor.HeartbeatToken = &token
+		// The first period starts now instead of at the epoch, otherwise a
+		// fresh heartbeat monitor would be reported as missed right away.
+		monitor.LastRun = clock.System.Now().Unix()
+	}
+
+	if err := h.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&monitor).Error; err != nil {
+			return err
+		}
+
+		return recordMonitorVersions(tx, []uint{monitor.ID}, nil, history.AuthorFromContext(c))
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to create monitor"})
 		return
 	}
 
+	if monitor.Type == MonitorTypeGroup {
+		go refreshGroup(h.database, monitor.ID)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"message": "monitor created successfully", "data": monitor})
 }
 
 func (h *MonitorHandler) HandleListMonitors(c *gin.Context) {
+	var query MonitorListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	var total int64
+	if err := query.Apply(h.database.Model(&MonitorConfig{})).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to count monitors"})
+		return
+	}
+
+	page := max(query.Page, 1)
+
+	// Without per_page every monitor is returned in a single page, which is
+	// what the dashboard expects.
+	perPage := query.PerPage
+	if perPage == 0 {
+		perPage = max(int(total), 1)
+	}
+
 	var monitors []MonitorConfig
 
-	if err := h.database.
-		Order("enabled DESC").
+	if err := query.Apply(h.database.Model(&MonitorConfig{})).
+		Order(query.orderClause()).
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Preload("Tags").
 		Find(&monitors).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve monitors"})
 		return
 	}
 
-	for i, monitor := range monitors {
-		var attempts []Attempt
+	slotCount := query.Slots
+	if slotCount == 0 {
+		slotCount = defaultSlotCount
+	}
+
+	if err := loadSlots(h.database, monitors, slotCount, query.Resolution); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve monitor slots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       monitors,
+		"pagination": pagination.New(int(total), perPage, page),
+	})
+}
 
-		cutoff := int64(monitor.Interval * 27)
+func (h *MonitorHandler) HandleBulkAction(c *gin.Context) {
+	var req BulkActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
 
-		if err := h.database.
-			Where("monitor_config_id = ? AND created_at >= strftime('%s', 'now') - ?", monitor.ID, cutoff).
-			Order("id DESC").
-			Limit(25).
-			Find(&attempts).Error; err != nil {
-			continue
+	monitorIDs, err := applyBulkAction(h.database, req, history.AuthorFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEmptyFilter), errors.Is(err, ErrIntegrationsRequired), errors.Is(err, ErrIntegrationNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to apply bulk action"})
 		}
+		return
+	}
+
+	if len(monitorIDs) > 0 && req.Action != BulkActionAssignIntegrations {
+		go refreshAllGroups(h.database)
+	}
+
+	if monitorIDs == nil {
+		monitorIDs = []uint{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "bulk action applied successfully",
+		"data": gin.H{
+			"action":      req.Action,
+			"affected":    len(monitorIDs),
+			"monitor_ids": monitorIDs,
+		},
+	})
+}
+
+// HandlePreviewOpenAPIMonitors lists the operations of an OpenAPI document
+// with the monitor proposed for each one, without creating anything.
+func (h *MonitorHandler) HandlePreviewOpenAPIMonitors(c *gin.Context) {
+	var req OpenAPIRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	operations, err := parseOpenAPI(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": operations})
+}
+
+func (h *MonitorHandler) HandleCreateOpenAPIMonitors(c *gin.Context) {
+	var req OpenAPIRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
 
-		monitors[i].Slots = generateSlots(attempts, monitor.Interval)
+	monitors, err := createOpenAPIMonitors(h.database, req, history.AuthorFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidOpenAPI), errors.Is(err, ErrBaseURLRequired), errors.Is(err, ErrOperationNotEligible),
+			errors.Is(err, ErrIntegrationsRequired), errors.Is(err, ErrIntegrationNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to create monitors"})
+		}
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": monitors})
+	c.JSON(http.StatusCreated, gin.H{"message": "monitors created successfully", "data": monitors})
 }
 
+// monitorUpdateColumns are the configuration columns written by
+// HandleUpdateMonitor. The runtime state is owned by the worker and only
+// reset, through monitorStateColumns, when a monitor is enabled or disabled.
+var monitorUpdateColumns = []string{
+	"slug", "name", "folder", "url", "method", "interval", "threshold", "timeout", "grace_period",
+	"headers", "grpc_service", "subprotocol", "send_message", "expect_message", "http_version",
+	"ip_version", "disable_redirects", "max_redirects", "tls_skip_verify", "tls_ca_certificate",
+	"tls_client_certificate", "tls_client_key", "proxy_url", "dns_server", "response_body_limit",
+	"degraded_response_time", "flap_window", "flap_threshold", "escalation_policy_id",
+	"aggregation_rule", "aggregation_minimum", "steps", "command", "arguments", "environment",
+	"updated_at",
+}
+
+var monitorStateColumns = []string{"enabled", "running", "running_since", "healthy", "health", "flapping", "last_run"}
+
 func (m *MonitorHandler) HandleUpdateMonitor(c *gin.Context) {
 	var req UpdateMonitorConfigRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -146,9 +361,31 @@ func (m *MonitorHandler) HandleUpdateMonitor(c *gin.Context) {
 		return
 	}
 
+	before, err := snapshotMonitors(m.database, []uint{monitor.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve monitor"})
+		return
+	}
+
+	if req.Slug != nil {
+		if !slug.Valid(*req.Slug) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": ErrInvalidSlug.Error()})
+			return
+		}
+
+		monitorSlug, err := resolveMonitorSlug(m.database, *req.Slug, monitor.Name, monitor.ID)
+		if err != nil {
+			respondSlugError(c, err)
+			return
+		}
+		monitor.Slug = &monitorSlug
+	}
 	if req.Name != nil {
 		monitor.Name = *req.Name
 	}
+	if req.Folder != nil {
+		monitor.Folder = normalizeFolder(*req.Folder)
+	}
 	if req.URL != nil {
 		monitor.URL = *req.URL
 	}
@@ -164,11 +401,111 @@ func (m *MonitorHandler) HandleUpdateMonitor(c *gin.Context) {
 	if req.Timeout != nil {
 		monitor.Timeout = *req.Timeout
 	}
+	if req.GracePeriod != nil {
+		monitor.GracePeriod = *req.GracePeriod
+	}
+	if req.Steps != nil && monitor.Type == MonitorTypeTransaction {
+		monitor.Steps = *req.Steps
+	}
+	if req.Command != nil && monitor.Type == MonitorTypeExec {
+		if err := validateExecCommand(*req.Command); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		monitor.Command = *req.Command
+	}
+	if req.Headers != nil {
+		monitor.Headers = datatypes.NewJSONType(*req.Headers)
+	}
+	if req.GrpcService != nil {
+		monitor.GrpcService = *req.GrpcService
+	}
+	if req.Subprotocol != nil {
+		monitor.Subprotocol = *req.Subprotocol
+	}
+	if req.SendMessage != nil {
+		monitor.SendMessage = *req.SendMessage
+	}
+	if req.ExpectMessage != nil {
+		monitor.ExpectMessage = *req.ExpectMessage
+	}
+	if req.HttpVersion != nil {
+		monitor.HttpVersion = *req.HttpVersion
+	}
+	if req.IPVersion != nil {
+		monitor.IPVersion = *req.IPVersion
+	}
+	if req.DisableRedirects != nil {
+		monitor.DisableRedirects = *req.DisableRedirects
+	}
+	if req.MaxRedirects != nil {
+		monitor.MaxRedirects = *req.MaxRedirects
+	}
+	if req.TLSSkipVerify != nil {
+		monitor.TLSSkipVerify = *req.TLSSkipVerify
+	}
+	if req.TLSCACertificate != nil {
+		monitor.TLSCACertificate = *req.TLSCACertificate
+	}
+	if req.TLSClientCertificate != nil {
+		monitor.TLSClientCertificate = *req.TLSClientCertificate
+	}
+	if req.TLSClientKey != nil {
+		monitor.TLSClientKey = *req.TLSClientKey
+	}
+	if req.ProxyURL != nil {
+		monitor.ProxyURL = *req.ProxyURL
+	}
+	if req.DNSServer != nil {
+		monitor.DNSServer = *req.DNSServer
+	}
+	if req.ResponseBodyLimit != nil {
+		monitor.ResponseBodyLimit = *req.ResponseBodyLimit
+	}
+	if err := validateHttpClientOptions(monitor); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if req.Arguments != nil {
+		monitor.Arguments = *req.Arguments
+	}
+	if req.Environment != nil {
+		if err := validateExecEnvironment(*req.Environment); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		monitor.Environment = *req.Environment
+	}
+	if req.DegradedResponseTime != nil {
+		monitor.DegradedResponseTime = *req.DegradedResponseTime
+	}
+	if req.FlapWindow != nil {
+		monitor.FlapWindow = *req.FlapWindow
+	}
+	if req.FlapThreshold != nil {
+		monitor.FlapThreshold = *req.FlapThreshold
+	}
 	if req.Enabled != nil {
 		monitor.Enabled = *req.Enabled
 		if !*req.Enabled {
 			monitor.Running = false
+			monitor.RunningSince = 0
 			monitor.Healthy = false
+			monitor.Health = HealthStateDown
+			monitor.Flapping = false
+		} else if monitor.Type == MonitorTypeHeartbeat {
+			monitor.LastRun = clock.System.Now().Unix()
+		}
+	}
+	if req.EscalationPolicyID != nil {
+		if *req.EscalationPolicyID == 0 {
+			monitor.EscalationPolicyID = nil
+		} else {
+			if err := m.database.First(&escalation.EscalationPolicy{}, *req.EscalationPolicyID).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": "escalation policy not found"})
+				return
+			}
+			monitor.EscalationPolicyID = req.EscalationPolicyID
 		}
 	}
 	if req.IntegrationIdList != nil {
@@ -190,18 +527,544 @@ func (m *MonitorHandler) HandleUpdateMonitor(c *gin.Context) {
 			return
 		}
 
-		if err := m.database.Model(&monitor).Association("Integrations").Clear(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to clear existing integrations"})
+		monitor.Integrations = integrations
+	}
+
+	if req.ParentIdList != nil {
+		if err := validateDependencies(m.database, monitor.ID, *req.ParentIdList); err != nil {
+			respondDependencyError(c, err)
 			return
 		}
 
-		monitor.Integrations = integrations
+		var parents []MonitorConfig
+		if len(*req.ParentIdList) > 0 {
+			if err := m.database.Where("id IN ?", *req.ParentIdList).Find(&parents).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve parent monitors"})
+				return
+			}
+		}
+
+		monitor.Parents = parents
 	}
 
-	if err := m.database.Save(&monitor).Error; err != nil {
+	var members []GroupMember
+	if monitor.Type == MonitorTypeGroup && (req.AggregationRule != nil || req.AggregationMinimum != nil || req.MemberList != nil) {
+		if req.AggregationRule != nil {
+			monitor.AggregationRule = *req.AggregationRule
+		}
+		if req.AggregationMinimum != nil {
+			monitor.AggregationMinimum = *req.AggregationMinimum
+		}
+
+		memberCount := 0
+		if req.MemberList != nil {
+			memberCount = len(*req.MemberList)
+		} else {
+			var count int64
+			if err := m.database.Model(&GroupMember{}).Where("group_id = ?", monitor.ID).Count(&count).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve group members"})
+				return
+			}
+			memberCount = int(count)
+		}
+
+		if err := validateAggregation(monitor.AggregationRule, monitor.AggregationMinimum, memberCount); err != nil {
+			respondGroupError(c, err)
+			return
+		}
+
+		if req.MemberList != nil {
+			built, err := buildGroupMembers(m.database, monitor.ID, *req.MemberList)
+			if err != nil {
+				respondGroupError(c, err)
+				return
+			}
+			members = built
+		}
+	}
+
+	if req.Tags != nil {
+		monitor.Tags = buildTags(*req.Tags)
+	}
+
+	if members != nil {
+		monitor.Members = members
+	}
+
+	columns := slices.Clone(monitorUpdateColumns)
+	if req.Enabled != nil {
+		columns = append(columns, monitorStateColumns...)
+	}
+
+	if err := m.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&monitor).Select(columns).Updates(&monitor).Error; err != nil {
+			return err
+		}
+
+		if req.IntegrationIdList != nil {
+			if err := tx.Model(&monitor).Association("Integrations").Replace(monitor.Integrations); err != nil {
+				return err
+			}
+		}
+
+		if req.ParentIdList != nil {
+			if err := tx.Where("monitor_config_id = ?", monitor.ID).Delete(&MonitorDependency{}).Error; err != nil {
+				return err
+			}
+
+			for _, parent := range monitor.Parents {
+				if err := tx.Create(&MonitorDependency{MonitorConfigID: monitor.ID, ParentID: parent.ID}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if req.Tags != nil {
+			if err := tx.Where("monitor_config_id = ?", monitor.ID).Delete(&MonitorTag{}).Error; err != nil {
+				return err
+			}
+
+			for i := range monitor.Tags {
+				monitor.Tags[i].MonitorConfigID = monitor.ID
+			}
+
+			if len(monitor.Tags) > 0 {
+				if err := tx.Create(&monitor.Tags).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if members != nil {
+			if err := tx.Where("group_id = ?", monitor.ID).Delete(&GroupMember{}).Error; err != nil {
+				return err
+			}
+
+			if len(members) > 0 {
+				if err := tx.Create(&monitor.Members).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return recordMonitorVersions(tx, []uint{monitor.ID}, before, history.AuthorFromContext(c))
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to update monitor"})
 		return
 	}
 
+	if monitor.Type == MonitorTypeGroup {
+		go refreshGroup(m.database, monitor.ID)
+	}
+	if req.Enabled != nil {
+		go refreshGroups(m.database, monitor.ID)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "monitor updated successfully", "data": monitor})
 }
+
+func (h *MonitorHandler) HandleRunMonitor(c *gin.Context) {
+	var monitor MonitorConfig
+	if err := h.database.
+		Preload("Integrations").
+		Preload("EscalationPolicy.Steps.Integrations").
+		First(&monitor, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "monitor not found"})
+		return
+	}
+
+	if !monitor.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"message": "monitor is disabled"})
+		return
+	}
+
+	if monitor.Type == MonitorTypeHeartbeat {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "heartbeat monitors are only run by their pings"})
+		return
+	}
+
+	// Claim the monitor like the worker does, so a manual run never overlaps
+	// a scheduled one.
+	claimedAt, err := claimMonitor(h.database, monitor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to run monitor"})
+		return
+	}
+
+	if claimedAt == 0 {
+		c.JSON(http.StatusConflict, gin.H{"message": "monitor is already running"})
+		return
+	}
+
+	monitor.Running = true
+	monitor.RunningSince = claimedAt
+
+	attempt := ExecuteMonitor(h.database, monitor)
+	if attempt == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to record attempt"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "monitor executed successfully", "data": attempt})
+}
+
+// HandleTestMonitor runs the probe of an unsaved monitor once. Nothing is
+// stored and no integration is notified.
+func (h *MonitorHandler) HandleTestMonitor(c *gin.Context) {
+	var req CreateMonitorConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	switch req.MonitorType() {
+	case MonitorTypeHeartbeat, MonitorTypeGroup:
+		c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("%s monitors cannot be tested before they are created", req.MonitorType())})
+		return
+	}
+
+	monitor := req.monitorConfig()
+
+	if err := validateHttpClientOptions(monitor); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	// The probe could send a secret anywhere and return it in the response,
+	// so only saved monitors get their references expanded.
+	if len(secretReferences(monitor)) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "secret references are only expanded for saved monitors, create the monitor and run it instead"})
+		return
+	}
+
+	executionResponse, err := probe(h.database, monitor)
+
+	c.JSON(http.StatusOK, gin.H{"data": newAttempt(monitor, executionResponse, err)})
+}
+
+func (h *MonitorHandler) HandleAcknowledgeMonitor(c *gin.Context) {
+	var monitor MonitorConfig
+	if err := h.database.First(&monitor, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "monitor not found"})
+		return
+	}
+
+	if monitor.IncidentStartedAt == 0 {
+		c.JSON(http.StatusConflict, gin.H{"message": "monitor has no open incident"})
+		return
+	}
+
+	if monitor.AcknowledgedAt > 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "incident already acknowledged", "data": monitor})
+		return
+	}
+
+	monitor.AcknowledgedAt = clock.System.Now().Unix()
+
+	if err := h.database.Model(&MonitorConfig{}).
+		Where("id = ?", monitor.ID).
+		UpdateColumn("acknowledged_at", monitor.AcknowledgedAt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to acknowledge incident"})
+		return
+	}
+
+	recordEscalationEvent(h.database, "[monitor-handler]", monitor, 0, escalation.EventKindAcknowledge)
+
+	c.JSON(http.StatusOK, gin.H{"message": "incident acknowledged successfully", "data": monitor})
+}
+
+func (h *MonitorHandler) HandleListEscalationEvents(c *gin.Context) {
+	var events []escalation.EscalationEvent
+
+	if err := h.database.
+		Where("monitor_config_id = ?", c.Param("id")).
+		Order("id DESC").
+		Limit(50).
+		Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve escalation events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": events})
+}
+
+func (h *MonitorHandler) HandleHeartbeatPing(c *gin.Context) {
+	kind := HeartbeatPingSuccess
+	if param := c.Param("kind"); param != "" {
+		kind = HeartbeatPingKind(param)
+	}
+
+	if kind != HeartbeatPingStart && kind != HeartbeatPingSuccess && kind != HeartbeatPingFail {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid heartbeat kind"})
+		return
+	}
+
+	var req HeartbeatPingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	var monitor MonitorConfig
+	if err := h.database.
+		Where("heartbeat_token = ? AND type = ?", c.Param("token"), MonitorTypeHeartbeat).
+		Preload("Integrations").
+		Preload("EscalationPolicy.Steps.Integrations").
+		First(&monitor).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "heartbeat not found"})
+		return
+	}
+
+	if !monitor.Enabled {
+		c.JSON(http.StatusOK, gin.H{"message": "monitor is disabled, ping ignored"})
+		return
+	}
+
+	if err := recordHeartbeatPing(h.database, monitor, kind, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to record heartbeat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "heartbeat recorded successfully"})
+}
+
+// HandleExportConfig returns every monitor and integration as a manifest
+// that HandleImportConfig accepts. Values that may carry credentials are
+// redacted and importing them keeps the current values.
+func (h *MonitorHandler) HandleExportConfig(c *gin.Context) {
+	format := c.DefaultQuery("format", "yaml")
+	if format != "yaml" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "format must be yaml or json"})
+		return
+	}
+
+	state, err := loadManifestState(h.database)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to load configuration"})
+		return
+	}
+
+	document, err := encodeManifest(state.manifest.redacted(), format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to encode configuration"})
+		return
+	}
+
+	contentType := "application/yaml"
+	if format == "json" {
+		contentType = "application/json"
+	}
+
+	c.Data(http.StatusOK, contentType, document)
+}
+
+func (h *MonitorHandler) HandleImportConfig(c *gin.Context) {
+	var query ImportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if query.DryRun {
+		audit.Skip(c)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxManifestSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "failed to read manifest"})
+		return
+	}
+
+	manifest, err := parseManifest(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	changes, err := applyManifest(h.database, manifest, query.mode(), query.DryRun, history.AuthorFromContext(c))
+	if err != nil {
+		if errors.Is(err, ErrInvalidManifest) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to apply manifest"})
+		return
+	}
+
+	if !query.DryRun {
+		go refreshAllGroups(h.database)
+	}
+
+	message := "manifest applied successfully"
+	if query.DryRun {
+		message = "manifest checked successfully, nothing was applied"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data": gin.H{
+			"mode":    query.mode(),
+			"dry_run": query.DryRun,
+			"changes": changes,
+		},
+	})
+}
+
+func (h *MonitorHandler) HandleImportUptimeKuma(c *gin.Context) {
+	h.handleToolImport(c, parseKumaBackup)
+}
+
+func (h *MonitorHandler) HandleImportCSV(c *gin.Context) {
+	h.handleToolImport(c, parseMonitorCSV)
+}
+
+// handleToolImport reads another tool's export and imports what maps onto
+// monitors and integrations. Rows that cannot be imported are reported
+// instead of failing the request.
+func (h *MonitorHandler) handleToolImport(c *gin.Context, parse func([]byte) (importSource, error)) {
+	var query ToolImportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if query.DryRun {
+		audit.Skip(c)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxManifestSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "failed to read import file"})
+		return
+	}
+
+	source, err := parse(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	rows, changes, err := importFromSource(h.database, source, query.Integrations, query.DryRun, history.AuthorFromContext(c))
+	if err != nil {
+		if errors.Is(err, ErrInvalidManifest) || errors.Is(err, ErrIntegrationNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to import monitors"})
+		return
+	}
+
+	if !query.DryRun {
+		go refreshAllGroups(h.database)
+	}
+
+	message := "import applied successfully"
+	if query.DryRun {
+		message = "import checked successfully, nothing was applied"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data": gin.H{
+			"dry_run": query.DryRun,
+			"rows":    rows,
+			"changes": changes,
+		},
+	})
+}
+
+// HandleListMonitorVersions lists the recorded versions of a monitor,
+// including the ones of monitors that were deleted.
+func (h *MonitorHandler) HandleListMonitorVersions(c *gin.Context) {
+	monitorID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "monitor not found"})
+		return
+	}
+
+	versions, err := history.List(h.database, history.EntityTypeMonitor, uint(monitorID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to list monitor versions"})
+		return
+	}
+
+	if len(versions) == 0 {
+		if err := h.database.First(&MonitorConfig{}, monitorID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"message": "monitor not found"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": versions})
+}
+
+func (h *MonitorHandler) HandleRollbackMonitor(c *gin.Context) {
+	var monitor MonitorConfig
+	if err := h.database.First(&monitor, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "monitor not found"})
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": history.ErrVersionNotFound.Error()})
+		return
+	}
+
+	if err := rollbackMonitor(h.database, monitor.ID, number, history.AuthorFromContext(c)); err != nil {
+		switch {
+		case errors.Is(err, history.ErrVersionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		case errors.Is(err, ErrRollbackConflict):
+			c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to roll back monitor"})
+		}
+		return
+	}
+
+	go refreshAllGroups(h.database)
+
+	if err := h.database.Preload("Integrations").Preload("EscalationPolicy").Preload("Parents").Preload("Members.Member").Preload("Tags").First(&monitor, monitor.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to retrieve monitor"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "monitor rolled back successfully", "data": monitor})
+}
+
+func respondDependencyError(c *gin.Context, err error) {
+	if errors.Is(err, ErrParentNotFound) || errors.Is(err, ErrDependencyCycle) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to validate monitor dependencies"})
+}
+
+func respondSlugError(c *gin.Context, err error) {
+	if errors.Is(err, ErrSlugInUse) {
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to check monitor slug"})
+}
+
+func respondGroupError(c *gin.Context, err error) {
+	if errors.Is(err, ErrMemberNotFound) || errors.Is(err, ErrMembershipCycle) || errors.Is(err, ErrInvalidAggregate) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to validate group members"})
+}
@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"gorm.io/gorm"
+)
+
+func TestUpdateMonitorKeepsRuntimeStateAndReplacesLinks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := newTestDatabase(t)
+
+	oldHook := integration.IntegrationConfig{Name: "Old", Type: integration.IntegrationTypeSlack, URL: "https://hooks.slack.com/services/old"}
+	newHook := integration.IntegrationConfig{Name: "New", Type: integration.IntegrationTypeSlack, URL: "https://hooks.slack.com/services/new"}
+	if err := database.Create(&[]*integration.IntegrationConfig{&oldHook, &newHook}).Error; err != nil {
+		t.Fatalf("failed to create integrations: %v", err)
+	}
+
+	parent := MonitorConfig{Name: "parent", Type: MonitorTypeHTTP, Interval: 60, Threshold: 1}
+	if err := database.Create(&parent).Error; err != nil {
+		t.Fatalf("failed to create parent: %v", err)
+	}
+
+	monitorConfig := MonitorConfig{
+		Name:         "api",
+		Type:         MonitorTypeHTTP,
+		URL:          "https://api.example.com",
+		Method:       "GET",
+		Interval:     60,
+		Threshold:    1,
+		Timeout:      5,
+		Enabled:      true,
+		Health:       HealthStateUp,
+		Healthy:      true,
+		Tags:         []MonitorTag{{Key: "team", Value: "ops"}},
+		Integrations: []integration.IntegrationConfig{oldHook},
+	}
+	if err := database.Create(&monitorConfig).Error; err != nil {
+		t.Fatalf("failed to create monitor: %v", err)
+	}
+
+	// The worker records a failure right after the handler has read the
+	// monitor, the update must not write the stale state back.
+	executed := false
+	database.Callback().Query().After("gorm:query").Register("test:worker", func(tx *gorm.DB) {
+		if executed || tx.Statement.Table != "monitor_configs" {
+			return
+		}
+		executed = true
+
+		tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+			Exec("UPDATE monitor_configs SET health = ?, healthy = ?, failed_attempts = ?, incident_started_at = ? WHERE id = ?",
+				HealthStateDown, false, 3, 1700000000, monitorConfig.ID)
+	})
+
+	router := gin.New()
+	router.PUT("/monitors/:id", (&MonitorHandler{database: database}).HandleUpdateMonitor)
+
+	body := fmt.Sprintf(`{"name": "api v2", "integration_id_list": [%d], "parent_id_list": [%d], "tags": [{"key": "team", "value": "payments"}]}`, newHook.ID, parent.ID)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/monitors/%d", monitorConfig.ID), strings.NewReader(body)))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body)
+	}
+
+	var stored MonitorConfig
+	if err := database.Preload("Integrations").Preload("Parents").Preload("Tags").First(&stored, monitorConfig.ID).Error; err != nil {
+		t.Fatalf("failed to reload monitor: %v", err)
+	}
+
+	if stored.Name != "api v2" {
+		t.Errorf("name = %q, want the update applied", stored.Name)
+	}
+
+	if stored.Health != HealthStateDown || stored.FailedAttempts != 3 || stored.IncidentStartedAt != 1700000000 {
+		t.Errorf("runtime state was overwritten: health=%s failed_attempts=%d incident_started_at=%d",
+			stored.Health, stored.FailedAttempts, stored.IncidentStartedAt)
+	}
+
+	if len(stored.Integrations) != 1 || stored.Integrations[0].ID != newHook.ID {
+		t.Errorf("integrations = %+v, want only the new one", stored.Integrations)
+	}
+
+	if len(stored.Parents) != 1 || stored.Parents[0].ID != parent.ID {
+		t.Errorf("parents = %+v, want the parent", stored.Parents)
+	}
+
+	if len(stored.Tags) != 1 || stored.Tags[0].Value != "payments" {
+		t.Errorf("tags = %+v, want team:payments", stored.Tags)
+	}
+}
+
+func TestUpdateMonitorLeavesLinksAloneOnFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := newTestDatabase(t)
+
+	hook := integration.IntegrationConfig{Name: "Slack", Type: integration.IntegrationTypeSlack, URL: "https://hooks.slack.com/services/ops"}
+	if err := database.Create(&hook).Error; err != nil {
+		t.Fatalf("failed to create integration: %v", err)
+	}
+
+	monitorConfig := MonitorConfig{
+		Name:         "api",
+		Type:         MonitorTypeHTTP,
+		URL:          "https://api.example.com",
+		Method:       "GET",
+		Interval:     60,
+		Threshold:    1,
+		Timeout:      5,
+		Tags:         []MonitorTag{{Key: "team", Value: "ops"}},
+		Integrations: []integration.IntegrationConfig{hook},
+	}
+	if err := database.Create(&monitorConfig).Error; err != nil {
+		t.Fatalf("failed to create monitor: %v", err)
+	}
+
+	database.Callback().Update().Before("gorm:update").Register("test:fail", func(tx *gorm.DB) {
+		if tx.Statement.Table == "monitor_configs" {
+			tx.AddError(gorm.ErrInvalidData)
+		}
+	})
+
+	router := gin.New()
+	router.PUT("/monitors/:id", (&MonitorHandler{database: database}).HandleUpdateMonitor)
+
+	body := fmt.Sprintf(`{"integration_id_list": [%d], "tags": []}`, hook.ID)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/monitors/%d", monitorConfig.ID), strings.NewReader(body)))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+
+	var stored MonitorConfig
+	if err := database.Preload("Integrations").Preload("Tags").First(&stored, monitorConfig.ID).Error; err != nil {
+		t.Fatalf("failed to reload monitor: %v", err)
+	}
+
+	if len(stored.Integrations) != 1 || len(stored.Tags) != 1 {
+		t.Errorf("links changed by a failed update: integrations=%d tags=%d", len(stored.Integrations), len(stored.Tags))
+	}
+}
+
+func TestTestMonitorRefusesSecretReferences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := newTestDatabase(t)
+
+	if err := database.Create(&secret.Secret{Name: "api-token", Value: "s3cr3t"}).Error; err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	router := gin.New()
+	router.POST("/monitors/test", (&MonitorHandler{database: database}).HandleTestMonitor)
+
+	test := func(authorization string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"name": "api", "url": %q, "method": "GET", "interval": 60, "threshold": 1, "timeout": 5, "headers": {"Authorization": %q}}`,
+			server.URL, authorization)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/monitors/test", strings.NewReader(body)))
+		return recorder
+	}
+
+	if recorder := test("Bearer " + secret.Reference("api-token")); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+
+	if len(received) != 0 {
+		t.Fatalf("the probe ran with authorization %q", received)
+	}
+
+	if recorder := test("Bearer plain"); recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body)
+	}
+
+	if len(received) != 1 || received[0] != "Bearer plain" {
+		t.Errorf("received authorization %q, want the plain value", received)
+	}
+}
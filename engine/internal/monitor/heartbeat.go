@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"gorm.io/gorm"
+)
+
+type HeartbeatPingKind string
+
+const (
+	HeartbeatPingStart   HeartbeatPingKind = "start"
+	HeartbeatPingSuccess HeartbeatPingKind = "success"
+	HeartbeatPingFail    HeartbeatPingKind = "fail"
+)
+
+var (
+	ErrHeartbeatMissed = errors.New("no heartbeat received within the expected period")
+	ErrHeartbeatFailed = errors.New("heartbeat reported a failed run")
+)
+
+func generateHeartbeatToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// recordHeartbeatPing turns a ping into an attempt. Start pings only mark the
+// beginning of a run, while success and fail pings go through the same
+// threshold and incident handling as polled checks.
+func recordHeartbeatPing(database *gorm.DB, monitorConfig MonitorConfig, kind HeartbeatPingKind, req HeartbeatPingRequest) error {
+	logPrefix := fmt.Sprintf("[heartbeat id=%d name=%s]", monitorConfig.ID, monitorConfig.Name)
+	now := clock.System.Now()
+
+	log.Printf("%s received %s ping", logPrefix, kind)
+
+	if kind == HeartbeatPingStart {
+		attempt := Attempt{
+			MonitorConfigID: monitorConfig.ID,
+			Healthy:         monitorConfig.Healthy,
+			Health:          monitorConfig.Health,
+			Response:        pingMessage(req, "run started"),
+		}
+		if err := database.Create(&attempt).Error; err != nil {
+			return err
+		}
+
+		return database.Model(&MonitorConfig{}).
+			Where("id = ?", monitorConfig.ID).
+			UpdateColumn("heartbeat_started_at", now.Unix()).Error
+	}
+
+	var duration time.Duration
+	if req.Duration != nil {
+		duration = time.Duration(*req.Duration) * time.Millisecond
+	} else if monitorConfig.HeartbeatStartedAt > 0 {
+		duration = now.Sub(time.Unix(monitorConfig.HeartbeatStartedAt, 0))
+	}
+
+	executionResponse := ExecutionResponse{
+		ResponseBody: pingMessage(req, "heartbeat received"),
+		ResponseTime: duration,
+	}
+
+	var err error
+	if kind == HeartbeatPingFail {
+		err = ErrHeartbeatFailed
+		if req.Message != "" {
+			err = fmt.Errorf("%w: %s", ErrHeartbeatFailed, req.Message)
+		}
+	}
+
+	recordExecution(database, logPrefix, monitorConfig, 0, executionResponse, err)
+
+	return database.Model(&MonitorConfig{}).
+		Where("id = ?", monitorConfig.ID).
+		UpdateColumn("heartbeat_started_at", 0).Error
+}
+
+func pingMessage(req HeartbeatPingRequest, fallback string) string {
+	if req.Message != "" {
+		return req.Message
+	}
+
+	return fallback
+}
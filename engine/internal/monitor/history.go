@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/escalation"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"gorm.io/gorm"
+)
+
+var ErrRollbackConflict = errors.New("version cannot be restored")
+
+// monitorSnapshot is the configuration kept in a monitor's history: its
+// manifest entry plus the escalation policy, which manifests do not manage.
+type monitorSnapshot struct {
+	MonitorManifest
+	EscalationPolicyID *uint `json:"escalation_policy_id,omitempty"`
+}
+
+// snapshotMonitors returns the snapshot of every existing monitor in ids.
+func snapshotMonitors(database *gorm.DB, ids []uint) (map[uint]monitorSnapshot, error) {
+	snapshots := make(map[uint]monitorSnapshot, len(ids))
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+
+	var monitors []MonitorConfig
+	if err := database.
+		Preload("Integrations").
+		Preload("Parents").
+		Preload("Members.Member").
+		Preload("Tags").
+		Where("id IN ?", ids).
+		Find(&monitors).Error; err != nil {
+		return nil, err
+	}
+
+	for _, monitorConfig := range monitors {
+		snapshots[monitorConfig.ID] = monitorSnapshot{
+			MonitorManifest:    manifestEntry(monitorConfig),
+			EscalationPolicyID: monitorConfig.EscalationPolicyID,
+		}
+	}
+
+	return snapshots, nil
+}
+
+// recordMonitorVersions records the change made to the monitors in ids,
+// given their snapshots from before the change.
+func recordMonitorVersions(tx *gorm.DB, ids []uint, before map[uint]monitorSnapshot, author history.Author) error {
+	after, err := snapshotMonitors(tx, ids)
+	if err != nil {
+		return err
+	}
+
+	return history.RecordChanges(tx, history.EntityTypeMonitor, ids, before, after, author)
+}
+
+// rollbackMonitor restores the configuration a monitor had at a version. The
+// restore goes through the manifest importer, so it is validated like any
+// other change and fails when the integrations, parents or members it
+// refers to no longer exist. The rollback is recorded as a new version.
+func rollbackMonitor(database *gorm.DB, monitorID uint, number int, author history.Author) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		var current MonitorConfig
+		if err := tx.First(&current, monitorID).Error; err != nil {
+			return err
+		}
+
+		version, err := history.Find(tx, history.EntityTypeMonitor, monitorID, number)
+		if err != nil {
+			return err
+		}
+
+		var snapshot monitorSnapshot
+		if err := json.Unmarshal(version.Snapshot, &snapshot); err != nil {
+			return err
+		}
+
+		before, err := snapshotMonitors(tx, []uint{monitorID})
+		if err != nil {
+			return err
+		}
+
+		// Manifests match monitors by slug, so an old slug is restored first.
+		if snapshot.Slug != stringValue(current.Slug) {
+			if _, err := resolveMonitorSlug(tx, snapshot.Slug, current.Name, monitorID); err != nil {
+				if errors.Is(err, ErrSlugInUse) {
+					return fmt.Errorf("%w: slug %q is now used by another monitor", ErrRollbackConflict, snapshot.Slug)
+				}
+				return err
+			}
+
+			if err := tx.Model(&current).UpdateColumn("slug", snapshot.Slug).Error; err != nil {
+				return err
+			}
+		}
+
+		if snapshot.EscalationPolicyID != nil {
+			if err := tx.First(&escalation.EscalationPolicy{}, *snapshot.EscalationPolicyID).Error; err != nil {
+				return fmt.Errorf("%w: escalation policy %d no longer exists", ErrRollbackConflict, *snapshot.EscalationPolicyID)
+			}
+		}
+
+		if err := tx.Model(&current).UpdateColumn("escalation_policy_id", snapshot.EscalationPolicyID).Error; err != nil {
+			return err
+		}
+
+		manifest := Manifest{Version: manifestVersion, Monitors: []MonitorManifest{snapshot.MonitorManifest}}
+		if _, err := applyManifestTx(tx, manifest, ImportModeMerge, nil); err != nil {
+			if errors.Is(err, ErrInvalidManifest) {
+				return fmt.Errorf("%w: %v", ErrRollbackConflict, err)
+			}
+			return err
+		}
+
+		author.Source = fmt.Sprintf("rollback to version %d", number)
+
+		return recordMonitorVersions(tx, []uint{monitorID}, before, author)
+	})
+}
@@ -0,0 +1,297 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+type HttpVersion string
+
+const (
+	HttpVersionAuto  HttpVersion = "AUTO"
+	HttpVersionHTTP1 HttpVersion = "HTTP1"
+	HttpVersionHTTP2 HttpVersion = "HTTP2"
+	HttpVersionHTTP3 HttpVersion = "HTTP3"
+)
+
+type IPVersion string
+
+const (
+	IPVersionAny  IPVersion = "ANY"
+	IPVersionIPv4 IPVersion = "IPV4"
+	IPVersionIPv6 IPVersion = "IPV6"
+)
+
+// defaultMaxRedirects matches the limit of the standard library client.
+const defaultMaxRedirects = 10
+
+var (
+	ErrInvalidHttpOptions = errors.New("invalid http client options")
+)
+
+// newHttpClient builds the client used by HTTP based probes from the options
+// stored on the monitor. The returned function releases idle connections and
+// must be called once the probe is done.
+func newHttpClient(monitorConfig MonitorConfig) (*http.Client, func(), error) {
+	tlsConfig, err := httpTLSConfig(monitorConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolver, err := httpResolver(monitorConfig.DNSServer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := &http.Client{
+		CheckRedirect: redirectPolicy(monitorConfig),
+	}
+
+	if monitorConfig.HttpVersion == HttpVersionHTTP3 {
+		if monitorConfig.ProxyURL != "" {
+			return nil, nil, fmt.Errorf("%w: proxies are not supported over HTTP/3", ErrInvalidHttpOptions)
+		}
+
+		dialer := &quicDialer{resolver: resolver, ipVersion: monitorConfig.IPVersion}
+		transport := &http3.Transport{
+			TLSClientConfig: tlsConfig,
+			Dial:            dialer.dial,
+		}
+		client.Transport = transport
+
+		return client, func() {
+			transport.Close()
+			dialer.close()
+		}, nil
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if monitorConfig.ProxyURL != "" {
+		proxyURL, err := parseProxyURL(monitorConfig.ProxyURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{Resolver: resolver}
+	network := ipNetwork(monitorConfig.IPVersion, "tcp")
+
+	protocols := new(http.Protocols)
+	switch monitorConfig.HttpVersion {
+	case HttpVersionHTTP1:
+		protocols.SetHTTP1(true)
+	case HttpVersionHTTP2:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: true,
+		Protocols:         protocols,
+	}
+	client.Transport = transport
+
+	return client, transport.CloseIdleConnections, nil
+}
+
+// validateHttpClientOptions reports configuration errors at save time instead
+// of on every execution.
+func validateHttpClientOptions(monitorConfig MonitorConfig) error {
+	// A key stored as a secret reference can only be paired with its
+	// certificate once the secret is resolved by the probe.
+	if secret.IsReference(monitorConfig.TLSClientKey) {
+		monitorConfig.TLSClientCertificate = ""
+		monitorConfig.TLSClientKey = ""
+	}
+
+	_, closeClient, err := newHttpClient(monitorConfig)
+	if err != nil {
+		return err
+	}
+	closeClient()
+
+	return nil
+}
+
+func redirectPolicy(monitorConfig MonitorConfig) func(*http.Request, []*http.Request) error {
+	if monitorConfig.DisableRedirects {
+		return func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	maxRedirects := monitorConfig.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+}
+
+// httpTLSConfig adds the custom CA bundle to the system roots, so monitors can
+// reach internal services without losing access to public ones.
+func httpTLSConfig(monitorConfig MonitorConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: monitorConfig.TLSSkipVerify,
+	}
+
+	if monitorConfig.TLSCACertificate != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(monitorConfig.TLSCACertificate)) {
+			return nil, fmt.Errorf("%w: ca certificate does not contain a valid PEM certificate", ErrInvalidHttpOptions)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if monitorConfig.TLSClientCertificate != "" || monitorConfig.TLSClientKey != "" {
+		certificate, err := tls.X509KeyPair([]byte(monitorConfig.TLSClientCertificate), []byte(monitorConfig.TLSClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid client certificate: %v", ErrInvalidHttpOptions, err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// httpResolver returns a resolver that sends every query to dnsServer, or nil
+// to use the system resolver.
+func httpResolver(dnsServer string) (*net.Resolver, error) {
+	if dnsServer == "" {
+		return nil, nil
+	}
+
+	address := dnsServer
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(dnsServer, "53")
+	}
+
+	if host, _, _ := net.SplitHostPort(address); net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("%w: dns server must be an IP address, got %q", ErrInvalidHttpOptions, dnsServer)
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}, nil
+}
+
+func parseProxyURL(rawURL string) (*url.URL, error) {
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid proxy url: %v", ErrInvalidHttpOptions, err)
+	}
+
+	if (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Host == "" {
+		return nil, fmt.Errorf("%w: proxy url must be http:// or https://", ErrInvalidHttpOptions)
+	}
+
+	return proxyURL, nil
+}
+
+// ipNetwork narrows a network name such as "tcp" or "ip" to the configured
+// address family.
+func ipNetwork(ipVersion IPVersion, network string) string {
+	switch ipVersion {
+	case IPVersionIPv4:
+		return network + "4"
+	case IPVersionIPv6:
+		return network + "6"
+	default:
+		return network
+	}
+}
+
+// quicDialer resolves and dials HTTP/3 connections with the monitor's
+// resolver and address family. quic-go does not close packet connections it
+// did not create, so they are tracked and closed with the client.
+type quicDialer struct {
+	resolver  *net.Resolver
+	ipVersion IPVersion
+
+	mutex sync.Mutex
+	conns []net.PacketConn
+}
+
+func (d *quicDialer) dial(ctx context.Context, addr string, tlsConfig *tls.Config, quicConfig *quic.Config) (*quic.Conn, error) {
+	host, rawPort, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.Atoi(rawPort)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", rawPort)
+	}
+
+	resolver := d.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	ips, err := resolver.LookupIP(ctx, ipNetwork(d.ipVersion, "ip"), host)
+	if err != nil {
+		return nil, err
+	}
+
+	network := "udp6"
+	if ips[0].To4() != nil {
+		network = "udp4"
+	}
+
+	conn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	d.conns = append(d.conns, conn)
+	d.mutex.Unlock()
+
+	return quic.DialEarly(ctx, conn, &net.UDPAddr{IP: ips[0], Port: port}, tlsConfig, quicConfig)
+}
+
+func (d *quicDialer) close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, conn := range d.conns {
+		conn.Close()
+	}
+	d.conns = nil
+}
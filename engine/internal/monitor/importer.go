@@ -0,0 +1,261 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"github.com/mateusgcoelho/sentinel/engine/internal/slug"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultImportInterval = 60
+	defaultImportTimeout  = 10
+)
+
+var (
+	ErrInvalidImportFile = errors.New("invalid import file")
+)
+
+type ImportRowStatus string
+
+const (
+	ImportRowImported ImportRowStatus = "IMPORTED"
+	ImportRowSkipped  ImportRowStatus = "SKIPPED"
+)
+
+// ImportRow reports what happened to one entry of the source file. Fields
+// that have no equivalent here are listed in Unsupported and dropped, they
+// never fail the row on their own.
+type ImportRow struct {
+	Row         int             `json:"row"`
+	Kind        ChangeKind      `json:"kind"`
+	Name        string          `json:"name"`
+	Slug        string          `json:"slug,omitempty"`
+	Status      ImportRowStatus `json:"status"`
+	Unsupported []string        `json:"unsupported,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+type ToolImportQuery struct {
+	Integrations []string `form:"integration"`
+	DryRun       bool     `form:"dry_run"`
+}
+
+// importedIntegration is an integration read from another tool. Its webhook
+// is stored as a secret named after the integration's slug.
+type importedIntegration struct {
+	row        *ImportRow
+	sourceID   string
+	entry      IntegrationManifest
+	webhookURL string
+}
+
+// importedMonitor is a monitor read from another tool. Integrations and
+// members point at source IDs until slugs are assigned.
+type importedMonitor struct {
+	row          *ImportRow
+	sourceID     string
+	entry        MonitorManifest
+	integrations []string
+	members      []string
+}
+
+type importSource struct {
+	rows         []*ImportRow
+	integrations []importedIntegration
+	monitors     []importedMonitor
+}
+
+func (s *importSource) addRow(row ImportRow) *ImportRow {
+	s.rows = append(s.rows, &row)
+	return s.rows[len(s.rows)-1]
+}
+
+func (r *ImportRow) unsupported(field string) {
+	r.Unsupported = append(r.Unsupported, field)
+}
+
+func (r *ImportRow) skip(format string, args ...any) {
+	r.Status = ImportRowSkipped
+	r.Error = fmt.Sprintf(format, args...)
+}
+
+// importFromSource creates the monitors and integrations of an import in one
+// transaction. Rows whose slug is already taken are skipped, so running the
+// same import twice does not duplicate monitors. The given integrations are
+// attached to every imported monitor.
+func importFromSource(database *gorm.DB, source importSource, integrationSlugs []string, dryRun bool, author history.Author) ([]ImportRow, []ManifestChange, error) {
+	var changes []ManifestChange
+	err := database.Transaction(func(tx *gorm.DB) error {
+		existingIntegrations, err := slugIDs(tx, &integration.IntegrationConfig{})
+		if err != nil {
+			return err
+		}
+
+		for _, integrationSlug := range integrationSlugs {
+			if _, ok := existingIntegrations[integrationSlug]; !ok {
+				return fmt.Errorf("%w: %s", ErrIntegrationNotFound, integrationSlug)
+			}
+		}
+
+		existingMonitors, err := slugIDs(tx, &MonitorConfig{})
+		if err != nil {
+			return err
+		}
+
+		manifest := Manifest{Version: manifestVersion}
+
+		integrationBySource := map[string]string{}
+		taken := map[string]bool{}
+		for _, item := range source.integrations {
+			if item.row.Status == ImportRowSkipped {
+				continue
+			}
+
+			itemSlug := uniqueImportSlug(slug.Make(item.entry.Name, "integration"), taken)
+			item.row.Slug = itemSlug
+			integrationBySource[item.sourceID] = itemSlug
+
+			// Notifications already imported, or created by hand with the
+			// same name, are linked instead of duplicated.
+			if _, exists := existingIntegrations[itemSlug]; exists {
+				item.row.skip("integration %q already exists, monitors are linked to it", itemSlug)
+				continue
+			}
+
+			// Source IDs repeat across backups, so the secret is named after
+			// the new integration's unique slug. An existing secret may be
+			// referenced elsewhere and is never overwritten.
+			secretName := itemSlug + "-webhook"
+
+			var secretCount int64
+			if err := tx.Model(&secret.Secret{}).Where("name = ?", secretName).Count(&secretCount).Error; err != nil {
+				return err
+			}
+
+			if secretCount > 0 {
+				item.row.skip("secret %q already exists, rename or delete it to import this integration", secretName)
+				delete(integrationBySource, item.sourceID)
+				continue
+			}
+
+			if err := tx.Create(&secret.Secret{Name: secretName, Value: item.webhookURL}).Error; err != nil {
+				return err
+			}
+
+			item.entry.Slug = itemSlug
+			item.entry.URL = secret.Reference(secretName)
+			manifest.Integrations = append(manifest.Integrations, item.entry)
+		}
+
+		monitorBySource := map[string]string{}
+		taken = map[string]bool{}
+		for _, item := range source.monitors {
+			if item.row.Status == ImportRowSkipped {
+				continue
+			}
+
+			itemSlug := uniqueImportSlug(slug.Make(item.entry.Name, "monitor"), taken)
+			item.row.Slug = itemSlug
+
+			if _, exists := existingMonitors[itemSlug]; exists {
+				item.row.skip("monitor %q already exists", itemSlug)
+				continue
+			}
+
+			monitorBySource[item.sourceID] = itemSlug
+		}
+
+		// Groups are checked last, once it is known which of their members
+		// made it into the import.
+		ordered := slices.Clone(source.monitors)
+		slices.SortStableFunc(ordered, func(a, b importedMonitor) int {
+			return boolOrder(a.entry.Type == MonitorTypeGroup) - boolOrder(b.entry.Type == MonitorTypeGroup)
+		})
+
+		imported := map[string]bool{}
+		for _, item := range ordered {
+			if item.row.Status == ImportRowSkipped {
+				continue
+			}
+
+			entry := item.entry
+			entry.Slug = item.row.Slug
+			entry.Integrations = append([]string{}, integrationSlugs...)
+
+			for _, sourceID := range item.integrations {
+				if integrationSlug, ok := integrationBySource[sourceID]; ok {
+					entry.Integrations = append(entry.Integrations, integrationSlug)
+				}
+			}
+
+			entry.Members = nil
+			for _, sourceID := range item.members {
+				memberSlug, ok := monitorBySource[sourceID]
+				if !ok || !imported[memberSlug] {
+					item.row.unsupported(fmt.Sprintf("member %s (not imported)", sourceID))
+					continue
+				}
+				entry.Members = append(entry.Members, MemberManifest{Monitor: memberSlug})
+			}
+
+			if problems := monitorEntryProblems(entry); len(problems) > 0 {
+				item.row.skip("%s", problems[0])
+				delete(monitorBySource, item.sourceID)
+				continue
+			}
+
+			imported[entry.Slug] = true
+			manifest.Monitors = append(manifest.Monitors, entry)
+		}
+
+		if changes, err = applyManifestTx(tx, manifest, ImportModeMerge, &author); err != nil {
+			return err
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, nil, err
+	}
+
+	rows := make([]ImportRow, 0, len(source.rows))
+	for _, row := range source.rows {
+		rows = append(rows, *row)
+	}
+
+	return rows, changes, nil
+}
+
+func uniqueImportSlug(base string, taken map[string]bool) string {
+	candidate := base
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+	taken[candidate] = true
+
+	return candidate
+}
+
+func boolOrder(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+// importTimeout keeps the default timeout below the interval, like the
+// create endpoint expects.
+func importTimeout(interval int) int {
+	return max(min(defaultImportTimeout, interval), 1)
+}
@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"gorm.io/gorm"
+)
+
+func kumaBackupWithNotification(id int, name string, webhookURL string) []byte {
+	return fmt.Appendf(nil, `{
+		"notificationList": [
+			{"id": %d, "name": %q, "config": %q}
+		],
+		"monitorList": []
+	}`, id, name, fmt.Sprintf(`{"type":"slack","slackwebhookURL":%q}`, webhookURL))
+}
+
+func importKuma(t *testing.T, database *gorm.DB, backup []byte) []ImportRow {
+	t.Helper()
+
+	source, err := parseKumaBackup(backup)
+	if err != nil {
+		t.Fatalf("failed to parse backup: %v", err)
+	}
+
+	rows, _, err := importFromSource(database, source, nil, false, history.Author{Source: "test"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	return rows
+}
+
+func integrationWebhook(t *testing.T, database *gorm.DB, integrationSlug string) string {
+	t.Helper()
+
+	var item integration.IntegrationConfig
+	if err := database.Where("slug = ?", integrationSlug).First(&item).Error; err != nil {
+		t.Fatalf("integration %q not found: %v", integrationSlug, err)
+	}
+
+	webhookURL, err := secret.Expand(database, item.URL)
+	if err != nil {
+		t.Fatalf("failed to expand %q: %v", item.URL, err)
+	}
+
+	return webhookURL
+}
+
+func TestKumaImportsReusingNotificationIDsKeepTheirWebhooks(t *testing.T) {
+	database := newTestDatabase(t)
+
+	importKuma(t, database, kumaBackupWithNotification(1, "Ops Slack", "https://hooks.slack.com/services/ops"))
+	importKuma(t, database, kumaBackupWithNotification(1, "Team Slack", "https://hooks.slack.com/services/team"))
+
+	if got := integrationWebhook(t, database, "ops-slack"); got != "https://hooks.slack.com/services/ops" {
+		t.Errorf("first integration posts to %q after the second import", got)
+	}
+
+	if got := integrationWebhook(t, database, "team-slack"); got != "https://hooks.slack.com/services/team" {
+		t.Errorf("second integration posts to %q", got)
+	}
+}
+
+func TestKumaImportNeverOverwritesAnExistingSecret(t *testing.T) {
+	database := newTestDatabase(t)
+
+	if err := database.Create(&secret.Secret{Name: "ops-slack-webhook", Value: "https://hooks.slack.com/services/kept"}).Error; err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	rows := importKuma(t, database, kumaBackupWithNotification(1, "Ops Slack", "https://hooks.slack.com/services/other"))
+
+	if len(rows) != 1 || rows[0].Status != ImportRowSkipped {
+		t.Fatalf("rows = %+v, want the integration skipped", rows)
+	}
+
+	var stored secret.Secret
+	if err := database.Where("name = ?", "ops-slack-webhook").First(&stored).Error; err != nil {
+		t.Fatalf("secret not found: %v", err)
+	}
+
+	if stored.Value != "https://hooks.slack.com/services/kept" {
+		t.Errorf("secret was overwritten with %q", stored.Value)
+	}
+
+	var count int64
+	database.Model(&integration.IntegrationConfig{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d integrations were created, want 0", count)
+	}
+}
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"log"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"github.com/mateusgcoelho/sentinel/engine/internal/escalation"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"gorm.io/gorm"
+)
+
+var escalationEngine = escalation.NewEngine(clock.System)
+
+// escalationPolicyFor returns the policy attached to the monitor. Monitors
+// without one keep the historical behaviour: alert their own integrations once
+// the threshold is reached and repeat roughly every three thresholds.
+func escalationPolicyFor(monitorConfig MonitorConfig) escalation.EscalationPolicy {
+	if monitorConfig.EscalationPolicy != nil && len(monitorConfig.EscalationPolicy.Steps) > 0 {
+		return *monitorConfig.EscalationPolicy
+	}
+
+	return escalation.EscalationPolicy{
+		Steps: []escalation.EscalationStep{
+			{
+				Position:       0,
+				RepeatInterval: monitorConfig.Interval * monitorConfig.Threshold * 3,
+				Integrations:   monitorConfig.Integrations,
+			},
+		},
+	}
+}
+
+func loadIncident(database *gorm.DB, monitorConfig MonitorConfig) (escalation.Incident, error) {
+	incident := escalation.Incident{
+		StartedAt:    time.Unix(monitorConfig.IncidentStartedAt, 0),
+		LastNotified: map[int]time.Time{},
+	}
+
+	if monitorConfig.AcknowledgedAt > 0 {
+		incident.AcknowledgedAt = time.Unix(monitorConfig.AcknowledgedAt, 0)
+	}
+
+	var events []escalation.EscalationEvent
+	if err := database.
+		Where("monitor_config_id = ? AND incident_started_at = ?", monitorConfig.ID, monitorConfig.IncidentStartedAt).
+		Where("kind IN ?", []escalation.EventKind{escalation.EventKindNotify, escalation.EventKindEscalate, escalation.EventKindRepeat}).
+		Order("id ASC").
+		Find(&events).Error; err != nil {
+		return incident, err
+	}
+
+	for _, event := range events {
+		incident.LastNotified[event.StepPosition] = time.Unix(event.CreatedAt, 0)
+	}
+
+	return incident, nil
+}
+
+func recordEscalationEvent(database *gorm.DB, logPrefix string, monitorConfig MonitorConfig, stepPosition int, kind escalation.EventKind) {
+	event := escalation.EscalationEvent{
+		MonitorConfigID:    monitorConfig.ID,
+		EscalationPolicyID: monitorConfig.EscalationPolicyID,
+		IncidentStartedAt:  monitorConfig.IncidentStartedAt,
+		StepPosition:       stepPosition,
+		Kind:               kind,
+	}
+	if err := database.Create(&event).Error; err != nil {
+		log.Printf("%s failed to record escalation event [%s]: %v", logPrefix, kind, err)
+	}
+}
+
+func escalateIncident(database *gorm.DB, logPrefix string, monitorConfig MonitorConfig, errorMessage string) {
+	incident, err := loadIncident(database, monitorConfig)
+	if err != nil {
+		log.Printf("%s failed to load incident: %v", logPrefix, err)
+		return
+	}
+
+	actions := escalationEngine.Evaluate(escalationPolicyFor(monitorConfig), incident)
+	if len(actions) == 0 {
+		return
+	}
+
+	dependents, err := affectedDependents(database, monitorConfig.ID)
+	if err != nil {
+		log.Printf("%s failed to load dependent monitors: %v", logPrefix, err)
+	}
+
+	for _, action := range actions {
+		log.Printf("%s escalation step %d due [%s]", logPrefix, action.Step.Position, action.Kind)
+
+		sendAlertMessage(database, logPrefix, action.Step.Integrations, monitorConfig.Name, errorMessage, monitorConfig.FailedAttempts, dependents)
+		recordEscalationEvent(database, logPrefix, monitorConfig, action.Step.Position, action.Kind)
+	}
+}
+
+// recoverIncident notifies every integration that was alerted during the
+// incident, so that steps which were never reached stay quiet.
+func recoverIncident(database *gorm.DB, logPrefix string, monitorConfig MonitorConfig) {
+	incident, err := loadIncident(database, monitorConfig)
+	if err != nil {
+		log.Printf("%s failed to load incident: %v", logPrefix, err)
+		return
+	}
+
+	var integrations []integration.IntegrationConfig
+	seen := map[uint]bool{}
+
+	for _, step := range escalation.SortedSteps(escalationPolicyFor(monitorConfig)) {
+		if _, notified := incident.LastNotified[step.Position]; !notified {
+			continue
+		}
+
+		for _, item := range step.Integrations {
+			if seen[item.ID] {
+				continue
+			}
+			seen[item.ID] = true
+			integrations = append(integrations, item)
+		}
+	}
+
+	sendRecoverMessage(database, logPrefix, integrations, monitorConfig.Name, monitorConfig.FailedAttempts)
+	recordEscalationEvent(database, logPrefix, monitorConfig, 0, escalation.EventKindRecover)
+}
@@ -0,0 +1,328 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+)
+
+// kumaBackup is the part of an Uptime Kuma JSON backup that maps onto
+// monitors and integrations. Unknown keys are ignored.
+type kumaBackup struct {
+	NotificationList []kumaNotification `json:"notificationList"`
+	MonitorList      []kumaMonitor      `json:"monitorList"`
+}
+
+type kumaNotification struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Config string `json:"config"`
+}
+
+type kumaNotificationConfig struct {
+	Type              string `json:"type"`
+	SlackWebhookURL   string `json:"slackwebhookURL"`
+	DiscordWebhookURL string `json:"discordWebhookUrl"`
+}
+
+type kumaMonitor struct {
+	ID                  int             `json:"id"`
+	Name                string          `json:"name"`
+	Description         string          `json:"description"`
+	Type                string          `json:"type"`
+	URL                 string          `json:"url"`
+	Method              string          `json:"method"`
+	Body                string          `json:"body"`
+	Headers             string          `json:"headers"`
+	Interval            int             `json:"interval"`
+	RetryInterval       int             `json:"retryInterval"`
+	ResendInterval      int             `json:"resendInterval"`
+	MaxRetries          int             `json:"maxretries"`
+	Timeout             float64         `json:"timeout"`
+	Active              kumaBool        `json:"active"`
+	UpsideDown          kumaBool        `json:"upsideDown"`
+	IgnoreTLS           kumaBool        `json:"ignoreTls"`
+	ExpiryNotification  kumaBool        `json:"expiryNotification"`
+	MaxRedirects        *int            `json:"maxredirects"`
+	AcceptedStatusCodes []string        `json:"accepted_statuscodes"`
+	Keyword             string          `json:"keyword"`
+	InvertKeyword       kumaBool        `json:"invertKeyword"`
+	PushToken           string          `json:"pushToken"`
+	Parent              *int            `json:"parent"`
+	AuthMethod          string          `json:"authMethod"`
+	BasicAuthUser       string          `json:"basic_auth_user"`
+	ProxyID             *int            `json:"proxyId"`
+	NotificationIDList  json.RawMessage `json:"notificationIDList"`
+	Tags                []kumaTag       `json:"tags"`
+}
+
+type kumaTag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// kumaBool accepts the booleans Uptime Kuma writes, which depending on the
+// version and database are true/false, 0/1 or null.
+type kumaBool bool
+
+func (b *kumaBool) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+
+	return nil
+}
+
+// parseKumaBackup maps an Uptime Kuma backup onto an import. Monitor types
+// and fields without an equivalent are reported on their row.
+func parseKumaBackup(body []byte) (importSource, error) {
+	var backup kumaBackup
+	if err := json.Unmarshal(body, &backup); err != nil {
+		return importSource{}, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+
+	var source importSource
+
+	for i, notification := range backup.NotificationList {
+		row := source.addRow(ImportRow{
+			Row:    i + 1,
+			Kind:   ChangeKindIntegration,
+			Name:   notification.Name,
+			Status: ImportRowImported,
+		})
+
+		var config kumaNotificationConfig
+		if err := json.Unmarshal([]byte(notification.Config), &config); err != nil {
+			row.skip("invalid notification config: %v", err)
+			continue
+		}
+
+		item := importedIntegration{
+			row:      row,
+			sourceID: strconv.Itoa(notification.ID),
+			entry:    IntegrationManifest{Name: notification.Name},
+		}
+
+		switch config.Type {
+		case "slack":
+			item.entry.Type = integration.IntegrationTypeSlack
+			item.webhookURL = config.SlackWebhookURL
+		case "discord":
+			item.entry.Type = integration.IntegrationTypeDiscord
+			item.webhookURL = config.DiscordWebhookURL
+		default:
+			row.skip("notification type %q is not supported", config.Type)
+			continue
+		}
+
+		if item.webhookURL == "" {
+			row.skip("notification has no webhook url")
+			continue
+		}
+
+		source.integrations = append(source.integrations, item)
+	}
+
+	members := map[int][]string{}
+	for _, kumaMonitor := range backup.MonitorList {
+		if kumaMonitor.Parent != nil {
+			members[*kumaMonitor.Parent] = append(members[*kumaMonitor.Parent], strconv.Itoa(kumaMonitor.ID))
+		}
+	}
+
+	for i, kumaMonitor := range backup.MonitorList {
+		row := source.addRow(ImportRow{
+			Row:    i + 1,
+			Kind:   ChangeKindMonitor,
+			Name:   kumaMonitor.Name,
+			Status: ImportRowImported,
+		})
+
+		item := importedMonitor{
+			row:      row,
+			sourceID: strconv.Itoa(kumaMonitor.ID),
+			members:  members[kumaMonitor.ID],
+		}
+
+		if err := mapKumaMonitor(kumaMonitor, &item); err != nil {
+			row.skip("%v", err)
+			continue
+		}
+
+		source.monitors = append(source.monitors, item)
+	}
+
+	return source, nil
+}
+
+func mapKumaMonitor(kumaMonitor kumaMonitor, item *importedMonitor) error {
+	row := item.row
+	interval := kumaMonitor.Interval
+	if interval <= 0 {
+		interval = defaultImportInterval
+	}
+
+	enabled := bool(kumaMonitor.Active)
+	entry := MonitorManifest{
+		Name:      kumaMonitor.Name,
+		Enabled:   &enabled,
+		Interval:  interval,
+		Threshold: max(kumaMonitor.MaxRetries, 0) + 1,
+	}
+
+	timeout := importTimeout(interval)
+	if kumaMonitor.Timeout > 0 {
+		timeout = int(math.Ceil(kumaMonitor.Timeout))
+	}
+
+	switch kumaMonitor.Type {
+	case "http", "keyword":
+		method := strings.ToUpper(kumaMonitor.Method)
+		if method == "" {
+			method = "GET"
+		}
+		if kumaMonitor.Type == "http" && !slices.Contains([]string{"GET", "POST", "PUT"}, method) {
+			return fmt.Errorf("method %s is not supported", method)
+		}
+
+		headers := map[string]string{}
+		if strings.TrimSpace(kumaMonitor.Headers) != "" {
+			if err := json.Unmarshal([]byte(kumaMonitor.Headers), &headers); err != nil {
+				return fmt.Errorf("invalid headers: %v", err)
+			}
+		}
+
+		entry.URL = kumaMonitor.URL
+		entry.Timeout = timeout
+		entry.TLSSkipVerify = bool(kumaMonitor.IgnoreTLS)
+		if kumaMonitor.MaxRedirects != nil {
+			if *kumaMonitor.MaxRedirects == 0 {
+				entry.DisableRedirects = true
+			} else {
+				entry.MaxRedirects = *kumaMonitor.MaxRedirects
+			}
+		}
+
+		if kumaMonitor.Type == "http" {
+			entry.Type = MonitorTypeHTTP
+			entry.Method = method
+			if len(headers) > 0 {
+				entry.Headers = headers
+			}
+			if kumaMonitor.Body != "" {
+				row.unsupported("body")
+			}
+			break
+		}
+
+		// Keyword monitors become a single step transaction that checks the
+		// response body.
+		if kumaMonitor.InvertKeyword {
+			return fmt.Errorf("inverted keyword monitors are not supported")
+		}
+		entry.Type = MonitorTypeTransaction
+		entry.Steps = []TransactionStep{{
+			Name:    "keyword",
+			Method:  method,
+			URL:     kumaMonitor.URL,
+			Headers: headers,
+			Body:    kumaMonitor.Body,
+			Assertions: []Assertion{{
+				Type:     AssertionTypeBody,
+				Operator: AssertionOperatorContains,
+				Value:    kumaMonitor.Keyword,
+			}},
+		}}
+	case "push":
+		entry.Type = MonitorTypeHeartbeat
+		if kumaMonitor.PushToken != "" {
+			row.unsupported("pushToken (a new heartbeat token is issued)")
+		}
+	case "group":
+		entry.Type = MonitorTypeGroup
+	default:
+		return fmt.Errorf("monitor type %q is not supported", kumaMonitor.Type)
+	}
+
+	for _, tag := range kumaMonitor.Tags {
+		entry.Tags = append(entry.Tags, TagRequest{Key: tag.Name, Value: tag.Value})
+	}
+
+	if kumaMonitor.Description != "" {
+		row.unsupported("description")
+	}
+	if kumaMonitor.UpsideDown {
+		row.unsupported("upsideDown")
+	}
+	if len(kumaMonitor.AcceptedStatusCodes) > 0 && !slices.Equal(kumaMonitor.AcceptedStatusCodes, []string{"200-299"}) {
+		row.unsupported("accepted_statuscodes")
+	}
+	if kumaMonitor.RetryInterval > 0 && kumaMonitor.RetryInterval != kumaMonitor.Interval {
+		row.unsupported("retryInterval")
+	}
+	if kumaMonitor.ResendInterval > 0 {
+		row.unsupported("resendInterval")
+	}
+	if kumaMonitor.ExpiryNotification {
+		row.unsupported("expiryNotification")
+	}
+	if kumaMonitor.BasicAuthUser != "" || (kumaMonitor.AuthMethod != "" && kumaMonitor.AuthMethod != "null") {
+		row.unsupported("authMethod")
+	}
+	if kumaMonitor.ProxyID != nil {
+		row.unsupported("proxyId")
+	}
+
+	notifications, err := kumaNotificationIDs(kumaMonitor.NotificationIDList)
+	if err != nil {
+		return err
+	}
+
+	item.entry = entry
+	item.integrations = notifications
+
+	return nil
+}
+
+// kumaNotificationIDs reads notificationIDList, which backups write either as
+// an object of id to enabled flag or as a plain list of ids.
+func kumaNotificationIDs(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var enabled map[string]kumaBool
+	if err := json.Unmarshal(raw, &enabled); err == nil {
+		var ids []string
+		for id, on := range enabled {
+			if on {
+				ids = append(ids, id)
+			}
+		}
+		slices.Sort(ids)
+		return ids, nil
+	}
+
+	var list []int
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("invalid notificationIDList: %v", err)
+	}
+
+	ids := make([]string, len(list))
+	for i, id := range list {
+		ids[i] = strconv.Itoa(id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,570 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"github.com/mateusgcoelho/sentinel/engine/internal/slug"
+	"gorm.io/gorm"
+)
+
+const (
+	manifestVersion = 1
+	maxManifestSize = 5 << 20
+
+	// redactedValue stands in for exported values that may carry
+	// credentials.
+	redactedValue = "${redacted}"
+)
+
+var (
+	ErrInvalidSlug     = errors.New("slug must contain only lowercase letters, digits and dashes")
+	ErrSlugInUse       = errors.New("slug is already in use")
+	ErrInvalidManifest = errors.New("invalid manifest")
+)
+
+// Manifest is the declarative form of the monitors, the integrations and the
+// links between them. Entries are keyed by slug instead of ID so the same
+// file can be applied to any instance. Escalation policies are not part of
+// it and are left untouched.
+type Manifest struct {
+	Version      int                   `json:"version"`
+	Integrations []IntegrationManifest `json:"integrations"`
+	Monitors     []MonitorManifest     `json:"monitors"`
+}
+
+// IntegrationManifest describes an integration. Webhook URLs carry
+// credentials, so they are only accepted as secret references and are left
+// out of exports otherwise. An empty URL keeps the current one.
+type IntegrationManifest struct {
+	Slug string                      `json:"slug"`
+	Name string                      `json:"name"`
+	Type integration.IntegrationType `json:"type"`
+	URL  string                      `json:"url,omitempty"`
+}
+
+type MemberManifest struct {
+	Monitor string `json:"monitor"`
+	Weight  int    `json:"weight,omitempty"`
+}
+
+// MonitorManifest mirrors CreateMonitorConfigRequest with relations written
+// as slugs. Like webhook URLs, the TLS client key must be a secret reference
+// and an empty one keeps the current key. Header values, step headers and
+// bodies and environment values are exported as redactedValue unless they
+// use secret references, and importing redactedValue keeps the current
+// value.
+type MonitorManifest struct {
+	Slug                 string            `json:"slug"`
+	Name                 string            `json:"name"`
+	Enabled              *bool             `json:"enabled,omitempty"`
+	Folder               string            `json:"folder,omitempty"`
+	Tags                 []TagRequest      `json:"tags,omitempty"`
+	Type                 MonitorType       `json:"type,omitempty"`
+	URL                  string            `json:"url,omitempty"`
+	Method               string            `json:"method,omitempty"`
+	Interval             int               `json:"interval"`
+	Threshold            int               `json:"threshold"`
+	Timeout              int               `json:"timeout,omitempty"`
+	GracePeriod          int               `json:"grace_period,omitempty"`
+	Headers              map[string]string `json:"headers,omitempty"`
+	GrpcService          string            `json:"grpc_service,omitempty"`
+	Subprotocol          string            `json:"subprotocol,omitempty"`
+	SendMessage          string            `json:"send_message,omitempty"`
+	ExpectMessage        string            `json:"expect_message,omitempty"`
+	HttpVersion          HttpVersion       `json:"http_version,omitempty"`
+	IPVersion            IPVersion         `json:"ip_version,omitempty"`
+	DisableRedirects     bool              `json:"disable_redirects,omitempty"`
+	MaxRedirects         int               `json:"max_redirects,omitempty"`
+	TLSSkipVerify        bool              `json:"tls_skip_verify,omitempty"`
+	TLSCACertificate     string            `json:"tls_ca_certificate,omitempty"`
+	TLSClientCertificate string            `json:"tls_client_certificate,omitempty"`
+	TLSClientKey         string            `json:"tls_client_key,omitempty"`
+	ProxyURL             string            `json:"proxy_url,omitempty"`
+	DNSServer            string            `json:"dns_server,omitempty"`
+	ResponseBodyLimit    int               `json:"response_body_limit,omitempty"`
+	DegradedResponseTime int               `json:"degraded_response_time,omitempty"`
+	FlapWindow           *int              `json:"flap_window,omitempty"`
+	FlapThreshold        *int              `json:"flap_threshold,omitempty"`
+	AggregationRule      AggregationRule   `json:"aggregation_rule,omitempty"`
+	AggregationMinimum   int               `json:"aggregation_minimum,omitempty"`
+	Members              []MemberManifest  `json:"members,omitempty"`
+	Steps                []TransactionStep `json:"steps,omitempty"`
+	Command              string            `json:"command,omitempty"`
+	Arguments            []string          `json:"arguments,omitempty"`
+	Environment          []string          `json:"environment,omitempty"`
+	Integrations         []string          `json:"integrations"`
+	Parents              []string          `json:"parents,omitempty"`
+}
+
+// request maps the entry onto a create request so it goes through the same
+// validation and defaults as the API.
+func (m MonitorManifest) request() CreateMonitorConfigRequest {
+	req := CreateMonitorConfigRequest{
+		Slug:                 m.Slug,
+		Name:                 m.Name,
+		Folder:               m.Folder,
+		Tags:                 m.Tags,
+		Type:                 m.Type,
+		URL:                  m.URL,
+		Method:               m.Method,
+		Interval:             m.Interval,
+		Threshold:            m.Threshold,
+		Timeout:              m.Timeout,
+		GracePeriod:          m.GracePeriod,
+		Headers:              m.Headers,
+		GrpcService:          m.GrpcService,
+		Subprotocol:          m.Subprotocol,
+		SendMessage:          m.SendMessage,
+		ExpectMessage:        m.ExpectMessage,
+		HttpVersion:          m.HttpVersion,
+		IPVersion:            m.IPVersion,
+		DisableRedirects:     m.DisableRedirects,
+		MaxRedirects:         m.MaxRedirects,
+		TLSSkipVerify:        m.TLSSkipVerify,
+		TLSCACertificate:     m.TLSCACertificate,
+		TLSClientCertificate: m.TLSClientCertificate,
+		TLSClientKey:         m.TLSClientKey,
+		ProxyURL:             m.ProxyURL,
+		DNSServer:            m.DNSServer,
+		ResponseBodyLimit:    m.ResponseBodyLimit,
+		DegradedResponseTime: m.DegradedResponseTime,
+		FlapWindow:           m.FlapWindow,
+		FlapThreshold:        m.FlapThreshold,
+		AggregationRule:      m.AggregationRule,
+		AggregationMinimum:   m.AggregationMinimum,
+		Steps:                m.Steps,
+		Command:              m.Command,
+		Arguments:            m.Arguments,
+		Environment:          m.Environment,
+	}
+
+	// Member IDs are only known once the manifest is applied, validation
+	// only needs the member count.
+	for i, member := range m.Members {
+		req.MemberList = append(req.MemberList, GroupMemberRequest{MonitorID: uint(i + 1), Weight: member.Weight})
+	}
+
+	return req
+}
+
+func (m MonitorManifest) enabled() bool {
+	return m.Enabled == nil || *m.Enabled
+}
+
+// monitorConfig builds the unsaved monitor described by the entry, without
+// its relations.
+func (m MonitorManifest) monitorConfig() MonitorConfig {
+	monitorConfig := m.request().monitorConfig()
+	monitorConfig.Slug = &m.Slug
+	monitorConfig.Enabled = m.enabled()
+
+	return monitorConfig
+}
+
+// canonical rewrites the entry the way an export of the resulting monitor
+// would look, so that it can be compared field by field with the current
+// state.
+func (m MonitorManifest) canonical() MonitorManifest {
+	members := make([]MemberManifest, 0, len(m.Members))
+	for _, member := range m.Members {
+		if member.Weight == 0 {
+			member.Weight = 1
+		}
+		members = append(members, member)
+	}
+
+	return exportMonitor(m.monitorConfig(), m.Integrations, m.Parents, members)
+}
+
+// exportMonitor turns a monitor into its manifest entry. Values that only
+// matter for another monitor type are left out.
+func exportMonitor(monitorConfig MonitorConfig, integrations []string, parents []string, members []MemberManifest) MonitorManifest {
+	flapWindow := monitorConfig.FlapWindow
+	flapThreshold := monitorConfig.FlapThreshold
+	enabled := monitorConfig.Enabled
+
+	tags := make([]TagRequest, 0, len(monitorConfig.Tags))
+	for _, tag := range monitorConfig.Tags {
+		tags = append(tags, TagRequest{Key: tag.Key, Value: tag.Value})
+	}
+	slices.SortFunc(tags, func(a, b TagRequest) int {
+		return strings.Compare(a.Key+":"+a.Value, b.Key+":"+b.Value)
+	})
+
+	entry := MonitorManifest{
+		Slug:                 stringValue(monitorConfig.Slug),
+		Name:                 monitorConfig.Name,
+		Enabled:              &enabled,
+		Folder:               monitorConfig.Folder,
+		Tags:                 tags,
+		Type:                 monitorConfig.Type,
+		URL:                  monitorConfig.URL,
+		Method:               monitorConfig.Method,
+		Interval:             monitorConfig.Interval,
+		Threshold:            monitorConfig.Threshold,
+		Timeout:              monitorConfig.Timeout,
+		GracePeriod:          monitorConfig.GracePeriod,
+		Headers:              monitorConfig.Headers.Data(),
+		GrpcService:          monitorConfig.GrpcService,
+		Subprotocol:          monitorConfig.Subprotocol,
+		SendMessage:          monitorConfig.SendMessage,
+		ExpectMessage:        monitorConfig.ExpectMessage,
+		HttpVersion:          monitorConfig.HttpVersion,
+		IPVersion:            monitorConfig.IPVersion,
+		DisableRedirects:     monitorConfig.DisableRedirects,
+		MaxRedirects:         monitorConfig.MaxRedirects,
+		TLSSkipVerify:        monitorConfig.TLSSkipVerify,
+		TLSCACertificate:     monitorConfig.TLSCACertificate,
+		TLSClientCertificate: monitorConfig.TLSClientCertificate,
+		ProxyURL:             monitorConfig.ProxyURL,
+		DNSServer:            monitorConfig.DNSServer,
+		ResponseBodyLimit:    monitorConfig.ResponseBodyLimit,
+		DegradedResponseTime: monitorConfig.DegradedResponseTime,
+		FlapWindow:           &flapWindow,
+		FlapThreshold:        &flapThreshold,
+		Steps:                monitorConfig.Steps,
+		Command:              monitorConfig.Command,
+		Arguments:            monitorConfig.Arguments,
+		Environment:          monitorConfig.Environment,
+		Integrations:         sortedCopy(uniqueStrings(integrations)),
+		Parents:              sortedCopy(uniqueStrings(parents)),
+	}
+
+	if secret.IsReference(monitorConfig.TLSClientKey) {
+		entry.TLSClientKey = monitorConfig.TLSClientKey
+	}
+
+	if monitorConfig.Type == MonitorTypeGroup {
+		entry.AggregationRule = monitorConfig.AggregationRule
+		entry.AggregationMinimum = monitorConfig.AggregationMinimum
+		entry.Members = slices.Clone(members)
+		slices.SortFunc(entry.Members, func(a, b MemberManifest) int {
+			return strings.Compare(a.Monitor, b.Monitor)
+		})
+	}
+
+	return entry
+}
+
+// manifestEntry exports a monitor loaded with its integrations, parents,
+// members and tags.
+func manifestEntry(monitorConfig MonitorConfig) MonitorManifest {
+	var integrationSlugs []string
+	for _, item := range monitorConfig.Integrations {
+		integrationSlugs = append(integrationSlugs, stringValue(item.Slug))
+	}
+
+	var parentSlugs []string
+	for _, parent := range monitorConfig.Parents {
+		parentSlugs = append(parentSlugs, stringValue(parent.Slug))
+	}
+
+	var members []MemberManifest
+	for _, member := range monitorConfig.Members {
+		if member.Member == nil {
+			continue
+		}
+		members = append(members, MemberManifest{Monitor: stringValue(member.Member.Slug), Weight: member.Weight})
+	}
+
+	return exportMonitor(monitorConfig, integrationSlugs, parentSlugs, members)
+}
+
+// redacted returns the entry with every value that may carry a credential
+// replaced by redactedValue.
+func (m MonitorManifest) redacted() MonitorManifest {
+	m.Headers = redactHeaders(m.Headers)
+
+	steps := make([]TransactionStep, 0, len(m.Steps))
+	for _, step := range m.Steps {
+		step.Headers = redactHeaders(step.Headers)
+		step.Body = redactValue(step.Body)
+		steps = append(steps, step)
+	}
+	if m.Steps != nil {
+		m.Steps = steps
+	}
+
+	environment := make([]string, 0, len(m.Environment))
+	for _, variable := range m.Environment {
+		name, value, _ := strings.Cut(variable, "=")
+		environment = append(environment, name+"="+redactValue(value))
+	}
+	if m.Environment != nil {
+		m.Environment = environment
+	}
+
+	return m
+}
+
+// unredacted puts the values of current back where the entry holds
+// redactedValue. Values current does not have stay redacted.
+func (m MonitorManifest) unredacted(current MonitorManifest) MonitorManifest {
+	m.Headers = unredactHeaders(m.Headers, current.Headers)
+
+	steps := make([]TransactionStep, 0, len(m.Steps))
+	for i, step := range m.Steps {
+		if i < len(current.Steps) {
+			step.Headers = unredactHeaders(step.Headers, current.Steps[i].Headers)
+			if step.Body == redactedValue {
+				step.Body = current.Steps[i].Body
+			}
+		}
+		steps = append(steps, step)
+	}
+	if m.Steps != nil {
+		m.Steps = steps
+	}
+
+	currentEnvironment := map[string]string{}
+	for _, variable := range current.Environment {
+		name, value, _ := strings.Cut(variable, "=")
+		currentEnvironment[name] = value
+	}
+
+	environment := make([]string, 0, len(m.Environment))
+	for _, variable := range m.Environment {
+		name, value, _ := strings.Cut(variable, "=")
+		if currentValue, ok := currentEnvironment[name]; ok && value == redactedValue {
+			variable = name + "=" + currentValue
+		}
+		environment = append(environment, variable)
+	}
+	if m.Environment != nil {
+		m.Environment = environment
+	}
+
+	return m
+}
+
+// redactedFields lists the fields of the entry that still hold
+// redactedValue.
+func (m MonitorManifest) redactedFields() []string {
+	var fields []string
+
+	for _, name := range slices.Sorted(maps.Keys(m.Headers)) {
+		if m.Headers[name] == redactedValue {
+			fields = append(fields, "headers."+name)
+		}
+	}
+
+	for i, step := range m.Steps {
+		for _, name := range slices.Sorted(maps.Keys(step.Headers)) {
+			if step.Headers[name] == redactedValue {
+				fields = append(fields, fmt.Sprintf("steps[%d].headers.%s", i, name))
+			}
+		}
+		if step.Body == redactedValue {
+			fields = append(fields, fmt.Sprintf("steps[%d].body", i))
+		}
+	}
+
+	for _, variable := range m.Environment {
+		if name, value, _ := strings.Cut(variable, "="); value == redactedValue {
+			fields = append(fields, "environment."+name)
+		}
+	}
+
+	return fields
+}
+
+// redactValue keeps empty values and values that use secret references,
+// whose credentials are stored in the secrets.
+func redactValue(value string) string {
+	if value == "" || len(secret.References(value)) > 0 {
+		return value
+	}
+
+	return redactedValue
+}
+
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		redacted[name] = redactValue(value)
+	}
+
+	return redacted
+}
+
+func unredactHeaders(headers map[string]string, current map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+
+	unredacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if currentValue, ok := current[name]; ok && value == redactedValue {
+			value = currentValue
+		}
+		unredacted[name] = value
+	}
+
+	return unredacted
+}
+
+func exportIntegration(item integration.IntegrationConfig) IntegrationManifest {
+	snapshot := item.Snapshot()
+
+	return IntegrationManifest{
+		Slug: snapshot.Slug,
+		Name: snapshot.Name,
+		Type: snapshot.Type,
+		URL:  snapshot.URL,
+	}
+}
+
+// redacted returns the manifest as exported, see MonitorManifest.redacted.
+func (m Manifest) redacted() Manifest {
+	monitors := make([]MonitorManifest, 0, len(m.Monitors))
+	for _, entry := range m.Monitors {
+		monitors = append(monitors, entry.redacted())
+	}
+	m.Monitors = monitors
+
+	return m
+}
+
+// manifestState is the current configuration, exported and indexed by slug.
+type manifestState struct {
+	manifest     Manifest
+	integrations map[string]integration.IntegrationConfig
+	monitors     map[string]MonitorConfig
+	entries      map[string]MonitorManifest
+}
+
+func loadManifestState(database *gorm.DB) (manifestState, error) {
+	state := manifestState{
+		manifest:     Manifest{Version: manifestVersion, Integrations: []IntegrationManifest{}, Monitors: []MonitorManifest{}},
+		integrations: map[string]integration.IntegrationConfig{},
+		monitors:     map[string]MonitorConfig{},
+		entries:      map[string]MonitorManifest{},
+	}
+
+	var integrations []integration.IntegrationConfig
+	if err := database.Order("slug ASC").Find(&integrations).Error; err != nil {
+		return state, err
+	}
+
+	for _, item := range integrations {
+		state.integrations[stringValue(item.Slug)] = item
+		state.manifest.Integrations = append(state.manifest.Integrations, exportIntegration(item))
+	}
+
+	var monitors []MonitorConfig
+	if err := database.
+		Preload("Integrations").
+		Preload("Parents").
+		Preload("Members.Member").
+		Preload("Tags").
+		Order("slug ASC").
+		Find(&monitors).Error; err != nil {
+		return state, err
+	}
+
+	for _, monitorConfig := range monitors {
+		entry := manifestEntry(monitorConfig)
+
+		state.monitors[entry.Slug] = monitorConfig
+		state.entries[entry.Slug] = entry
+		state.manifest.Monitors = append(state.manifest.Monitors, entry)
+	}
+
+	return state, nil
+}
+
+// parseManifest accepts YAML or JSON, JSON being a subset of YAML. Unknown
+// fields are rejected so typos do not silently reset a value.
+func parseManifest(body []byte) (Manifest, error) {
+	var manifest Manifest
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return manifest, fmt.Errorf("%w: document is empty", ErrInvalidManifest)
+	}
+
+	document, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		return manifest, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+	}
+
+	if manifest.Version != manifestVersion {
+		return manifest, fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalidManifest, manifest.Version, manifestVersion)
+	}
+
+	return manifest, nil
+}
+
+func encodeManifest(manifest Manifest, format string) ([]byte, error) {
+	document, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "json" {
+		return document, nil
+	}
+
+	return yaml.JSONToYAML(document)
+}
+
+// UniqueSlug derives a free slug from the monitor name.
+func UniqueSlug(database *gorm.DB, name string) (string, error) {
+	return slug.Unique(slug.Make(name, "monitor"), func(candidate string) (bool, error) {
+		var count int64
+		err := database.Model(&MonitorConfig{}).Where("slug = ?", candidate).Count(&count).Error
+		return count > 0, err
+	})
+}
+
+// resolveMonitorSlug returns the requested slug when no other monitor uses
+// it, or derives one from the name when none was requested.
+func resolveMonitorSlug(database *gorm.DB, requested string, name string, monitorID uint) (string, error) {
+	if requested == "" {
+		return UniqueSlug(database, name)
+	}
+
+	var count int64
+	if err := database.Model(&MonitorConfig{}).Where("slug = ? AND id <> ?", requested, monitorID).Count(&count).Error; err != nil {
+		return "", err
+	}
+
+	if count > 0 {
+		return "", ErrSlugInUse
+	}
+
+	return requested, nil
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string{}, values...)
+	slices.Sort(sorted)
+
+	return sorted
+}
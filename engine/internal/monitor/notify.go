@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"log"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/discord"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"github.com/mateusgcoelho/sentinel/engine/internal/slack"
+	"gorm.io/gorm"
+)
+
+func sendAlertMessage(database *gorm.DB, logPrefix string, integrations []integration.IntegrationConfig, monitorName string, errorMessage string, failedAttempts int, dependents []string) {
+	for _, item := range integrations {
+		webhookURL, err := secret.Expand(database, item.URL)
+		if err != nil {
+			log.Printf("%s failed to resolve webhook of integration [%s]: %v", logPrefix, item.Name, err)
+			continue
+		}
+
+		switch item.Type {
+		case integration.IntegrationTypeDiscord:
+			err = discord.SendAlertMessage(webhookURL, monitorName, errorMessage, failedAttempts, dependents)
+		case integration.IntegrationTypeSlack:
+			err = slack.SendAlertMessage(webhookURL, monitorName, errorMessage, failedAttempts, dependents)
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.Printf("%s failed to send alert via integration [%s]: %v", logPrefix, item.Name, err)
+		} else {
+			log.Printf("%s alert sent successfully via integration [%s]", logPrefix, item.Name)
+		}
+	}
+}
+
+func sendRecoverMessage(database *gorm.DB, logPrefix string, integrations []integration.IntegrationConfig, monitorName string, failedAttempts int) {
+	for _, item := range integrations {
+		webhookURL, err := secret.Expand(database, item.URL)
+		if err != nil {
+			log.Printf("%s failed to resolve webhook of integration [%s]: %v", logPrefix, item.Name, err)
+			continue
+		}
+
+		switch item.Type {
+		case integration.IntegrationTypeDiscord:
+			err = discord.SendRecoverMessage(webhookURL, monitorName, failedAttempts)
+		case integration.IntegrationTypeSlack:
+			err = slack.SendRecoverMessage(webhookURL, monitorName, failedAttempts)
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.Printf("%s failed to send recovery alert via integration [%s]: %v", logPrefix, item.Name, err)
+		} else {
+			log.Printf("%s recovery alert sent successfully via integration [%s]", logPrefix, item.Name)
+		}
+	}
+}
+
+func sendFlappingMessage(database *gorm.DB, logPrefix string, integrations []integration.IntegrationConfig, monitorName string, flapping bool, changeRate int) {
+	for _, item := range integrations {
+		webhookURL, err := secret.Expand(database, item.URL)
+		if err != nil {
+			log.Printf("%s failed to resolve webhook of integration [%s]: %v", logPrefix, item.Name, err)
+			continue
+		}
+
+		switch item.Type {
+		case integration.IntegrationTypeDiscord:
+			err = discord.SendFlappingMessage(webhookURL, monitorName, flapping, changeRate)
+		case integration.IntegrationTypeSlack:
+			err = slack.SendFlappingMessage(webhookURL, monitorName, flapping, changeRate)
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.Printf("%s failed to send flapping alert via integration [%s]: %v", logPrefix, item.Name, err)
+		} else {
+			log.Printf("%s flapping alert sent successfully via integration [%s]", logPrefix, item.Name)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/goccy/go-yaml"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"gorm.io/gorm"
+)
+
+const openAPIHealthTag = "health"
+
+var (
+	ErrInvalidOpenAPI       = errors.New("invalid OpenAPI document")
+	ErrBaseURLRequired      = errors.New("base_url is required when the document has no absolute server url")
+	ErrOperationNotEligible = errors.New("operation cannot be monitored")
+)
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// OpenAPIRequest describes the monitors to generate from a document. The
+// document may be JSON or YAML. Operations selects operations by key
+// ("GET /health") or operationId, all eligible operations are used when it
+// is empty.
+type OpenAPIRequest struct {
+	Document          string       `json:"document" binding:"required"`
+	BaseURL           string       `json:"base_url" binding:"omitempty,url"`
+	Operations        []string     `json:"operations"`
+	Folder            string       `json:"folder"`
+	Tags              []TagRequest `json:"tags" binding:"omitempty,dive"`
+	Interval          int          `json:"interval" binding:"omitempty,min=1"`
+	Threshold         int          `json:"threshold" binding:"omitempty,min=1"`
+	Timeout           int          `json:"timeout" binding:"omitempty,min=1"`
+	IntegrationIdList []uint       `json:"integration_id_list"`
+}
+
+// OpenAPIOperation is an operation of the document and, when it can be
+// monitored, the monitor proposed for it. Only GET operations and operations
+// tagged health are eligible, and only when they need no required
+// parameter or request body.
+type OpenAPIOperation struct {
+	Key            string                      `json:"key"`
+	OperationID    string                      `json:"operation_id,omitempty"`
+	Method         string                      `json:"method"`
+	Path           string                      `json:"path"`
+	Summary        string                      `json:"summary,omitempty"`
+	Tags           []string                    `json:"tags"`
+	ExpectedStatus []string                    `json:"expected_status"`
+	Eligible       bool                        `json:"eligible"`
+	Reason         string                      `json:"reason,omitempty"`
+	Monitor        *CreateMonitorConfigRequest `json:"monitor,omitempty"`
+}
+
+type openAPIDocument struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title string `json:"title"`
+	} `json:"info"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters    map[string]openAPIParameter   `json:"parameters"`
+		RequestBodies map[string]openAPIRequestBody `json:"requestBodies"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Tags        []string                   `json:"tags"`
+	Parameters  []openAPIParameter         `json:"parameters"`
+	RequestBody *openAPIRequestBody        `json:"requestBody"`
+	Responses   map[string]json.RawMessage `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+}
+
+type openAPIRequestBody struct {
+	Ref      string `json:"$ref"`
+	Required bool   `json:"required"`
+}
+
+// parseOpenAPI reads an OpenAPI 3 document and lists its operations sorted by
+// path and method, with a proposed monitor for each eligible one.
+func parseOpenAPI(req OpenAPIRequest) ([]OpenAPIOperation, error) {
+	body, err := yaml.YAMLToJSON([]byte(req.Document))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOpenAPI, err)
+	}
+
+	var document openAPIDocument
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOpenAPI, err)
+	}
+
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%w: only OpenAPI 3 documents are supported", ErrInvalidOpenAPI)
+	}
+
+	baseURL, err := openAPIBaseURL(document, req.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(document.Paths))
+	for path := range document.Paths {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	operations := []OpenAPIOperation{}
+	for _, path := range paths {
+		item := document.Paths[path]
+
+		var shared []openAPIParameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("%w: parameters of %s: %v", ErrInvalidOpenAPI, path, err)
+			}
+		}
+
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+
+			var operation openAPIOperation
+			if err := json.Unmarshal(raw, &operation); err != nil {
+				return nil, fmt.Errorf("%w: %s %s: %v", ErrInvalidOpenAPI, strings.ToUpper(method), path, err)
+			}
+
+			operations = append(operations, proposeOperation(document, req, baseURL, path, strings.ToUpper(method), operation, shared))
+		}
+	}
+
+	return operations, nil
+}
+
+func proposeOperation(document openAPIDocument, req OpenAPIRequest, baseURL string, path string, method string, operation openAPIOperation, shared []openAPIParameter) OpenAPIOperation {
+	proposal := OpenAPIOperation{
+		Key:            method + " " + path,
+		OperationID:    operation.OperationID,
+		Method:         method,
+		Path:           path,
+		Summary:        operation.Summary,
+		Tags:           operation.Tags,
+		ExpectedStatus: successStatuses(operation.Responses),
+	}
+	if proposal.Tags == nil {
+		proposal.Tags = []string{}
+	}
+
+	healthTagged := slices.ContainsFunc(operation.Tags, func(tag string) bool {
+		return strings.EqualFold(tag, openAPIHealthTag)
+	})
+
+	switch {
+	case method != "GET" && !healthTagged:
+		proposal.Reason = "only GET operations and operations tagged health are monitored"
+		return proposal
+	case method == "OPTIONS" || method == "TRACE":
+		proposal.Reason = fmt.Sprintf("%s operations cannot be monitored", method)
+		return proposal
+	}
+
+	if name, ok := requiredParameter(document, append(slices.Clone(shared), operation.Parameters...)); ok {
+		proposal.Reason = fmt.Sprintf("parameter %q is required", name)
+		return proposal
+	}
+
+	if requestBodyRequired(document, operation.RequestBody) {
+		proposal.Reason = "request body is required"
+		return proposal
+	}
+
+	monitorReq := openAPIMonitor(document, req, baseURL+path, proposal)
+
+	if err := binding.Validator.ValidateStruct(monitorReq); err != nil {
+		proposal.Reason = err.Error()
+		return proposal
+	}
+
+	if err := monitorReq.Validate(); err != nil {
+		proposal.Reason = err.Error()
+		return proposal
+	}
+
+	proposal.Eligible = true
+	proposal.Monitor = &monitorReq
+
+	return proposal
+}
+
+// openAPIMonitor builds the monitor of an operation. Plain HTTP monitors
+// already expect a 2xx status, operations documenting other success codes,
+// or using a method HTTP monitors do not support, become a single step
+// transaction that asserts the status code.
+func openAPIMonitor(document openAPIDocument, req OpenAPIRequest, operationURL string, proposal OpenAPIOperation) CreateMonitorConfigRequest {
+	interval := req.Interval
+	if interval <= 0 {
+		interval = defaultImportInterval
+	}
+
+	threshold := req.Threshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = importTimeout(interval)
+	}
+
+	name := proposal.Key
+	if document.Info.Title != "" {
+		name = document.Info.Title + " " + proposal.Key
+	}
+
+	monitorReq := CreateMonitorConfigRequest{
+		Name:              name,
+		Folder:            req.Folder,
+		Tags:              req.Tags,
+		Type:              MonitorTypeHTTP,
+		URL:               operationURL,
+		Method:            proposal.Method,
+		Interval:          interval,
+		Threshold:         threshold,
+		Timeout:           timeout,
+		IntegrationIdList: req.IntegrationIdList,
+	}
+
+	expected := ""
+	if len(proposal.ExpectedStatus) > 0 && !strings.HasPrefix(proposal.ExpectedStatus[0], "2") {
+		expected = proposal.ExpectedStatus[0]
+	}
+
+	if expected == "" && slices.Contains([]string{"GET", "POST", "PUT"}, proposal.Method) {
+		return monitorReq
+	}
+
+	step := TransactionStep{
+		Name:   proposal.Key,
+		Method: proposal.Method,
+		URL:    operationURL,
+	}
+
+	if code, err := strconv.Atoi(expected); err == nil {
+		step.Assertions = []Assertion{{
+			Type:     AssertionTypeStatusCode,
+			Operator: AssertionOperatorEquals,
+			Value:    strconv.Itoa(code),
+		}}
+	} else if expected != "" {
+		// A range such as 3XX.
+		class := int(expected[0]-'0') * 100
+		step.Assertions = []Assertion{
+			{Type: AssertionTypeStatusCode, Operator: AssertionOperatorGreaterThan, Value: strconv.Itoa(class - 1)},
+			{Type: AssertionTypeStatusCode, Operator: AssertionOperatorLessThan, Value: strconv.Itoa(class + 100)},
+		}
+	}
+
+	// A documented redirect is the expected answer, following it would check
+	// a different endpoint.
+	if strings.HasPrefix(expected, "3") {
+		monitorReq.DisableRedirects = true
+	}
+
+	monitorReq.Type = MonitorTypeTransaction
+	monitorReq.URL = ""
+	monitorReq.Method = ""
+	monitorReq.Steps = []TransactionStep{step}
+
+	return monitorReq
+}
+
+// successStatuses returns the documented 2xx and 3xx responses, lowest
+// first, so 200 wins over 204 and 2XX ranges sort after exact codes.
+func successStatuses(responses map[string]json.RawMessage) []string {
+	statuses := []string{}
+	for status := range responses {
+		status = strings.ToUpper(status)
+		if len(status) == 3 && (status[0] == '2' || status[0] == '3') {
+			statuses = append(statuses, status)
+		}
+	}
+	slices.Sort(statuses)
+
+	return statuses
+}
+
+func requiredParameter(document openAPIDocument, parameters []openAPIParameter) (string, bool) {
+	for _, parameter := range parameters {
+		if name, ok := strings.CutPrefix(parameter.Ref, "#/components/parameters/"); ok {
+			parameter = document.Components.Parameters[name]
+		}
+
+		// Path parameters are always required, the flag only matters for
+		// the other locations.
+		if parameter.Required || parameter.In == "path" {
+			return parameter.Name, true
+		}
+	}
+
+	return "", false
+}
+
+func requestBodyRequired(document openAPIDocument, requestBody *openAPIRequestBody) bool {
+	if requestBody == nil {
+		return false
+	}
+
+	if name, ok := strings.CutPrefix(requestBody.Ref, "#/components/requestBodies/"); ok {
+		return document.Components.RequestBodies[name].Required
+	}
+
+	return requestBody.Required
+}
+
+// openAPIBaseURL prefers the requested base URL and falls back to the first
+// server of the document when it is absolute.
+func openAPIBaseURL(document openAPIDocument, requested string) (string, error) {
+	base := requested
+	if base == "" && len(document.Servers) > 0 {
+		base = document.Servers[0].URL
+	}
+
+	parsed, err := url.Parse(base)
+	if base == "" || err != nil || parsed.Host == "" || strings.Contains(base, "{") {
+		return "", ErrBaseURLRequired
+	}
+
+	return strings.TrimRight(base, "/"), nil
+}
+
+// createOpenAPIMonitors creates the monitors of the selected operations in a
+// single transaction. Slugs are derived from the monitor names.
+func createOpenAPIMonitors(database *gorm.DB, req OpenAPIRequest, author history.Author) ([]MonitorConfig, error) {
+	if len(req.IntegrationIdList) == 0 {
+		return nil, ErrIntegrationsRequired
+	}
+
+	operations, err := parseOpenAPI(req)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := selectOperations(operations, req.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	monitors := []MonitorConfig{}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		var integrations []integration.IntegrationConfig
+		if err := tx.Where("id IN ?", req.IntegrationIdList).Find(&integrations).Error; err != nil {
+			return err
+		}
+
+		if len(integrations) != len(req.IntegrationIdList) {
+			return ErrIntegrationNotFound
+		}
+
+		for _, operation := range selected {
+			// Generated requests go through the same checks as POST /monitors.
+			if err := binding.Validator.ValidateStruct(operation.Monitor); err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrOperationNotEligible, operation.Key, err)
+			}
+
+			if err := operation.Monitor.Validate(); err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrOperationNotEligible, operation.Key, err)
+			}
+
+			monitor := operation.Monitor.monitorConfig()
+			if err := validateHttpClientOptions(monitor); err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrOperationNotEligible, operation.Key, err)
+			}
+
+			monitorSlug, err := resolveMonitorSlug(tx, "", monitor.Name, 0)
+			if err != nil {
+				return err
+			}
+
+			monitor.Slug = &monitorSlug
+			monitor.Integrations = integrations
+
+			if err := tx.Create(&monitor).Error; err != nil {
+				return err
+			}
+
+			if err := recordMonitorVersions(tx, []uint{monitor.ID}, nil, author); err != nil {
+				return err
+			}
+
+			monitors = append(monitors, monitor)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return monitors, nil
+}
+
+// selectOperations returns the eligible operations matching the given keys or
+// operation IDs, or every eligible operation when none are given.
+func selectOperations(operations []OpenAPIOperation, wanted []string) ([]OpenAPIOperation, error) {
+	if len(wanted) == 0 {
+		var selected []OpenAPIOperation
+		for _, operation := range operations {
+			if operation.Eligible {
+				selected = append(selected, operation)
+			}
+		}
+
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("%w: the document has no eligible operations", ErrOperationNotEligible)
+		}
+
+		return selected, nil
+	}
+
+	selected := make([]OpenAPIOperation, 0, len(wanted))
+	for _, key := range uniqueStrings(wanted) {
+		index := slices.IndexFunc(operations, func(operation OpenAPIOperation) bool {
+			return operation.Key == key || (operation.OperationID != "" && operation.OperationID == key)
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %s not found", ErrOperationNotEligible, key)
+		}
+
+		operation := operations[index]
+		if !operation.Eligible {
+			return nil, fmt.Errorf("%w: %s: %s", ErrOperationNotEligible, key, operation.Reason)
+		}
+
+		selected = append(selected, operation)
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+)
+
+const testOpenAPIDocument = `{
+	"openapi": "3.0.0",
+	"info": {"title": "Orders"},
+	"servers": [{"url": "https://orders.example.com"}],
+	"paths": {
+		"/health": {"get": {"responses": {"200": {"description": "ok"}}}},
+		"/orders/{id}": {
+			"get": {
+				"parameters": [{"name": "id", "in": "path", "required": true}],
+				"responses": {"200": {"description": "ok"}}
+			}
+		}
+	}
+}`
+
+func TestCreateOpenAPIMonitorsCreatesEligibleOperations(t *testing.T) {
+	database := newTestDatabase(t)
+
+	hook := integration.IntegrationConfig{Name: "Slack", Type: integration.IntegrationTypeSlack, URL: "https://hooks.slack.com/services/ops"}
+	if err := database.Create(&hook).Error; err != nil {
+		t.Fatalf("failed to create integration: %v", err)
+	}
+
+	monitors, err := createOpenAPIMonitors(database, OpenAPIRequest{
+		Document:          testOpenAPIDocument,
+		IntegrationIdList: []uint{hook.ID},
+	}, history.Author{Source: "test"})
+	if err != nil {
+		t.Fatalf("createOpenAPIMonitors failed: %v", err)
+	}
+
+	if len(monitors) != 1 {
+		t.Fatalf("created %d monitors, want 1", len(monitors))
+	}
+
+	created := monitors[0]
+	if created.URL != "https://orders.example.com/health" || created.Method != "GET" || created.Timeout <= 0 {
+		t.Errorf("created monitor = %s %s timeout=%d", created.Method, created.URL, created.Timeout)
+	}
+}
@@ -24,14 +24,37 @@ func (w *PruneEventsWorker) StartWorker() error {
 		cutoff := time.Now().Add(-30 * time.Minute)
 		log.Printf("[prune-events-worker] pruning monitor events older than %d", cutoff.Unix())
 
-		result := w.database.Where("created_at < ?", cutoff.Unix()).Delete(&Attempt{})
+		pruned, err := pruneAttempts(w.database, cutoff.Unix())
 
-		if result.Error != nil {
-			log.Printf("[prune-events-worker] failed to prune monitor events: %v", result.Error)
+		if err != nil {
+			log.Printf("[prune-events-worker] failed to prune monitor events: %v", err)
 		} else {
-			log.Printf("[prune-events-worker] successfully pruned %d old monitor events", result.RowsAffected)
+			log.Printf("[prune-events-worker] successfully pruned %d old monitor events", pruned)
 		}
 
 		time.Sleep(30 * time.Second)
 	}
 }
+
+// pruneAttempts deletes the attempts created before cutoff, except the last
+// flap window of every monitor, which flapping detection needs however long
+// its interval is.
+func pruneAttempts(database *gorm.DB, cutoff int64) (int64, error) {
+	result := database.Exec(`
+		DELETE FROM attempts
+		WHERE created_at < ?
+		AND id NOT IN (
+			SELECT id FROM (
+				SELECT
+					attempts.id,
+					monitor_configs.flap_window,
+					ROW_NUMBER() OVER (PARTITION BY attempts.monitor_config_id ORDER BY attempts.id DESC) AS position
+				FROM attempts
+				JOIN monitor_configs ON monitor_configs.id = attempts.monitor_config_id
+				WHERE monitor_configs.flap_window > 1
+			)
+			WHERE position <= flap_window
+		)`, cutoff)
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"maps"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// expandSecrets returns a copy of the monitor with the secret references in
+// headers, the TLS client key, the environment and transaction steps
+// replaced by their values. The stored monitor keeps the references.
+func expandSecrets(database *gorm.DB, monitorConfig MonitorConfig) (MonitorConfig, error) {
+	if len(secretReferences(monitorConfig)) == 0 {
+		return monitorConfig, nil
+	}
+
+	headers, err := expandMap(database, monitorConfig.Headers.Data())
+	if err != nil {
+		return monitorConfig, err
+	}
+	monitorConfig.Headers = datatypes.NewJSONType(headers)
+
+	if monitorConfig.TLSClientKey, err = secret.Expand(database, monitorConfig.TLSClientKey); err != nil {
+		return monitorConfig, err
+	}
+
+	environment := make([]string, len(monitorConfig.Environment))
+	for i, variable := range monitorConfig.Environment {
+		if environment[i], err = secret.Expand(database, variable); err != nil {
+			return monitorConfig, err
+		}
+	}
+	monitorConfig.Environment = environment
+
+	steps := make([]TransactionStep, len(monitorConfig.Steps))
+	for i, step := range monitorConfig.Steps {
+		if step.Headers, err = expandMap(database, step.Headers); err != nil {
+			return monitorConfig, err
+		}
+		if step.Body, err = secret.Expand(database, step.Body); err != nil {
+			return monitorConfig, err
+		}
+		steps[i] = step
+	}
+	monitorConfig.Steps = steps
+
+	return monitorConfig, nil
+}
+
+func expandMap(database *gorm.DB, values map[string]string) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	expanded := maps.Clone(values)
+	for key, value := range expanded {
+		resolved, err := secret.Expand(database, value)
+		if err != nil {
+			return nil, err
+		}
+		expanded[key] = resolved
+	}
+
+	return expanded, nil
+}
+
+// secretReferences lists the secret names used by the fields that accept
+// references.
+func secretReferences(monitorConfig MonitorConfig) []string {
+	var names []string
+
+	for _, value := range monitorConfig.Headers.Data() {
+		names = append(names, secret.References(value)...)
+	}
+
+	names = append(names, secret.References(monitorConfig.TLSClientKey)...)
+
+	for _, variable := range monitorConfig.Environment {
+		names = append(names, secret.References(variable)...)
+	}
+
+	for _, step := range monitorConfig.Steps {
+		for _, value := range step.Headers {
+			names = append(names, secret.References(value)...)
+		}
+		names = append(names, secret.References(step.Body)...)
+	}
+
+	return names
+}
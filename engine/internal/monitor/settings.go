@@ -0,0 +1,16 @@
+package monitor
+
+// ExecutionSettings holds the process wide options that apply to every
+// monitor execution, as opposed to the per-monitor fields of MonitorConfig.
+type ExecutionSettings struct {
+	ExecEnabled       bool
+	ExecAllowedPaths  []string
+	ResponseBodyLimit int
+}
+
+var executionSettings = ExecutionSettings{}
+
+// Configure must be called before the worker starts.
+func Configure(settings ExecutionSettings) {
+	executionSettings = settings
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultSlotCount = 25
+
+// slotAttempt holds the few attempt columns the status bar needs, which keeps
+// scanning thousands of rows cheap.
+type slotAttempt struct {
+	MonitorConfigID uint
+	CreatedAt       int64
+	Healthy         bool
+	Health          HealthState
+}
+
+// loadSlots fills the status bar of every monitor with a single windowed query
+// over the attempts table. A resolution of zero uses each monitor's own
+// interval as the slot width.
+func loadSlots(database *gorm.DB, monitors []MonitorConfig, slotCount int, resolution int) error {
+	if len(monitors) == 0 {
+		return nil
+	}
+
+	monitorIDs := make([]uint, len(monitors))
+	for i, monitorConfig := range monitors {
+		monitorIDs[i] = monitorConfig.ID
+	}
+
+	now := time.Now()
+
+	// Two extra slots cover the partial interval in progress and the one
+	// that is still aligning to the reference time.
+	var attempts []slotAttempt
+	if err := database.
+		Model(&Attempt{}).
+		Select("attempts.monitor_config_id, attempts.created_at, attempts.healthy, attempts.health").
+		Joins("JOIN monitor_configs ON monitor_configs.id = attempts.monitor_config_id").
+		Where("attempts.monitor_config_id IN ?", monitorIDs).
+		Where("attempts.created_at >= ? - (CASE WHEN ? > 0 THEN ? ELSE monitor_configs.interval END) * ?", now.Unix(), resolution, resolution, slotCount+2).
+		Order("attempts.id ASC").
+		Find(&attempts).Error; err != nil {
+		return err
+	}
+
+	attemptsByMonitor := make(map[uint][]slotAttempt, len(monitors))
+	for _, attempt := range attempts {
+		attemptsByMonitor[attempt.MonitorConfigID] = append(attemptsByMonitor[attempt.MonitorConfigID], attempt)
+	}
+
+	for i, monitorConfig := range monitors {
+		slotWidth := resolution
+		if slotWidth <= 0 {
+			slotWidth = monitorConfig.Interval
+		}
+
+		monitors[i].Slots = generateSlots(attemptsByMonitor[monitorConfig.ID], slotCount, slotWidth, now)
+	}
+
+	return nil
+}
+
+// generateSlots buckets attempts into slotCount slots of slotWidth seconds
+// aligned to now. The attempt of the interval still in progress is left out
+// and, when several attempts share a slot, the latest one is shown.
+func generateSlots(attempts []slotAttempt, slotCount int, slotWidth int, now time.Time) []Slot {
+	slots := make([]Slot, slotCount)
+
+	if slotWidth <= 0 {
+		return slots
+	}
+
+	slotWidthInMilliseconds := int64(slotWidth) * 1000
+
+	referenceTime := (now.UnixMilli() / slotWidthInMilliseconds) * slotWidthInMilliseconds
+
+	for _, attempt := range attempts {
+		attemptTime := attempt.CreatedAt * 1000
+
+		if attemptTime >= referenceTime {
+			continue
+		}
+
+		slotsPassed := int((referenceTime - attemptTime) / slotWidthInMilliseconds)
+
+		finalIndex := slotCount - 1 - slotsPassed
+
+		if finalIndex < 0 || finalIndex >= slotCount {
+			continue
+		}
+
+		if slots[finalIndex].IsMonitoringEnabled && slots[finalIndex].Timestamp > attemptTime {
+			continue
+		}
+
+		slots[finalIndex] = Slot{
+			Timestamp:           attemptTime,
+			Healthy:             attempt.Healthy,
+			Health:              attempt.Health,
+			IsMonitoringEnabled: true,
+		}
+	}
+
+	return slots
+}
@@ -0,0 +1,241 @@
+package monitor
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// slotsNow sits 30 seconds into a minute, so with one minute slots the
+// reference time is 12:00:00 and the interval in progress is 12:00:00-12:01:00.
+var slotsNow = time.Date(2026, 1, 1, 12, 0, 30, 0, time.UTC)
+
+func slotsReference() int64 {
+	return time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC).Unix()
+}
+
+func TestGenerateSlotsBucketsAttemptsByWidth(t *testing.T) {
+	reference := slotsReference()
+
+	attempts := []slotAttempt{
+		{CreatedAt: reference - 10, Healthy: true, Health: HealthStateUp},
+		{CreatedAt: reference - 70, Healthy: false, Health: HealthStateDown},
+		{CreatedAt: reference - 130, Healthy: true, Health: HealthStateDegraded},
+	}
+
+	slots := generateSlots(attempts, 5, 60, slotsNow)
+
+	if len(slots) != 5 {
+		t.Fatalf("got %d slots, want 5", len(slots))
+	}
+
+	want := []struct {
+		enabled bool
+		health  HealthState
+	}{
+		{false, ""},
+		{false, ""},
+		{true, HealthStateDegraded},
+		{true, HealthStateDown},
+		{true, HealthStateUp},
+	}
+
+	for i, w := range want {
+		if slots[i].IsMonitoringEnabled != w.enabled || slots[i].Health != w.health {
+			t.Errorf("slot %d = %+v, want enabled=%t health=%s", i, slots[i], w.enabled, w.health)
+		}
+	}
+
+	if slots[4].Timestamp != (reference-10)*1000 {
+		t.Errorf("slot timestamp = %d, want %d", slots[4].Timestamp, (reference-10)*1000)
+	}
+}
+
+func TestGenerateSlotsShowsLatestAttemptOfSlot(t *testing.T) {
+	reference := slotsReference()
+
+	// Order must not matter, the latest attempt of the slot wins.
+	for _, attempts := range [][]slotAttempt{
+		{{CreatedAt: reference - 50, Health: HealthStateDown}, {CreatedAt: reference - 5, Health: HealthStateUp}},
+		{{CreatedAt: reference - 5, Health: HealthStateUp}, {CreatedAt: reference - 50, Health: HealthStateDown}},
+	} {
+		slots := generateSlots(attempts, 3, 60, slotsNow)
+
+		if slots[2].Health != HealthStateUp {
+			t.Errorf("last slot health = %s, want the latest attempt's %s", slots[2].Health, HealthStateUp)
+		}
+	}
+}
+
+func TestGenerateSlotsLeavesOutIntervalInProgress(t *testing.T) {
+	reference := slotsReference()
+
+	attempts := []slotAttempt{
+		{CreatedAt: reference, Health: HealthStateUp},
+		{CreatedAt: reference + 20, Health: HealthStateUp},
+	}
+
+	for i, slot := range generateSlots(attempts, 3, 60, slotsNow) {
+		if slot.IsMonitoringEnabled {
+			t.Errorf("slot %d holds an attempt of the interval still in progress: %+v", i, slot)
+		}
+	}
+}
+
+func TestGenerateSlotsDropsAttemptsBeforeWindow(t *testing.T) {
+	reference := slotsReference()
+
+	attempts := []slotAttempt{
+		{CreatedAt: reference - 3*60, Health: HealthStateDown},
+		{CreatedAt: reference - 3*60 + 1, Health: HealthStateUp},
+	}
+
+	slots := generateSlots(attempts, 3, 60, slotsNow)
+
+	if !slots[0].IsMonitoringEnabled || slots[0].Health != HealthStateUp {
+		t.Errorf("oldest slot = %+v, want the attempt inside the window", slots[0])
+	}
+}
+
+func TestGenerateSlotsWithoutWidth(t *testing.T) {
+	attempts := []slotAttempt{{CreatedAt: slotsReference() - 10, Health: HealthStateUp}}
+
+	for _, width := range []int{0, -1} {
+		slots := generateSlots(attempts, 4, width, slotsNow)
+
+		if len(slots) != 4 {
+			t.Fatalf("width %d: got %d slots, want 4", width, len(slots))
+		}
+
+		for i, slot := range slots {
+			if slot.IsMonitoringEnabled {
+				t.Errorf("width %d: slot %d should be empty", width, i)
+			}
+		}
+	}
+}
+
+func TestMonitorListQuerySlotBounds(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{query: "", wantErr: false},
+		{query: "slots=1&resolution=1", wantErr: false},
+		{query: "slots=500&resolution=86400", wantErr: false},
+		{query: "slots=501", wantErr: true},
+		{query: "slots=-1", wantErr: true},
+		{query: "resolution=86401", wantErr: true},
+		{query: "resolution=-60", wantErr: true},
+		{query: "slots=ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/monitors?"+tt.query, nil)
+
+		var query MonitorListQuery
+		err := binding.Query.Bind(req, &query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, wantErr %t", tt.query, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadSlotsUsesEachMonitorsInterval(t *testing.T) {
+	database := newTestDatabase(t)
+
+	fast := MonitorConfig{Name: "fast", Interval: 10, Threshold: 1}
+	slow := MonitorConfig{Name: "slow", Interval: 3600, Threshold: 1}
+	if err := database.Create(&[]*MonitorConfig{&fast, &slow}).Error; err != nil {
+		t.Fatalf("failed to create monitors: %v", err)
+	}
+
+	// Two hours back is far outside the fast monitor's 25 slots, but inside
+	// the slow one's.
+	twoHoursAgo := time.Now().Add(-2 * time.Hour).Unix()
+	if err := database.Create(&[]Attempt{
+		{MonitorConfigID: fast.ID, Healthy: true, Health: HealthStateUp, CreatedAt: twoHoursAgo},
+		{MonitorConfigID: slow.ID, Healthy: true, Health: HealthStateUp, CreatedAt: twoHoursAgo},
+	}).Error; err != nil {
+		t.Fatalf("failed to create attempts: %v", err)
+	}
+
+	monitors := []MonitorConfig{fast, slow}
+	if err := loadSlots(database, monitors, defaultSlotCount, 0); err != nil {
+		t.Fatalf("loadSlots failed: %v", err)
+	}
+
+	if filled := filledSlots(monitors[0].Slots); filled != 0 {
+		t.Errorf("fast monitor has %d filled slots, want 0", filled)
+	}
+
+	if filled := filledSlots(monitors[1].Slots); filled != 1 {
+		t.Errorf("slow monitor has %d filled slots, want 1", filled)
+	}
+
+	// A shared resolution overrides the intervals.
+	if err := loadSlots(database, monitors, defaultSlotCount, 3600); err != nil {
+		t.Fatalf("loadSlots failed: %v", err)
+	}
+
+	if filled := filledSlots(monitors[0].Slots); filled != 1 {
+		t.Errorf("fast monitor has %d filled slots at an hourly resolution, want 1", filled)
+	}
+}
+
+func filledSlots(slots []Slot) int {
+	filled := 0
+	for _, slot := range slots {
+		if slot.IsMonitoringEnabled {
+			filled++
+		}
+	}
+
+	return filled
+}
+
+// BenchmarkLoadSlots loads the status bars of a 1,000 monitor page with 30
+// attempts each, as the dashboard's monitor list does.
+func BenchmarkLoadSlots(b *testing.B) {
+	const (
+		monitorCount       = 1000
+		attemptsPerMonitor = 30
+		interval           = 60
+	)
+
+	database := newTestDatabase(b)
+
+	monitors := make([]MonitorConfig, monitorCount)
+	for i := range monitors {
+		monitors[i] = MonitorConfig{Name: "monitor", Interval: interval, Threshold: 1}
+	}
+	if err := database.CreateInBatches(&monitors, 500).Error; err != nil {
+		b.Fatalf("failed to create monitors: %v", err)
+	}
+
+	now := time.Now().Unix()
+	attempts := make([]Attempt, 0, monitorCount*attemptsPerMonitor)
+	for _, monitorConfig := range monitors {
+		for i := range attemptsPerMonitor {
+			attempts = append(attempts, Attempt{
+				MonitorConfigID: monitorConfig.ID,
+				Healthy:         true,
+				Health:          HealthStateUp,
+				CreatedAt:       now - int64(i*interval),
+			})
+		}
+	}
+	if err := database.CreateInBatches(&attempts, 500).Error; err != nil {
+		b.Fatalf("failed to create attempts: %v", err)
+	}
+
+	b.ResetTimer()
+
+	for b.Loop() {
+		if err := loadSlots(database, monitors, defaultSlotCount, 0); err != nil {
+			b.Fatalf("loadSlots failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoEvent = errors.New("no server-sent event received")
+)
+
+// executeSSECheck subscribes to the stream and waits for the first complete
+// event, or for the first one whose data contains the expected message.
+func executeSSECheck(monitorConfig MonitorConfig) (ExecutionResponse, error) {
+	timeout := time.Duration(monitorConfig.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, monitorConfig.URL, nil)
+	if err != nil {
+		return ExecutionResponse{}, fmt.Errorf("failed to create http request: %v", err)
+	}
+
+	for key, value := range monitorConfig.Headers.Data() {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	client, closeClient, err := newHttpClient(monitorConfig)
+	if err != nil {
+		return ExecutionResponse{}, err
+	}
+	defer closeClient()
+
+	startedAt := time.Now()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ExecutionResponse{ResponseTime: time.Since(startedAt)}, ErrDeadlineExceeded
+		}
+
+		return ExecutionResponse{ResponseTime: time.Since(startedAt)}, fmt.Errorf("failed to execute http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	executionResponse := ExecutionResponse{
+		StatusCode: resp.StatusCode,
+		FinalURL:   resp.Request.URL.String(),
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		executionResponse.ResponseTime = time.Since(startedAt)
+		return executionResponse, fmt.Errorf("%w %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		executionResponse.ResponseTime = time.Since(startedAt)
+		return executionResponse, fmt.Errorf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+
+	var data []string
+	hasEvent := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line dispatches the event collected so far.
+		if line == "" {
+			if hasEvent {
+				message := strings.Join(data, "\n")
+				executionResponse.ResponseBody = message
+
+				if strings.Contains(message, monitorConfig.ExpectMessage) {
+					executionResponse.ResponseTime = time.Since(startedAt)
+					return executionResponse, nil
+				}
+			}
+
+			data = nil
+			hasEvent = false
+			continue
+		}
+
+		// Lines starting with a colon are comments, used as keep-alives.
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "data":
+			data = append(data, value)
+			hasEvent = true
+		case "event", "id":
+			hasEvent = true
+		}
+	}
+
+	executionResponse.ResponseTime = time.Since(startedAt)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		if monitorConfig.ExpectMessage != "" {
+			return executionResponse, fmt.Errorf("%w: no event containing %q within the timeout", ErrUnexpectedMessage, monitorConfig.ExpectMessage)
+		}
+		return executionResponse, fmt.Errorf("%w within the timeout", ErrNoEvent)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return executionResponse, fmt.Errorf("failed to read event stream: %w", err)
+	}
+
+	return executionResponse, fmt.Errorf("%w before the stream was closed", ErrNoEvent)
+}
@@ -0,0 +1,791 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"github.com/mateusgcoelho/sentinel/engine/internal/slug"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImportMode decides what happens to monitors and integrations missing from
+// the manifest: MERGE leaves them alone, SYNC deletes them.
+type ImportMode string
+
+const (
+	ImportModeMerge ImportMode = "MERGE"
+	ImportModeSync  ImportMode = "SYNC"
+)
+
+type ChangeAction string
+
+const (
+	ChangeActionCreate ChangeAction = "CREATE"
+	ChangeActionUpdate ChangeAction = "UPDATE"
+	ChangeActionDelete ChangeAction = "DELETE"
+)
+
+type ChangeKind string
+
+const (
+	ChangeKindIntegration ChangeKind = "INTEGRATION"
+	ChangeKindMonitor     ChangeKind = "MONITOR"
+)
+
+var errDryRun = errors.New("dry run")
+
+type ManifestChange struct {
+	Kind   ChangeKind   `json:"kind"`
+	Slug   string       `json:"slug"`
+	Action ChangeAction `json:"action"`
+	Fields []string     `json:"fields,omitempty"`
+}
+
+type ImportQuery struct {
+	Mode   ImportMode `form:"mode" binding:"omitempty,oneof=MERGE SYNC"`
+	DryRun bool       `form:"dry_run"`
+}
+
+func (q ImportQuery) mode() ImportMode {
+	if q.Mode == "" {
+		return ImportModeMerge
+	}
+
+	return q.Mode
+}
+
+type manifestPlan struct {
+	changes            []ManifestChange
+	integrations       []IntegrationManifest
+	monitors           []MonitorManifest
+	deleteIntegrations []string
+	deleteMonitors     []string
+}
+
+// applyManifest diffs the manifest against the current configuration and
+// applies the changes in a single transaction. A dry run goes through the
+// same steps and rolls back, so checks that need the final state, such as
+// dependency cycles, are reported exactly as a real run would report them.
+func applyManifest(database *gorm.DB, manifest Manifest, mode ImportMode, dryRun bool, author history.Author) ([]ManifestChange, error) {
+	var changes []ManifestChange
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if changes, err = applyManifestTx(tx, manifest, mode, &author); err != nil {
+			return err
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+
+	return changes, err
+}
+
+// applyManifestTx applies the manifest inside tx and records a version for
+// every changed monitor and integration. A nil author skips the recording,
+// for callers that record the change themselves.
+func applyManifestTx(tx *gorm.DB, manifest Manifest, mode ImportMode, author *history.Author) ([]ManifestChange, error) {
+	state, err := loadManifestState(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest = restoreRedactedValues(state, manifest)
+
+	if err := validateManifest(tx, state, manifest, mode); err != nil {
+		return nil, err
+	}
+
+	plan, err := planManifest(state, manifest, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if author == nil {
+		if err := executePlan(tx, state, plan); err != nil {
+			return nil, err
+		}
+
+		return append([]ManifestChange{}, plan.changes...), nil
+	}
+
+	var monitorIDs, integrationIDs []uint
+	for _, entry := range plan.monitors {
+		if current, exists := state.monitors[entry.Slug]; exists {
+			monitorIDs = append(monitorIDs, current.ID)
+		}
+	}
+	for _, monitorSlug := range plan.deleteMonitors {
+		monitorIDs = append(monitorIDs, state.monitors[monitorSlug].ID)
+	}
+	for _, entry := range plan.integrations {
+		if current, exists := state.integrations[entry.Slug]; exists {
+			integrationIDs = append(integrationIDs, current.ID)
+		}
+	}
+	for _, integrationSlug := range plan.deleteIntegrations {
+		integrationIDs = append(integrationIDs, state.integrations[integrationSlug].ID)
+	}
+
+	beforeMonitors, err := snapshotMonitors(tx, monitorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeIntegrations, err := integration.Snapshots(tx, integrationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := executePlan(tx, state, plan); err != nil {
+		return nil, err
+	}
+
+	// Created entries only have an ID once the plan ran.
+	if err := appendCreatedIDs(tx, &MonitorConfig{}, plan.monitorSlugs(), &monitorIDs); err != nil {
+		return nil, err
+	}
+	if err := appendCreatedIDs(tx, &integration.IntegrationConfig{}, plan.integrationSlugs(), &integrationIDs); err != nil {
+		return nil, err
+	}
+
+	if err := integration.RecordVersions(tx, integrationIDs, beforeIntegrations, *author); err != nil {
+		return nil, err
+	}
+
+	if err := recordMonitorVersions(tx, monitorIDs, beforeMonitors, *author); err != nil {
+		return nil, err
+	}
+
+	return append([]ManifestChange{}, plan.changes...), nil
+}
+
+func (p manifestPlan) monitorSlugs() []string {
+	slugs := make([]string, 0, len(p.monitors))
+	for _, entry := range p.monitors {
+		slugs = append(slugs, entry.Slug)
+	}
+
+	return slugs
+}
+
+func (p manifestPlan) integrationSlugs() []string {
+	slugs := make([]string, 0, len(p.integrations))
+	for _, entry := range p.integrations {
+		slugs = append(slugs, entry.Slug)
+	}
+
+	return slugs
+}
+
+// appendCreatedIDs adds to ids the IDs of the given slugs that are not in it
+// yet.
+func appendCreatedIDs(tx *gorm.DB, model any, slugs []string, ids *[]uint) error {
+	if len(slugs) == 0 {
+		return nil
+	}
+
+	var found []uint
+	if err := tx.Model(model).Where("slug IN ?", slugs).Pluck("id", &found).Error; err != nil {
+		return err
+	}
+
+	for _, id := range found {
+		if !slices.Contains(*ids, id) {
+			*ids = append(*ids, id)
+		}
+	}
+
+	return nil
+}
+
+// restoreRedactedValues puts the current values back into the fields an
+// export redacted, so that an exported manifest applies without changes.
+func restoreRedactedValues(state manifestState, manifest Manifest) Manifest {
+	monitors := make([]MonitorManifest, 0, len(manifest.Monitors))
+	for _, entry := range manifest.Monitors {
+		monitors = append(monitors, entry.unredacted(state.entries[entry.Slug]))
+	}
+	manifest.Monitors = monitors
+
+	return manifest
+}
+
+// validateManifest checks every entry and reports all problems at once.
+func validateManifest(database *gorm.DB, state manifestState, manifest Manifest, mode ImportMode) error {
+	var problems []string
+	var secretNames []string
+
+	report := func(kind ChangeKind, entrySlug string, format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("%s %q: %s", strings.ToLower(string(kind)), entrySlug, fmt.Sprintf(format, args...)))
+	}
+
+	integrationSlugs := map[string]bool{}
+	for _, entry := range manifest.Integrations {
+		if !slug.Valid(entry.Slug) {
+			report(ChangeKindIntegration, entry.Slug, "%v", ErrInvalidSlug)
+		}
+		if integrationSlugs[entry.Slug] {
+			report(ChangeKindIntegration, entry.Slug, "duplicate slug")
+		}
+		integrationSlugs[entry.Slug] = true
+
+		if entry.Name == "" {
+			report(ChangeKindIntegration, entry.Slug, "name is required")
+		}
+
+		if entry.Type != integration.IntegrationTypeSlack && entry.Type != integration.IntegrationTypeDiscord {
+			report(ChangeKindIntegration, entry.Slug, "type must be SLACK or DISCORD")
+		}
+
+		if entry.URL != "" {
+			if !secret.IsReference(entry.URL) {
+				report(ChangeKindIntegration, entry.Slug, "url must be a secret reference such as %s", secret.Reference("NAME"))
+			}
+			secretNames = append(secretNames, secret.References(entry.URL)...)
+		} else if _, exists := state.integrations[entry.Slug]; !exists {
+			report(ChangeKindIntegration, entry.Slug, "url is required for new integrations")
+		}
+	}
+
+	monitorSlugs := map[string]bool{}
+	for _, entry := range manifest.Monitors {
+		if monitorSlugs[entry.Slug] {
+			report(ChangeKindMonitor, entry.Slug, "duplicate slug")
+		}
+		monitorSlugs[entry.Slug] = true
+	}
+
+	// Merged manifests may point at entries that only exist in the database.
+	if mode == ImportModeMerge {
+		for existing := range state.integrations {
+			integrationSlugs[existing] = true
+		}
+		for existing := range state.monitors {
+			monitorSlugs[existing] = true
+		}
+	}
+
+	for _, entry := range manifest.Monitors {
+		entryProblems := monitorEntryProblems(entry)
+		for _, problem := range entryProblems {
+			report(ChangeKindMonitor, entry.Slug, "%s", problem)
+		}
+		if len(entryProblems) > 0 {
+			continue
+		}
+
+		monitorConfig := entry.monitorConfig()
+
+		if current, exists := state.monitors[entry.Slug]; exists && current.Type != monitorConfig.Type {
+			report(ChangeKindMonitor, entry.Slug, "type cannot change from %s to %s, delete the monitor first", current.Type, monitorConfig.Type)
+		}
+
+		for _, integrationSlug := range entry.Integrations {
+			if !integrationSlugs[integrationSlug] {
+				report(ChangeKindMonitor, entry.Slug, "unknown integration %q", integrationSlug)
+			}
+		}
+
+		for _, parentSlug := range entry.Parents {
+			if !monitorSlugs[parentSlug] {
+				report(ChangeKindMonitor, entry.Slug, "unknown parent %q", parentSlug)
+			}
+		}
+
+		for _, member := range entry.Members {
+			if !monitorSlugs[member.Monitor] {
+				report(ChangeKindMonitor, entry.Slug, "unknown member %q", member.Monitor)
+			}
+		}
+
+		secretNames = append(secretNames, secretReferences(monitorConfig)...)
+	}
+
+	missing, err := secret.Missing(database, secretNames)
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("secrets not found: %s", strings.Join(missing, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidManifest, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// monitorEntryProblems runs the checks that only need the entry itself, the
+// same ones the create endpoint runs.
+func monitorEntryProblems(entry MonitorManifest) []string {
+	req := entry.request()
+
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return []string{err.Error()}
+	}
+
+	if entry.Slug == "" {
+		return []string{ErrInvalidSlug.Error()}
+	}
+
+	if err := req.Validate(); err != nil {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+
+	if err := validateHttpClientOptions(entry.monitorConfig()); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if entry.TLSClientKey != "" && !secret.IsReference(entry.TLSClientKey) {
+		problems = append(problems, fmt.Sprintf("tls_client_key must be a secret reference such as %s", secret.Reference("NAME")))
+	}
+
+	// Redacted values only stand for values the monitor already has.
+	for _, field := range entry.redactedFields() {
+		problems = append(problems, fmt.Sprintf("%s is redacted and has no current value", field))
+	}
+
+	if len(entry.Integrations) == 0 {
+		problems = append(problems, "at least one integration is required")
+	}
+
+	return problems
+}
+
+// planManifest lists the entries to create, update and, in sync mode,
+// delete. Entries that already match are left out.
+func planManifest(state manifestState, manifest Manifest, mode ImportMode) (manifestPlan, error) {
+	var plan manifestPlan
+
+	for _, entry := range manifest.Integrations {
+		current, exists := state.integrations[entry.Slug]
+		if !exists {
+			plan.integrations = append(plan.integrations, entry)
+			plan.changes = append(plan.changes, ManifestChange{Kind: ChangeKindIntegration, Slug: entry.Slug, Action: ChangeActionCreate})
+			continue
+		}
+
+		currentEntry := exportIntegration(current)
+		if entry.URL == "" {
+			entry.URL = currentEntry.URL
+		}
+
+		fields, err := changedFields(currentEntry, entry)
+		if err != nil {
+			return plan, err
+		}
+
+		if len(fields) > 0 {
+			plan.integrations = append(plan.integrations, entry)
+			plan.changes = append(plan.changes, ManifestChange{Kind: ChangeKindIntegration, Slug: entry.Slug, Action: ChangeActionUpdate, Fields: fields})
+		}
+	}
+
+	for _, entry := range manifest.Monitors {
+		currentEntry, exists := state.entries[entry.Slug]
+		if !exists {
+			plan.monitors = append(plan.monitors, entry)
+			plan.changes = append(plan.changes, ManifestChange{Kind: ChangeKindMonitor, Slug: entry.Slug, Action: ChangeActionCreate})
+			continue
+		}
+
+		desired := entry.canonical()
+		if desired.TLSClientKey == "" {
+			desired.TLSClientKey = currentEntry.TLSClientKey
+		}
+
+		fields, err := changedFields(currentEntry, desired)
+		if err != nil {
+			return plan, err
+		}
+
+		if len(fields) > 0 {
+			plan.monitors = append(plan.monitors, entry)
+			plan.changes = append(plan.changes, ManifestChange{Kind: ChangeKindMonitor, Slug: entry.Slug, Action: ChangeActionUpdate, Fields: fields})
+		}
+	}
+
+	if mode != ImportModeSync {
+		return plan, nil
+	}
+
+	declaredIntegrations := map[string]bool{}
+	for _, entry := range manifest.Integrations {
+		declaredIntegrations[entry.Slug] = true
+	}
+
+	for _, entry := range state.manifest.Integrations {
+		if !declaredIntegrations[entry.Slug] {
+			plan.deleteIntegrations = append(plan.deleteIntegrations, entry.Slug)
+			plan.changes = append(plan.changes, ManifestChange{Kind: ChangeKindIntegration, Slug: entry.Slug, Action: ChangeActionDelete})
+		}
+	}
+
+	declaredMonitors := map[string]bool{}
+	for _, entry := range manifest.Monitors {
+		declaredMonitors[entry.Slug] = true
+	}
+
+	for _, entry := range state.manifest.Monitors {
+		if !declaredMonitors[entry.Slug] {
+			plan.deleteMonitors = append(plan.deleteMonitors, entry.Slug)
+			plan.changes = append(plan.changes, ManifestChange{Kind: ChangeKindMonitor, Slug: entry.Slug, Action: ChangeActionDelete})
+		}
+	}
+
+	return plan, nil
+}
+
+func executePlan(tx *gorm.DB, state manifestState, plan manifestPlan) error {
+	if len(plan.deleteMonitors) > 0 {
+		monitorIDs := make([]uint, 0, len(plan.deleteMonitors))
+		for _, monitorSlug := range plan.deleteMonitors {
+			monitorIDs = append(monitorIDs, state.monitors[monitorSlug].ID)
+		}
+
+		if err := deleteMonitors(tx, monitorIDs); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range plan.integrations {
+		if err := saveIntegration(tx, state, entry); err != nil {
+			return err
+		}
+	}
+
+	integrationIDs, err := slugIDs(tx, &integration.IntegrationConfig{})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range plan.monitors {
+		if err := saveMonitor(tx, state, entry); err != nil {
+			return err
+		}
+	}
+
+	monitorIDs, err := slugIDs(tx, &MonitorConfig{})
+	if err != nil {
+		return err
+	}
+
+	// Links are cleared for every changed monitor before any is added back,
+	// so cycle checks do not see edges that the manifest removes.
+	for _, entry := range plan.monitors {
+		if err := clearMonitorLinks(tx, monitorIDs[entry.Slug]); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range plan.monitors {
+		if err := linkMonitor(tx, entry, monitorIDs, integrationIDs); err != nil {
+			return err
+		}
+	}
+
+	if len(plan.deleteIntegrations) > 0 {
+		if err := deleteIntegrations(tx, plan.deleteIntegrations, integrationIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func saveIntegration(tx *gorm.DB, state manifestState, entry IntegrationManifest) error {
+	current, exists := state.integrations[entry.Slug]
+	if !exists {
+		entrySlug := entry.Slug
+		return tx.Create(&integration.IntegrationConfig{
+			Slug: &entrySlug,
+			Name: entry.Name,
+			Type: entry.Type,
+			URL:  entry.URL,
+		}).Error
+	}
+
+	updates := map[string]any{
+		"name": entry.Name,
+		"type": entry.Type,
+	}
+	if entry.URL != "" {
+		updates["url"] = entry.URL
+	}
+
+	return tx.Model(&current).Updates(updates).Error
+}
+
+func saveMonitor(tx *gorm.DB, state manifestState, entry MonitorManifest) error {
+	desired := entry.monitorConfig()
+
+	current, exists := state.monitors[entry.Slug]
+	if !exists {
+		if desired.Type == MonitorTypeHeartbeat {
+			token, err := generateHeartbeatToken()
+			if err != nil {
+				return err
+			}
+
+			desired.HeartbeatToken = &token
+			desired.LastRun = clock.System.Now().Unix()
+		}
+
+		enabled := desired.Enabled
+		if err := tx.Omit(clause.Associations).Create(&desired).Error; err != nil {
+			return err
+		}
+
+		// Enabled defaults to true, so GORM skips a false value on create.
+		if !enabled {
+			return tx.Model(&desired).UpdateColumn("enabled", false).Error
+		}
+
+		return nil
+	}
+
+	assignManifestFields(&current, desired)
+
+	return tx.Omit(clause.Associations).Save(&current).Error
+}
+
+// assignManifestFields copies the fields managed by manifests onto an
+// existing monitor, leaving runtime state and the escalation policy alone.
+func assignManifestFields(monitorConfig *MonitorConfig, desired MonitorConfig) {
+	monitorConfig.Name = desired.Name
+	monitorConfig.Folder = desired.Folder
+	monitorConfig.URL = desired.URL
+	monitorConfig.Method = desired.Method
+	monitorConfig.Interval = desired.Interval
+	monitorConfig.Threshold = desired.Threshold
+	monitorConfig.Timeout = desired.Timeout
+	monitorConfig.GracePeriod = desired.GracePeriod
+	monitorConfig.Headers = desired.Headers
+	monitorConfig.GrpcService = desired.GrpcService
+	monitorConfig.Subprotocol = desired.Subprotocol
+	monitorConfig.SendMessage = desired.SendMessage
+	monitorConfig.ExpectMessage = desired.ExpectMessage
+	monitorConfig.HttpVersion = desired.HttpVersion
+	monitorConfig.IPVersion = desired.IPVersion
+	monitorConfig.DisableRedirects = desired.DisableRedirects
+	monitorConfig.MaxRedirects = desired.MaxRedirects
+	monitorConfig.TLSSkipVerify = desired.TLSSkipVerify
+	monitorConfig.TLSCACertificate = desired.TLSCACertificate
+	monitorConfig.TLSClientCertificate = desired.TLSClientCertificate
+	if desired.TLSClientKey != "" {
+		monitorConfig.TLSClientKey = desired.TLSClientKey
+	}
+	monitorConfig.ProxyURL = desired.ProxyURL
+	monitorConfig.DNSServer = desired.DNSServer
+	monitorConfig.ResponseBodyLimit = desired.ResponseBodyLimit
+	monitorConfig.DegradedResponseTime = desired.DegradedResponseTime
+	monitorConfig.FlapWindow = desired.FlapWindow
+	monitorConfig.FlapThreshold = desired.FlapThreshold
+	monitorConfig.Steps = desired.Steps
+	monitorConfig.Command = desired.Command
+	monitorConfig.Arguments = desired.Arguments
+	monitorConfig.Environment = desired.Environment
+
+	if monitorConfig.Type == MonitorTypeGroup {
+		monitorConfig.AggregationRule = desired.AggregationRule
+		monitorConfig.AggregationMinimum = desired.AggregationMinimum
+	}
+
+	if monitorConfig.Enabled != desired.Enabled {
+		monitorConfig.Enabled = desired.Enabled
+
+		if !desired.Enabled {
+			monitorConfig.Running = false
+			monitorConfig.RunningSince = 0
+			monitorConfig.Healthy = false
+			monitorConfig.Health = HealthStateDown
+			monitorConfig.Flapping = false
+		} else if monitorConfig.Type == MonitorTypeHeartbeat {
+			monitorConfig.LastRun = clock.System.Now().Unix()
+		}
+	}
+}
+
+func clearMonitorLinks(tx *gorm.DB, monitorID uint) error {
+	if err := tx.Exec("DELETE FROM monitor_config_integrations WHERE monitor_config_id = ?", monitorID).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("monitor_config_id = ?", monitorID).Delete(&MonitorDependency{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("group_id = ?", monitorID).Delete(&GroupMember{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("monitor_config_id = ?", monitorID).Delete(&MonitorTag{}).Error
+}
+
+func linkMonitor(tx *gorm.DB, entry MonitorManifest, monitorIDs map[string]uint, integrationIDs map[string]uint) error {
+	monitorConfig := MonitorConfig{ID: monitorIDs[entry.Slug]}
+
+	for _, integrationSlug := range uniqueStrings(entry.Integrations) {
+		if err := tx.Exec(
+			"INSERT INTO monitor_config_integrations (monitor_config_id, integration_config_id) VALUES (?, ?)",
+			monitorConfig.ID, integrationIDs[integrationSlug],
+		).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(entry.Parents) > 0 {
+		parentIDs := make([]uint, 0, len(entry.Parents))
+		for _, parentSlug := range uniqueStrings(entry.Parents) {
+			parentIDs = append(parentIDs, monitorIDs[parentSlug])
+		}
+
+		if err := validateDependencies(tx, monitorConfig.ID, parentIDs); err != nil {
+			return manifestLinkError(entry.Slug, err)
+		}
+
+		for _, parentID := range parentIDs {
+			if err := tx.Create(&MonitorDependency{MonitorConfigID: monitorConfig.ID, ParentID: parentID}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if entry.request().MonitorType() == MonitorTypeGroup && len(entry.Members) > 0 {
+		reqMembers := make([]GroupMemberRequest, 0, len(entry.Members))
+		for _, member := range entry.Members {
+			reqMembers = append(reqMembers, GroupMemberRequest{MonitorID: monitorIDs[member.Monitor], Weight: member.Weight})
+		}
+
+		members, err := buildGroupMembers(tx, monitorConfig.ID, reqMembers)
+		if err != nil {
+			return manifestLinkError(entry.Slug, err)
+		}
+
+		if err := tx.Create(&members).Error; err != nil {
+			return err
+		}
+	}
+
+	tags := buildTags(entry.Tags)
+	for i := range tags {
+		tags[i].MonitorConfigID = monitorConfig.ID
+	}
+
+	if len(tags) > 0 {
+		return tx.Create(&tags).Error
+	}
+
+	return nil
+}
+
+// deleteIntegrations refuses to remove integrations that escalation policies
+// still notify, since policies are not part of the manifest.
+func deleteIntegrations(tx *gorm.DB, integrationSlugs []string, integrationIDs map[string]uint) error {
+	ids := make([]uint, 0, len(integrationSlugs))
+	for _, integrationSlug := range integrationSlugs {
+		ids = append(ids, integrationIDs[integrationSlug])
+	}
+
+	var used []uint
+	if err := tx.Table("escalation_step_integrations").
+		Where("integration_config_id IN ?", ids).
+		Distinct().
+		Pluck("integration_config_id", &used).Error; err != nil {
+		return err
+	}
+
+	if len(used) > 0 {
+		var names []string
+		for _, integrationSlug := range integrationSlugs {
+			if slices.Contains(used, integrationIDs[integrationSlug]) {
+				names = append(names, integrationSlug)
+			}
+		}
+
+		return fmt.Errorf("%w: integrations used by escalation policies cannot be deleted: %s", ErrInvalidManifest, strings.Join(names, ", "))
+	}
+
+	if err := tx.Exec("DELETE FROM monitor_config_integrations WHERE integration_config_id IN ?", ids).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("id IN ?", ids).Delete(&integration.IntegrationConfig{}).Error
+}
+
+func manifestLinkError(entrySlug string, err error) error {
+	if errors.Is(err, ErrParentNotFound) || errors.Is(err, ErrDependencyCycle) ||
+		errors.Is(err, ErrMemberNotFound) || errors.Is(err, ErrMembershipCycle) || errors.Is(err, ErrInvalidAggregate) {
+		return fmt.Errorf("%w: monitor %q: %v", ErrInvalidManifest, entrySlug, err)
+	}
+
+	return err
+}
+
+// slugIDs maps the slug of every row of the model's table to its ID.
+func slugIDs(tx *gorm.DB, model any) (map[string]uint, error) {
+	var rows []struct {
+		ID   uint
+		Slug string
+	}
+	if err := tx.Model(model).Where("slug IS NOT NULL").Select("id, slug").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]uint, len(rows))
+	for _, row := range rows {
+		ids[row.Slug] = row.ID
+	}
+
+	return ids, nil
+}
+
+// changedFields compares two entries through their JSON form and returns the
+// names of the fields that differ.
+func changedFields(current any, desired any) ([]string, error) {
+	changes, err := history.Diff(current, desired)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+
+	return fields, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+
+	return unique
+}
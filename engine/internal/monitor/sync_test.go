@@ -0,0 +1,270 @@
+package monitor
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/history"
+	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/secret"
+	"gorm.io/gorm"
+)
+
+// testManifest declares the ops integration and an HTTP monitor for each
+// slug, every monitor depending on the parents given for it.
+func testManifest(slugs []string, parents map[string][]string) Manifest {
+	manifest := Manifest{
+		Version:      manifestVersion,
+		Integrations: []IntegrationManifest{{Slug: "ops", Name: "Ops", Type: integration.IntegrationTypeSlack, URL: secret.Reference("slack-ops")}},
+	}
+
+	for _, monitorSlug := range slugs {
+		manifest.Monitors = append(manifest.Monitors, MonitorManifest{
+			Slug:         monitorSlug,
+			Name:         monitorSlug,
+			URL:          "https://" + monitorSlug + ".example.com",
+			Method:       "GET",
+			Timeout:      5,
+			Interval:     60,
+			Threshold:    1,
+			Integrations: []string{"ops"},
+			Parents:      parents[monitorSlug],
+		})
+	}
+
+	return manifest
+}
+
+// newManifestDatabase returns a database where the manifest was applied.
+func newManifestDatabase(t *testing.T, manifest Manifest) *gorm.DB {
+	t.Helper()
+	database := newTestDatabase(t)
+
+	if err := database.Create(&secret.Secret{Name: "slack-ops", Value: "https://hooks.slack.com/services/ops"}).Error; err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	if _, err := applyManifest(database, manifest, ImportModeMerge, false, history.Author{}); err != nil {
+		t.Fatalf("failed to apply manifest: %v", err)
+	}
+
+	return database
+}
+
+func monitorSlugs(t *testing.T, database *gorm.DB) []string {
+	t.Helper()
+
+	var slugs []string
+	if err := database.Model(&MonitorConfig{}).Order("slug ASC").Pluck("slug", &slugs).Error; err != nil {
+		t.Fatalf("failed to list monitors: %v", err)
+	}
+
+	return slugs
+}
+
+func changeSummary(changes []ManifestChange) []string {
+	summary := make([]string, 0, len(changes))
+	for _, change := range changes {
+		summary = append(summary, string(change.Action)+" "+strings.ToLower(string(change.Kind))+" "+change.Slug)
+	}
+
+	return summary
+}
+
+func TestPlanManifestMergeKeepsAndSyncDeletes(t *testing.T) {
+	database := newManifestDatabase(t, testManifest([]string{"api", "web"}, nil))
+
+	state, err := loadManifestState(database)
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+
+	manifest := testManifest([]string{"api", "worker"}, nil)
+	manifest.Monitors[0].Interval = 30
+
+	tests := []struct {
+		mode ImportMode
+		want []string
+	}{
+		{mode: ImportModeMerge, want: []string{"UPDATE monitor api", "CREATE monitor worker"}},
+		{mode: ImportModeSync, want: []string{"UPDATE monitor api", "CREATE monitor worker", "DELETE monitor web"}},
+	}
+
+	for _, tt := range tests {
+		plan, err := planManifest(state, manifest, tt.mode)
+		if err != nil {
+			t.Fatalf("%s: failed to plan: %v", tt.mode, err)
+		}
+
+		if got := changeSummary(plan.changes); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: changes = %q, want %q", tt.mode, got, tt.want)
+		}
+
+		if fields := plan.changes[0].Fields; !slices.Equal(fields, []string{"interval"}) {
+			t.Errorf("%s: api fields = %q, want only the interval", tt.mode, fields)
+		}
+
+		if tt.mode == ImportModeSync && !slices.Equal(plan.deleteMonitors, []string{"web"}) {
+			t.Errorf("%s: deleted monitors = %q, want web", tt.mode, plan.deleteMonitors)
+		}
+	}
+}
+
+func TestApplyManifestSyncDeletesMissingEntries(t *testing.T) {
+	database := newManifestDatabase(t, testManifest([]string{"api", "web"}, nil))
+
+	manifest := testManifest([]string{"api"}, nil)
+
+	if _, err := applyManifest(database, manifest, ImportModeMerge, false, history.Author{}); err != nil {
+		t.Fatalf("failed to merge: %v", err)
+	}
+
+	if got := monitorSlugs(t, database); !slices.Equal(got, []string{"api", "web"}) {
+		t.Errorf("monitors after merge = %q, want both kept", got)
+	}
+
+	if _, err := applyManifest(database, manifest, ImportModeSync, false, history.Author{}); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	if got := monitorSlugs(t, database); !slices.Equal(got, []string{"api"}) {
+		t.Errorf("monitors after sync = %q, want only api", got)
+	}
+}
+
+func TestApplyManifestDryRunRollsBack(t *testing.T) {
+	database := newManifestDatabase(t, testManifest([]string{"api", "web"}, nil))
+
+	changes, err := applyManifest(database, testManifest([]string{"worker"}, nil), ImportModeSync, true, history.Author{})
+	if err != nil {
+		t.Fatalf("failed to apply: %v", err)
+	}
+
+	want := []string{"CREATE monitor worker", "DELETE monitor api", "DELETE monitor web"}
+	if got := changeSummary(changes); !slices.Equal(got, want) {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+
+	if got := monitorSlugs(t, database); !slices.Equal(got, []string{"api", "web"}) {
+		t.Errorf("monitors = %q, want the dry run rolled back", got)
+	}
+
+	var versions int64
+	if err := database.Model(&history.Version{}).Where("entity_type = ?", history.EntityTypeMonitor).Count(&versions).Error; err != nil {
+		t.Fatalf("failed to count versions: %v", err)
+	}
+
+	if versions != 2 {
+		t.Errorf("versions = %d, want only the two from the setup", versions)
+	}
+}
+
+func TestApplyManifestReportsDependencyCycles(t *testing.T) {
+	database := newManifestDatabase(t, testManifest([]string{"api", "db"}, map[string][]string{"api": {"db"}}))
+
+	// The cycle only exists once both entries are applied.
+	manifest := testManifest([]string{"api", "db"}, map[string][]string{"api": {"db"}, "db": {"api"}})
+
+	for _, dryRun := range []bool{true, false} {
+		_, err := applyManifest(database, manifest, ImportModeMerge, dryRun, history.Author{})
+		if !errors.Is(err, ErrInvalidManifest) || !strings.Contains(err.Error(), ErrDependencyCycle.Error()) {
+			t.Errorf("dry run %t: err = %v, want a dependency cycle", dryRun, err)
+		}
+	}
+
+	// Replacing the edge in the same manifest is not a cycle.
+	manifest = testManifest([]string{"api", "db"}, map[string][]string{"db": {"api"}})
+
+	if _, err := applyManifest(database, manifest, ImportModeMerge, false, history.Author{}); err != nil {
+		t.Errorf("reversing the dependency: %v", err)
+	}
+}
+
+func TestExportRedactsValuesAndImportKeepsThem(t *testing.T) {
+	manifest := testManifest([]string{"api"}, nil)
+	manifest.Monitors[0].Headers = map[string]string{
+		"Authorization": "Bearer plain-token",
+		"X-Api-Key":     secret.Reference("slack-ops"),
+	}
+
+	database := newManifestDatabase(t, manifest)
+
+	state, err := loadManifestState(database)
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+
+	exported := state.manifest.redacted()
+
+	headers := exported.Monitors[0].Headers
+	if headers["Authorization"] != redactedValue || headers["X-Api-Key"] != secret.Reference("slack-ops") {
+		t.Errorf("exported headers = %v, want the plain value redacted", headers)
+	}
+
+	if state.manifest.Monitors[0].Headers["Authorization"] != "Bearer plain-token" {
+		t.Errorf("redacting changed the state")
+	}
+
+	changes, err := applyManifest(database, exported, ImportModeSync, false, history.Author{})
+	if err != nil {
+		t.Fatalf("failed to apply the export: %v", err)
+	}
+
+	if len(changes) != 0 {
+		t.Errorf("changes = %+v, want none", changes)
+	}
+
+	var stored MonitorConfig
+	if err := database.Where("slug = ?", "api").First(&stored).Error; err != nil {
+		t.Fatalf("failed to load monitor: %v", err)
+	}
+
+	if got := stored.Headers.Data()["Authorization"]; got != "Bearer plain-token" {
+		t.Errorf("authorization = %q, want the current value kept", got)
+	}
+
+	// A redacted value the monitor does not have cannot be applied.
+	exported.Monitors[0].Slug = "api-copy"
+
+	if _, err := applyManifest(database, exported, ImportModeMerge, true, history.Author{}); !errors.Is(err, ErrInvalidManifest) ||
+		!strings.Contains(err.Error(), "headers.Authorization is redacted") {
+		t.Errorf("err = %v, want the redacted header reported", err)
+	}
+}
+
+func TestRedactedManifestEntryRoundTrips(t *testing.T) {
+	current := MonitorManifest{
+		Steps: []TransactionStep{
+			{Name: "login", Headers: map[string]string{"Cookie": "session=1"}, Body: `{"password": "hunter2"}`},
+			{Name: "me", Body: "token=" + secret.Reference("token")},
+		},
+		Environment: []string{"TOKEN=plain", "TARGET=", "API_KEY=" + secret.Reference("key")},
+	}
+
+	redacted := current.redacted()
+
+	wantEnvironment := []string{"TOKEN=" + redactedValue, "TARGET=", "API_KEY=" + secret.Reference("key")}
+	if !slices.Equal(redacted.Environment, wantEnvironment) {
+		t.Errorf("environment = %q, want %q", redacted.Environment, wantEnvironment)
+	}
+
+	if redacted.Steps[0].Headers["Cookie"] != redactedValue || redacted.Steps[0].Body != redactedValue || redacted.Steps[1].Body != current.Steps[1].Body {
+		t.Errorf("steps = %+v, want the plain values redacted", redacted.Steps)
+	}
+
+	if fields := redacted.redactedFields(); !slices.Equal(fields, []string{"steps[0].headers.Cookie", "steps[0].body", "environment.TOKEN"}) {
+		t.Errorf("redacted fields = %q", fields)
+	}
+
+	restored := redacted.unredacted(current)
+	if len(restored.redactedFields()) != 0 || !slices.Equal(restored.Environment, current.Environment) ||
+		restored.Steps[0].Body != current.Steps[0].Body || restored.Steps[0].Headers["Cookie"] != "session=1" {
+		t.Errorf("restored = %+v, want the current values", restored)
+	}
+
+	if fields := redacted.unredacted(MonitorManifest{}).redactedFields(); len(fields) != 3 {
+		t.Errorf("restored from nothing = %q, want every field still redacted", fields)
+	}
+}
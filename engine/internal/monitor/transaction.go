@@ -0,0 +1,329 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/jsonpath"
+)
+
+type ExtractionSource string
+
+const (
+	ExtractionSourceJSON   ExtractionSource = "JSON"
+	ExtractionSourceHeader ExtractionSource = "HEADER"
+)
+
+type AssertionType string
+
+const (
+	AssertionTypeStatusCode   AssertionType = "STATUS_CODE"
+	AssertionTypeBody         AssertionType = "BODY"
+	AssertionTypeJSON         AssertionType = "JSON"
+	AssertionTypeHeader       AssertionType = "HEADER"
+	AssertionTypeResponseTime AssertionType = "RESPONSE_TIME"
+)
+
+type AssertionOperator string
+
+const (
+	AssertionOperatorEquals      AssertionOperator = "EQUALS"
+	AssertionOperatorNotEquals   AssertionOperator = "NOT_EQUALS"
+	AssertionOperatorContains    AssertionOperator = "CONTAINS"
+	AssertionOperatorLessThan    AssertionOperator = "LESS_THAN"
+	AssertionOperatorGreaterThan AssertionOperator = "GREATER_THAN"
+	AssertionOperatorExists      AssertionOperator = "EXISTS"
+)
+
+var (
+	ErrAssertionFailed = errors.New("assertion failed")
+	ErrStepFailed      = errors.New("transaction step failed")
+)
+
+const maxTransactionBodySize = 1 << 20
+
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+type TransactionStep struct {
+	Name       string               `json:"name" binding:"required"`
+	Method     string               `json:"method" binding:"required,oneof=GET POST PUT PATCH DELETE HEAD"`
+	URL        string               `json:"url" binding:"required"`
+	Headers    map[string]string    `json:"headers"`
+	Body       string               `json:"body"`
+	Extract    []VariableExtraction `json:"extract" binding:"omitempty,dive"`
+	Assertions []Assertion          `json:"assertions" binding:"omitempty,dive"`
+}
+
+type VariableExtraction struct {
+	Variable string           `json:"variable" binding:"required"`
+	Source   ExtractionSource `json:"source" binding:"required,oneof=JSON HEADER"`
+	Path     string           `json:"path" binding:"required"`
+}
+
+// Assertion checks one property of a response. Path is the JSONPath for JSON
+// assertions and the header name for header assertions. Value is compared as
+// a number for status code and response time (milliseconds) assertions.
+type Assertion struct {
+	Type     AssertionType     `json:"type" binding:"required,oneof=STATUS_CODE BODY JSON HEADER RESPONSE_TIME"`
+	Path     string            `json:"path"`
+	Operator AssertionOperator `json:"operator" binding:"required,oneof=EQUALS NOT_EQUALS CONTAINS LESS_THAN GREATER_THAN EXISTS"`
+	Value    string            `json:"value"`
+}
+
+type StepResult struct {
+	Name         string `json:"name"`
+	StatusCode   int    `json:"status_code"`
+	ResponseTime int64  `json:"response_time"`
+	Success      bool   `json:"success"`
+	Error        string `json:"error,omitempty"`
+}
+
+func executeTransaction(monitorConfig MonitorConfig) (ExecutionResponse, error) {
+	timeout := time.Duration(monitorConfig.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return ExecutionResponse{}, fmt.Errorf("failed to create cookie jar: %v", err)
+	}
+
+	client, closeClient, err := newHttpClient(monitorConfig)
+	if err != nil {
+		return ExecutionResponse{}, err
+	}
+	defer closeClient()
+	client.Jar = jar
+
+	variables := map[string]string{}
+	results := make([]StepResult, 0, len(monitorConfig.Steps))
+	startedAt := time.Now()
+
+	var lastStatusCode int
+	var lastBody string
+
+	for i, step := range monitorConfig.Steps {
+		result, body, err := executeTransactionStep(ctx, client, step, variables)
+		results = append(results, result)
+		lastStatusCode = result.StatusCode
+		lastBody = body
+
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				err = ErrDeadlineExceeded
+			}
+
+			message := fmt.Sprintf("step %d (%s) failed: %v", i+1, step.Name, err)
+
+			return ExecutionResponse{
+				StatusCode:   result.StatusCode,
+				ResponseBody: message,
+				ResponseTime: time.Since(startedAt),
+				Steps:        results,
+			}, fmt.Errorf("%w: step %d (%s) failed: %w", ErrStepFailed, i+1, step.Name, err)
+		}
+	}
+
+	return ExecutionResponse{
+		StatusCode:   lastStatusCode,
+		ResponseBody: lastBody,
+		ResponseTime: time.Since(startedAt),
+		Steps:        results,
+	}, nil
+}
+
+func executeTransactionStep(ctx context.Context, client *http.Client, step TransactionStep, variables map[string]string) (StepResult, string, error) {
+	result := StepResult{Name: step.Name}
+
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(substituteVariables(step.Body, variables))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, step.Method, substituteVariables(step.URL, variables), body)
+	if err != nil {
+		result.Error = err.Error()
+		return result, "", fmt.Errorf("failed to create http request: %v", err)
+	}
+
+	for key, value := range step.Headers {
+		req.Header.Set(key, substituteVariables(value, variables))
+	}
+
+	startedAt := time.Now()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		result.ResponseTime = time.Since(startedAt).Milliseconds()
+		result.Error = err.Error()
+		return result, "", fmt.Errorf("failed to execute http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Assertions need the whole body, but a runaway download must not take
+	// the engine down with it.
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxTransactionBodySize))
+	result.ResponseTime = time.Since(startedAt).Milliseconds()
+	result.StatusCode = resp.StatusCode
+	if err != nil {
+		result.Error = err.Error()
+		return result, "", fmt.Errorf("failed to read response body: %v", err)
+	}
+	responseBody := string(bodyBytes)
+
+	var document any
+	hasDocument := json.Unmarshal(bodyBytes, &document) == nil
+
+	if err := checkAssertions(step.Assertions, resp, responseBody, document, hasDocument, result.ResponseTime); err != nil {
+		result.Error = err.Error()
+		return result, responseBody, err
+	}
+
+	for _, extraction := range step.Extract {
+		value, err := extractVariable(extraction, resp, document, hasDocument)
+		if err != nil {
+			result.Error = err.Error()
+			return result, responseBody, err
+		}
+		variables[extraction.Variable] = value
+	}
+
+	result.Success = true
+
+	return result, responseBody, nil
+}
+
+func substituteVariables(input string, variables map[string]string) string {
+	return variablePattern.ReplaceAllStringFunc(input, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		if value, ok := variables[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+func extractVariable(extraction VariableExtraction, resp *http.Response, document any, hasDocument bool) (string, error) {
+	switch extraction.Source {
+	case ExtractionSourceHeader:
+		value := resp.Header.Get(extraction.Path)
+		if value == "" {
+			return "", fmt.Errorf("failed to extract %s: header %s not found", extraction.Variable, extraction.Path)
+		}
+		return value, nil
+	default:
+		if !hasDocument {
+			return "", fmt.Errorf("failed to extract %s: response body is not json", extraction.Variable)
+		}
+		value, err := jsonpath.Lookup(document, extraction.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract %s: %v", extraction.Variable, err)
+		}
+		return jsonpath.Stringify(value), nil
+	}
+}
+
+// checkAssertions evaluates every assertion of a step. Steps without a status
+// code assertion must answer with a 2xx status, like a plain HTTP monitor.
+func checkAssertions(assertions []Assertion, resp *http.Response, body string, document any, hasDocument bool, responseTime int64) error {
+	hasStatusAssertion := false
+
+	for _, assertion := range assertions {
+		var actual string
+		exists := true
+
+		switch assertion.Type {
+		case AssertionTypeStatusCode:
+			hasStatusAssertion = true
+			actual = strconv.Itoa(resp.StatusCode)
+		case AssertionTypeResponseTime:
+			actual = strconv.FormatInt(responseTime, 10)
+		case AssertionTypeBody:
+			actual = body
+		case AssertionTypeHeader:
+			actual = resp.Header.Get(assertion.Path)
+			exists = len(resp.Header.Values(assertion.Path)) > 0
+		case AssertionTypeJSON:
+			if !hasDocument {
+				exists = false
+				break
+			}
+			value, err := jsonpath.Lookup(document, assertion.Path)
+			if err != nil {
+				exists = false
+				break
+			}
+			actual = jsonpath.Stringify(value)
+		}
+
+		if !compareAssertion(assertion.Operator, actual, exists, assertion.Value) {
+			return fmt.Errorf("%w: %s", ErrAssertionFailed, describeAssertion(assertion, actual, exists))
+		}
+	}
+
+	if !hasStatusAssertion && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return fmt.Errorf("%w: expected a 2xx status code, got %d", ErrAssertionFailed, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func compareAssertion(operator AssertionOperator, actual string, exists bool, expected string) bool {
+	if operator == AssertionOperatorExists {
+		return exists
+	}
+
+	if !exists {
+		return false
+	}
+
+	switch operator {
+	case AssertionOperatorEquals:
+		return actual == expected
+	case AssertionOperatorNotEquals:
+		return actual != expected
+	case AssertionOperatorContains:
+		return strings.Contains(actual, expected)
+	case AssertionOperatorLessThan, AssertionOperatorGreaterThan:
+		actualNumber, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return false
+		}
+		expectedNumber, err := strconv.ParseFloat(expected, 64)
+		if err != nil {
+			return false
+		}
+		if operator == AssertionOperatorLessThan {
+			return actualNumber < expectedNumber
+		}
+		return actualNumber > expectedNumber
+	}
+
+	return false
+}
+
+func describeAssertion(assertion Assertion, actual string, exists bool) string {
+	subject := strings.ToLower(string(assertion.Type))
+	if assertion.Path != "" {
+		subject = fmt.Sprintf("%s %s", subject, assertion.Path)
+	}
+
+	if !exists {
+		return fmt.Sprintf("%s not found", subject)
+	}
+
+	if len(actual) > 100 {
+		actual = actual[:100] + "..."
+	}
+
+	return fmt.Sprintf("expected %s %s %q, got %q", subject, strings.ToLower(string(assertion.Operator)), assertion.Value, actual)
+}
@@ -1,106 +1,372 @@
 package monitor
 
 import (
-	"math"
-	"time"
+	"errors"
+	"fmt"
 
+	"github.com/mateusgcoelho/sentinel/engine/internal/escalation"
 	"github.com/mateusgcoelho/sentinel/engine/internal/integration"
+	"github.com/mateusgcoelho/sentinel/engine/internal/slug"
+	"gorm.io/datatypes"
+)
+
+type MonitorType string
+
+const (
+	MonitorTypeHTTP        MonitorType = "HTTP"
+	MonitorTypeHeartbeat   MonitorType = "HEARTBEAT"
+	MonitorTypeGroup       MonitorType = "GROUP"
+	MonitorTypeTransaction MonitorType = "TRANSACTION"
+	MonitorTypeExec        MonitorType = "EXEC"
+	MonitorTypeGRPC        MonitorType = "GRPC"
+	MonitorTypeWebsocket   MonitorType = "WEBSOCKET"
+	MonitorTypeSSE         MonitorType = "SSE"
+)
+
+type HealthState string
+
+const (
+	HealthStateUp       HealthState = "UP"
+	HealthStateDegraded HealthState = "DEGRADED"
+	HealthStateDown     HealthState = "DOWN"
+)
+
+const (
+	defaultFlapWindow    = 10
+	defaultFlapThreshold = 50
 )
 
 type MonitorConfig struct {
-	ID             uint                            `gorm:"primaryKey" json:"id"`
-	Name           string                          `gorm:"not null" json:"name"`
-	URL            string                          `gorm:"not null" json:"url"`
-	Method         string                          `gorm:"not null" json:"method"`
-	Interval       int                             `gorm:"not null" json:"interval"`
-	Threshold      int                             `gorm:"not null" json:"threshold"`
-	Timeout        int                             `gorm:"not null" json:"timeout"`
-	Healthy        bool                            `gorm:"not null" json:"healthy"`
-	LastRun        int64                           `gorm:"not null" json:"last_run"`
-	Running        bool                            `gorm:"not null" json:"running"`
-	Enabled        bool                            `gorm:"default:true" json:"enabled"`
-	CreatedAt      int64                           `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt      int64                           `gorm:"autoUpdateTime" json:"updated_at"`
-	FailedAttempts int                             `gorm:"not null" json:"failed_attempts"`
-	Slots          []Slot                          `gorm:"-" json:"slots"`
-	Integrations   []integration.IntegrationConfig `gorm:"many2many:monitor_config_integrations;" json:"integrations"`
+	ID                   uint                                  `gorm:"primaryKey" json:"id"`
+	Slug                 *string                               `gorm:"uniqueIndex" json:"slug"`
+	Name                 string                                `gorm:"not null" json:"name"`
+	Folder               string                                `gorm:"not null;default:'';index" json:"folder"`
+	Type                 MonitorType                           `gorm:"not null;default:HTTP" json:"type"`
+	URL                  string                                `gorm:"not null" json:"url"`
+	Method               string                                `gorm:"not null" json:"method"`
+	Interval             int                                   `gorm:"not null" json:"interval"`
+	Threshold            int                                   `gorm:"not null" json:"threshold"`
+	Timeout              int                                   `gorm:"not null" json:"timeout"`
+	Headers              datatypes.JSONType[map[string]string] `gorm:"type:json" json:"headers"`
+	GrpcService          string                                `gorm:"not null;default:''" json:"grpc_service"`
+	Subprotocol          string                                `gorm:"not null;default:''" json:"subprotocol"`
+	SendMessage          string                                `gorm:"not null;default:''" json:"send_message"`
+	ExpectMessage        string                                `gorm:"not null;default:''" json:"expect_message"`
+	HttpVersion          HttpVersion                           `gorm:"not null;default:AUTO" json:"http_version"`
+	IPVersion            IPVersion                             `gorm:"not null;default:ANY" json:"ip_version"`
+	DisableRedirects     bool                                  `gorm:"not null;default:false" json:"disable_redirects"`
+	MaxRedirects         int                                   `gorm:"not null;default:0" json:"max_redirects"`
+	TLSSkipVerify        bool                                  `gorm:"not null;default:false" json:"tls_skip_verify"`
+	TLSCACertificate     string                                `gorm:"not null;default:''" json:"tls_ca_certificate,omitempty"`
+	TLSClientCertificate string                                `gorm:"not null;default:''" json:"tls_client_certificate,omitempty"`
+	TLSClientKey         string                                `gorm:"not null;default:''" json:"-"`
+	ProxyURL             string                                `gorm:"not null;default:''" json:"proxy_url,omitempty"`
+	DNSServer            string                                `gorm:"not null;default:''" json:"dns_server,omitempty"`
+	ResponseBodyLimit    int                                   `gorm:"not null;default:0" json:"response_body_limit"`
+	GracePeriod          int                                   `gorm:"not null;default:0" json:"grace_period"`
+	HeartbeatToken       *string                               `gorm:"uniqueIndex" json:"heartbeat_token,omitempty"`
+	HeartbeatStartedAt   int64                                 `gorm:"not null;default:0" json:"heartbeat_started_at"`
+	Healthy              bool                                  `gorm:"not null" json:"healthy"`
+	Health               HealthState                           `gorm:"not null;default:DOWN" json:"health"`
+	DegradedResponseTime int                                   `gorm:"not null;default:0" json:"degraded_response_time"`
+	FlapWindow           int                                   `gorm:"not null;default:0" json:"flap_window"`
+	FlapThreshold        int                                   `gorm:"not null;default:0" json:"flap_threshold"`
+	Flapping             bool                                  `gorm:"not null;default:false" json:"flapping"`
+	LastRun              int64                                 `gorm:"not null" json:"last_run"`
+	Running              bool                                  `gorm:"not null" json:"running"`
+	RunningSince         int64                                 `gorm:"not null;default:0" json:"running_since"`
+	RefreshPending       bool                                  `gorm:"not null;default:false" json:"-"`
+	Enabled              bool                                  `gorm:"default:true" json:"enabled"`
+	CreatedAt            int64                                 `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt            int64                                 `gorm:"autoUpdateTime" json:"updated_at"`
+	FailedAttempts       int                                   `gorm:"not null" json:"failed_attempts"`
+	IncidentStartedAt    int64                                 `gorm:"not null;default:0" json:"incident_started_at"`
+	AcknowledgedAt       int64                                 `gorm:"not null;default:0" json:"acknowledged_at"`
+	EscalationPolicyID   *uint                                 `json:"escalation_policy_id"`
+	EscalationPolicy     *escalation.EscalationPolicy          `gorm:"foreignKey:EscalationPolicyID" json:"escalation_policy,omitempty"`
+	AggregationRule      AggregationRule                       `gorm:"not null;default:ALL" json:"aggregation_rule"`
+	AggregationMinimum   int                                   `gorm:"not null;default:0" json:"aggregation_minimum"`
+	Steps                datatypes.JSONSlice[TransactionStep]  `gorm:"type:json" json:"steps,omitempty"`
+	Command              string                                `gorm:"not null;default:''" json:"command,omitempty"`
+	Arguments            datatypes.JSONSlice[string]           `gorm:"type:json" json:"arguments,omitempty"`
+	Environment          datatypes.JSONSlice[string]           `gorm:"type:json" json:"environment,omitempty"`
+	Members              []GroupMember                         `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE;" json:"members,omitempty"`
+	Tags                 []MonitorTag                          `gorm:"foreignKey:MonitorConfigID;constraint:OnDelete:CASCADE;" json:"tags"`
+	Parents              []MonitorConfig                       `gorm:"many2many:monitor_config_dependencies;joinForeignKey:MonitorConfigID;joinReferences:ParentID" json:"parents,omitempty"`
+	Slots                []Slot                                `gorm:"-" json:"slots"`
+	Integrations         []integration.IntegrationConfig       `gorm:"many2many:monitor_config_integrations;" json:"integrations"`
 }
 
 type Slot struct {
-	Timestamp           int64 `json:"timestamp"`
-	Healthy             bool  `json:"healthy"`
-	IsMonitoringEnabled bool  `json:"is_monitoring_enabled"`
+	Timestamp           int64       `json:"timestamp"`
+	Healthy             bool        `json:"healthy"`
+	Health              HealthState `json:"health"`
+	IsMonitoringEnabled bool        `json:"is_monitoring_enabled"`
 }
 
 type Attempt struct {
-	ID              uint          `gorm:"primaryKey" json:"id"`
-	MonitorConfigID uint          `gorm:"not null" json:"monitor_config_id"`
-	MonitorConfig   MonitorConfig `gorm:"foreignKey:MonitorConfigID" json:"monitor_config"`
-	Healthy         bool          `gorm:"not null" json:"healthy"`
-	StatusCode      int           `gorm:"not null" json:"status_code"`
-	Response        any           `gorm:"type:json" json:"response"`
-	CreatedAt       int64         `gorm:"autoCreateTime" json:"created_at"`
+	ID                uint                            `gorm:"primaryKey" json:"id"`
+	MonitorConfigID   uint                            `gorm:"not null;index:idx_attempts_monitor_created_at,priority:1" json:"monitor_config_id"`
+	MonitorConfig     *MonitorConfig                  `gorm:"foreignKey:MonitorConfigID" json:"monitor_config,omitempty"`
+	Healthy           bool                            `gorm:"not null" json:"healthy"`
+	Health            HealthState                     `gorm:"not null;default:DOWN" json:"health"`
+	StatusCode        int                             `gorm:"not null" json:"status_code"`
+	ResponseTime      int64                           `gorm:"not null;default:0" json:"response_time"`
+	BlockedByID       *uint                           `json:"blocked_by_id"`
+	FailureReason     FailureReason                   `gorm:"not null;default:''" json:"failure_reason,omitempty"`
+	FinalURL          string                          `gorm:"not null;default:''" json:"final_url,omitempty"`
+	Steps             datatypes.JSONSlice[StepResult] `gorm:"type:json" json:"steps,omitempty"`
+	Response          any                             `gorm:"type:json" json:"response"`
+	ResponseSize      int64                           `gorm:"not null;default:0" json:"response_size"`
+	ResponseHash      string                          `gorm:"not null;default:''" json:"response_hash,omitempty"`
+	ResponseTruncated bool                            `gorm:"not null;default:false" json:"response_truncated"`
+	CreatedAt         int64                           `gorm:"autoCreateTime;index:idx_attempts_monitor_created_at,priority:2" json:"created_at"`
 }
 
 type CreateMonitorConfigRequest struct {
-	Name              string `json:"name" binding:"required"`
-	URL               string `json:"url" binding:"required,url"`
-	Method            string `json:"method" binding:"required,oneof=GET POST PUT"`
-	Interval          int    `json:"interval" binding:"required,min=1"`
-	Threshold         int    `json:"threshold" binding:"required,min=1"`
-	Timeout           int    `json:"timeout" binding:"required,min=1"`
-	IntegrationIdList []uint `json:"integration_id_list"`
+	Slug                 string               `json:"slug"`
+	Name                 string               `json:"name" binding:"required"`
+	Folder               string               `json:"folder"`
+	Tags                 []TagRequest         `json:"tags" binding:"omitempty,dive"`
+	Type                 MonitorType          `json:"type" binding:"omitempty,oneof=HTTP HEARTBEAT GROUP TRANSACTION EXEC GRPC WEBSOCKET SSE"`
+	URL                  string               `json:"url" binding:"omitempty,url"`
+	Method               string               `json:"method" binding:"omitempty,oneof=GET POST PUT"`
+	Interval             int                  `json:"interval" binding:"required,min=1"`
+	Threshold            int                  `json:"threshold" binding:"required,min=1"`
+	Timeout              int                  `json:"timeout" binding:"omitempty,min=1"`
+	GracePeriod          int                  `json:"grace_period" binding:"min=0"`
+	Headers              map[string]string    `json:"headers"`
+	GrpcService          string               `json:"grpc_service"`
+	Subprotocol          string               `json:"subprotocol"`
+	SendMessage          string               `json:"send_message"`
+	ExpectMessage        string               `json:"expect_message"`
+	HttpVersion          HttpVersion          `json:"http_version" binding:"omitempty,oneof=AUTO HTTP1 HTTP2 HTTP3"`
+	IPVersion            IPVersion            `json:"ip_version" binding:"omitempty,oneof=ANY IPV4 IPV6"`
+	DisableRedirects     bool                 `json:"disable_redirects"`
+	MaxRedirects         int                  `json:"max_redirects" binding:"min=0"`
+	TLSSkipVerify        bool                 `json:"tls_skip_verify"`
+	TLSCACertificate     string               `json:"tls_ca_certificate"`
+	TLSClientCertificate string               `json:"tls_client_certificate"`
+	TLSClientKey         string               `json:"tls_client_key"`
+	ProxyURL             string               `json:"proxy_url"`
+	DNSServer            string               `json:"dns_server"`
+	ResponseBodyLimit    int                  `json:"response_body_limit" binding:"min=0,max=1048576"`
+	DegradedResponseTime int                  `json:"degraded_response_time" binding:"min=0"`
+	FlapWindow           *int                 `json:"flap_window" binding:"omitempty,min=0,max=100"`
+	FlapThreshold        *int                 `json:"flap_threshold" binding:"omitempty,min=1,max=100"`
+	IntegrationIdList    []uint               `json:"integration_id_list"`
+	ParentIdList         []uint               `json:"parent_id_list"`
+	AggregationRule      AggregationRule      `json:"aggregation_rule" binding:"omitempty,oneof=ALL AT_LEAST WEIGHTED"`
+	AggregationMinimum   int                  `json:"aggregation_minimum" binding:"min=0"`
+	MemberList           []GroupMemberRequest `json:"member_list" binding:"omitempty,dive"`
+	Steps                []TransactionStep    `json:"steps" binding:"omitempty,dive"`
+	Command              string               `json:"command"`
+	Arguments            []string             `json:"arguments"`
+	Environment          []string             `json:"environment" binding:"omitempty,dive,contains=="`
+	EscalationPolicyID   *uint                `json:"escalation_policy_id"`
 }
 
 type UpdateMonitorConfigRequest struct {
-	Name              *string `json:"name"`
-	URL               *string `json:"url" binding:"omitempty,url"`
-	Method            *string `json:"method" binding:"omitempty,oneof=GET POST PUT"`
-	Interval          *int    `json:"interval" binding:"omitempty,min=1"`
-	Threshold         *int    `json:"threshold" binding:"omitempty,min=1"`
-	Timeout           *int    `json:"timeout" binding:"omitempty,min=1"`
-	Enabled           *bool   `json:"enabled"`
-	IntegrationIdList *[]uint `json:"integration_id_list"`
+	Slug                 *string               `json:"slug"`
+	Name                 *string               `json:"name"`
+	Folder               *string               `json:"folder"`
+	Tags                 *[]TagRequest         `json:"tags" binding:"omitempty,dive"`
+	URL                  *string               `json:"url" binding:"omitempty,url"`
+	Method               *string               `json:"method" binding:"omitempty,oneof=GET POST PUT"`
+	Interval             *int                  `json:"interval" binding:"omitempty,min=1"`
+	Threshold            *int                  `json:"threshold" binding:"omitempty,min=1"`
+	Timeout              *int                  `json:"timeout" binding:"omitempty,min=1"`
+	GracePeriod          *int                  `json:"grace_period" binding:"omitempty,min=0"`
+	Headers              *map[string]string    `json:"headers"`
+	GrpcService          *string               `json:"grpc_service"`
+	Subprotocol          *string               `json:"subprotocol"`
+	SendMessage          *string               `json:"send_message"`
+	ExpectMessage        *string               `json:"expect_message"`
+	HttpVersion          *HttpVersion          `json:"http_version" binding:"omitempty,oneof=AUTO HTTP1 HTTP2 HTTP3"`
+	IPVersion            *IPVersion            `json:"ip_version" binding:"omitempty,oneof=ANY IPV4 IPV6"`
+	DisableRedirects     *bool                 `json:"disable_redirects"`
+	MaxRedirects         *int                  `json:"max_redirects" binding:"omitempty,min=0"`
+	TLSSkipVerify        *bool                 `json:"tls_skip_verify"`
+	TLSCACertificate     *string               `json:"tls_ca_certificate"`
+	TLSClientCertificate *string               `json:"tls_client_certificate"`
+	TLSClientKey         *string               `json:"tls_client_key"`
+	ProxyURL             *string               `json:"proxy_url"`
+	DNSServer            *string               `json:"dns_server"`
+	ResponseBodyLimit    *int                  `json:"response_body_limit" binding:"omitempty,min=0,max=1048576"`
+	Enabled              *bool                 `json:"enabled"`
+	DegradedResponseTime *int                  `json:"degraded_response_time" binding:"omitempty,min=0"`
+	FlapWindow           *int                  `json:"flap_window" binding:"omitempty,min=0,max=100"`
+	FlapThreshold        *int                  `json:"flap_threshold" binding:"omitempty,min=1,max=100"`
+	IntegrationIdList    *[]uint               `json:"integration_id_list"`
+	ParentIdList         *[]uint               `json:"parent_id_list"`
+	AggregationRule      *AggregationRule      `json:"aggregation_rule" binding:"omitempty,oneof=ALL AT_LEAST WEIGHTED"`
+	AggregationMinimum   *int                  `json:"aggregation_minimum" binding:"omitempty,min=0"`
+	MemberList           *[]GroupMemberRequest `json:"member_list" binding:"omitempty,dive"`
+	Steps                *[]TransactionStep    `json:"steps" binding:"omitempty,min=1,dive"`
+	Command              *string               `json:"command"`
+	Arguments            *[]string             `json:"arguments"`
+	Environment          *[]string             `json:"environment" binding:"omitempty,dive,contains=="`
+	EscalationPolicyID   *uint                 `json:"escalation_policy_id"`
+}
+
+type HeartbeatPingRequest struct {
+	Duration *int64 `json:"duration" binding:"omitempty,min=0"`
+	Message  string `json:"message"`
 }
 
-const totalSlots = 25
+func (r CreateMonitorConfigRequest) MonitorType() MonitorType {
+	if r.Type == "" {
+		return MonitorTypeHTTP
+	}
 
-func generateSlots(attempts []Attempt, intervalInSeconds int) []Slot {
-	slots := make([]Slot, totalSlots)
+	return r.Type
+}
 
-	for i := range slots {
-		slots[i] = Slot{
-			Timestamp:           0,
-			Healthy:             false,
-			IsMonitoringEnabled: false,
-		}
+func (r CreateMonitorConfigRequest) aggregationRule() AggregationRule {
+	if r.AggregationRule == "" {
+		return AggregationRuleAll
+	}
+
+	return r.AggregationRule
+}
+
+func (r CreateMonitorConfigRequest) httpVersion() HttpVersion {
+	if r.HttpVersion == "" {
+		return HttpVersionAuto
 	}
 
-	if intervalInSeconds <= 0 {
-		return slots
+	return r.HttpVersion
+}
+
+func (r CreateMonitorConfigRequest) ipVersion() IPVersion {
+	if r.IPVersion == "" {
+		return IPVersionAny
 	}
 
-	intervalInMilliseconds := int64(intervalInSeconds) * 1000
+	return r.IPVersion
+}
+
+// monitorConfig maps the request onto an unsaved monitor. Relations that
+// have to be loaded from the database are left for the caller to set.
+func (r CreateMonitorConfigRequest) monitorConfig() MonitorConfig {
+	flapWindow := defaultFlapWindow
+	if r.FlapWindow != nil {
+		flapWindow = *r.FlapWindow
+	}
 
-	now := time.Now().UnixNano() / int64(time.Millisecond)
+	flapThreshold := defaultFlapThreshold
+	if r.FlapThreshold != nil {
+		flapThreshold = *r.FlapThreshold
+	}
 
-	referenceTime := (now / intervalInMilliseconds) * intervalInMilliseconds
+	monitorConfig := MonitorConfig{
+		Name:                 r.Name,
+		Folder:               normalizeFolder(r.Folder),
+		Tags:                 buildTags(r.Tags),
+		Type:                 r.MonitorType(),
+		URL:                  r.URL,
+		Method:               r.Method,
+		Interval:             r.Interval,
+		Threshold:            r.Threshold,
+		Timeout:              r.Timeout,
+		GracePeriod:          r.GracePeriod,
+		DegradedResponseTime: r.DegradedResponseTime,
+		FlapWindow:           flapWindow,
+		FlapThreshold:        flapThreshold,
+		Healthy:              false,
+		Health:               HealthStateDown,
+		Running:              false,
+		Steps:                r.Steps,
+		Headers:              datatypes.NewJSONType(r.Headers),
+		GrpcService:          r.GrpcService,
+		Subprotocol:          r.Subprotocol,
+		SendMessage:          r.SendMessage,
+		ExpectMessage:        r.ExpectMessage,
+		HttpVersion:          r.httpVersion(),
+		IPVersion:            r.ipVersion(),
+		DisableRedirects:     r.DisableRedirects,
+		MaxRedirects:         r.MaxRedirects,
+		TLSSkipVerify:        r.TLSSkipVerify,
+		TLSCACertificate:     r.TLSCACertificate,
+		TLSClientCertificate: r.TLSClientCertificate,
+		TLSClientKey:         r.TLSClientKey,
+		ProxyURL:             r.ProxyURL,
+		DNSServer:            r.DNSServer,
+		ResponseBodyLimit:    r.ResponseBodyLimit,
+		Command:              r.Command,
+		Arguments:            r.Arguments,
+		Environment:          r.Environment,
+		EscalationPolicyID:   r.EscalationPolicyID,
+	}
 
-	for _, attempt := range attempts {
-		attemptTime := attempt.CreatedAt * 1000
+	if monitorConfig.Type == MonitorTypeGroup {
+		monitorConfig.AggregationRule = r.aggregationRule()
+		monitorConfig.AggregationMinimum = r.AggregationMinimum
+	}
 
-		intervalsPassed := int(math.Floor(float64(referenceTime-attemptTime) / float64(intervalInMilliseconds)))
+	return monitorConfig
+}
 
-		finalIndex := totalSlots - 1 - intervalsPassed
+// Validate checks the fields that are only required for some monitor types,
+// which binding tags alone cannot express.
+func (r CreateMonitorConfigRequest) Validate() error {
+	if r.Slug != "" && !slug.Valid(r.Slug) {
+		return ErrInvalidSlug
+	}
 
-		if finalIndex >= 0 && finalIndex < totalSlots {
-			slots[finalIndex] = Slot{
-				Timestamp:           attemptTime,
-				Healthy:             attempt.Healthy,
-				IsMonitoringEnabled: true,
-			}
+	switch r.MonitorType() {
+	case MonitorTypeHTTP:
+		if r.URL == "" {
+			return errors.New("url is required for HTTP monitors")
+		}
+		if r.Method == "" {
+			return errors.New("method is required for HTTP monitors")
+		}
+		if r.Timeout <= 0 {
+			return errors.New("timeout is required for HTTP monitors")
+		}
+	case MonitorTypeGroup:
+		return validateAggregation(r.aggregationRule(), r.AggregationMinimum, len(r.MemberList))
+	case MonitorTypeTransaction:
+		if len(r.Steps) == 0 {
+			return errors.New("at least one step is required for TRANSACTION monitors")
+		}
+		if r.Timeout <= 0 {
+			return errors.New("timeout is required for TRANSACTION monitors")
+		}
+	case MonitorTypeExec:
+		if r.Command == "" {
+			return errors.New("command is required for EXEC monitors")
+		}
+		if r.Timeout <= 0 {
+			return errors.New("timeout is required for EXEC monitors")
+		}
+		if err := validateExecEnvironment(r.Environment); err != nil {
+			return err
+		}
+		return validateExecCommand(r.Command)
+	case MonitorTypeGRPC:
+		if _, _, err := grpcTarget(r.URL); err != nil {
+			return fmt.Errorf("invalid url for GRPC monitors: %v", err)
+		}
+		if r.Timeout <= 0 {
+			return errors.New("timeout is required for GRPC monitors")
+		}
+	case MonitorTypeWebsocket:
+		if _, err := websocketOrigin(r.URL); err != nil {
+			return fmt.Errorf("invalid url for WEBSOCKET monitors: %v", err)
+		}
+		if r.Timeout <= 0 {
+			return errors.New("timeout is required for WEBSOCKET monitors")
+		}
+	case MonitorTypeSSE:
+		if r.URL == "" {
+			return errors.New("url is required for SSE monitors")
+		}
+		if r.Timeout <= 0 {
+			return errors.New("timeout is required for SSE monitors")
 		}
 	}
 
-	return slots
+	return nil
 }
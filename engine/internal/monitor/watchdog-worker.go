@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// staleRunningMargin is added to the monitor timeout before an execution is
+// considered stuck, leaving room for alerts and database writes.
+const staleRunningMargin = 60
+
+type RunningWatchdogWorker struct {
+	database *gorm.DB
+}
+
+func NewRunningWatchdogWorker(db *gorm.DB) *RunningWatchdogWorker {
+	return &RunningWatchdogWorker{
+		database: db,
+	}
+}
+
+func (w *RunningWatchdogWorker) StartWorker() error {
+	log.Println("[running-watchdog-worker] starting running watchdog worker")
+
+	for {
+		if err := w.resetStaleMonitors(); err != nil {
+			log.Printf("[running-watchdog-worker] failed to reset stale monitors: %v", err)
+		}
+
+		time.Sleep(30 * time.Second)
+	}
+}
+
+func (w *RunningWatchdogWorker) resetStaleMonitors() error {
+	var monitors []MonitorConfig
+	if err := w.database.
+		Where("running = ? AND running_since > 0 AND (running_since + timeout + ?) < ?", true, staleRunningMargin, time.Now().Unix()).
+		Find(&monitors).Error; err != nil {
+		return err
+	}
+
+	for _, monitorConfig := range monitors {
+		logPrefix := fmt.Sprintf("[running-watchdog-worker] [monitor_config_id: %d | monitor_config_name: %s]", monitorConfig.ID, monitorConfig.Name)
+
+		// Matching on running_since keeps the reset from releasing a newer
+		// execution that claimed the monitor in the meantime.
+		tx := w.database.Model(&MonitorConfig{}).
+			Where("id = ? AND running = ? AND running_since = ?", monitorConfig.ID, true, monitorConfig.RunningSince).
+			UpdateColumns(map[string]any{"running": false, "running_since": 0})
+		if tx.Error != nil {
+			log.Printf("%s failed to reset running flag: %v", logPrefix, tx.Error)
+			continue
+		}
+
+		if tx.RowsAffected == 0 {
+			continue
+		}
+
+		log.Printf("%s execution stuck since %d, running flag reset", logPrefix, monitorConfig.RunningSince)
+
+		elapsed := time.Now().Unix() - monitorConfig.RunningSince
+		recordCheckerError(w.database, logPrefix, monitorConfig, fmt.Sprintf("execution did not finish after %d seconds and was reset by the watchdog", elapsed))
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+var (
+	ErrUnexpectedMessage = errors.New("expected message not received")
+)
+
+// websocketOrigin derives the Origin header sent during the handshake from
+// the monitor URL, since most gateways only accept same-origin upgrades.
+func websocketOrigin(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if parsed.Host == "" {
+		return "", fmt.Errorf("missing host in %q", rawURL)
+	}
+
+	switch parsed.Scheme {
+	case "ws":
+		return "http://" + parsed.Host, nil
+	case "wss":
+		return "https://" + parsed.Host, nil
+	default:
+		return "", fmt.Errorf("unsupported scheme %q, use ws:// or wss://", parsed.Scheme)
+	}
+}
+
+// executeWebsocketCheck opens the connection and, when a message is
+// configured, sends it and waits for a reply. Replies must contain the
+// expected message when one is set, otherwise any reply is accepted.
+func executeWebsocketCheck(monitorConfig MonitorConfig) (ExecutionResponse, error) {
+	origin, err := websocketOrigin(monitorConfig.URL)
+	if err != nil {
+		return ExecutionResponse{}, fmt.Errorf("invalid websocket url: %v", err)
+	}
+
+	config, err := websocket.NewConfig(monitorConfig.URL, origin)
+	if err != nil {
+		return ExecutionResponse{}, fmt.Errorf("invalid websocket url: %v", err)
+	}
+
+	config.Header = http.Header{}
+	for key, value := range monitorConfig.Headers.Data() {
+		config.Header.Set(key, value)
+	}
+
+	if monitorConfig.Subprotocol != "" {
+		config.Protocol = []string{monitorConfig.Subprotocol}
+	}
+
+	timeout := time.Duration(monitorConfig.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	startedAt := time.Now()
+
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ExecutionResponse{ResponseTime: time.Since(startedAt)}, ErrDeadlineExceeded
+		}
+
+		// DialError does not unwrap, keep the cause so it can be classified.
+		var dialErr *websocket.DialError
+		if errors.As(err, &dialErr) {
+			err = dialErr.Err
+		}
+
+		return ExecutionResponse{ResponseTime: time.Since(startedAt)}, fmt.Errorf("failed to open websocket: %w", err)
+	}
+	defer conn.Close()
+
+	executionResponse := ExecutionResponse{StatusCode: http.StatusSwitchingProtocols}
+
+	if monitorConfig.SendMessage == "" && monitorConfig.ExpectMessage == "" {
+		executionResponse.ResponseBody = "connection established"
+		executionResponse.ResponseTime = time.Since(startedAt)
+		return executionResponse, nil
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return executionResponse, fmt.Errorf("failed to set websocket deadline: %v", err)
+	}
+
+	if monitorConfig.SendMessage != "" {
+		if err := websocket.Message.Send(conn, monitorConfig.SendMessage); err != nil {
+			executionResponse.ResponseTime = time.Since(startedAt)
+			return executionResponse, fmt.Errorf("failed to send websocket message: %w", err)
+		}
+	}
+
+	for {
+		var message string
+		if err := websocket.Message.Receive(conn, &message); err != nil {
+			executionResponse.ResponseTime = time.Since(startedAt)
+
+			if time.Now().After(deadline) {
+				if monitorConfig.ExpectMessage != "" {
+					return executionResponse, fmt.Errorf("%w: no message containing %q within the timeout", ErrUnexpectedMessage, monitorConfig.ExpectMessage)
+				}
+				return executionResponse, ErrDeadlineExceeded
+			}
+
+			return executionResponse, fmt.Errorf("failed to receive websocket message: %w", err)
+		}
+
+		executionResponse.ResponseBody = message
+
+		if strings.Contains(message, monitorConfig.ExpectMessage) {
+			executionResponse.ResponseTime = time.Since(startedAt)
+			return executionResponse, nil
+		}
+	}
+}
@@ -8,6 +8,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// groupEscalationInterval is how often, in seconds, open group incidents are
+// escalated, since groups are not polled like other monitors.
+const groupEscalationInterval = 10
+
 type MonitorWorker struct {
 	database *gorm.DB
 }
@@ -25,11 +29,24 @@ func (w *MonitorWorker) StartWorker() error {
 		return err
 	}
 
+	var lastGroupEscalation int64
+
 	for {
 		var monitors []MonitorConfig
 		now := time.Now().Unix()
 
-		if err := w.database.Where("(last_run + interval) <= ? AND running = ? AND enabled = ?", now, false, true).Preload("Integrations").Find(&monitors).Error; err != nil {
+		if now-lastGroupEscalation >= groupEscalationInterval {
+			lastGroupEscalation = now
+			if err := escalateGroupIncidents(w.database); err != nil {
+				log.Printf("[worker] failed to escalate group incidents: %v", err)
+			}
+		}
+
+		if err := refreshPendingGroups(w.database); err != nil {
+			log.Printf("[worker] failed to refresh pending groups: %v", err)
+		}
+
+		if err := w.database.Where("(last_run + interval + grace_period) <= ? AND running = ? AND enabled = ? AND type <> ?", now, false, true, MonitorTypeGroup).Preload("Integrations").Preload("EscalationPolicy.Steps.Integrations").Find(&monitors).Error; err != nil {
 			log.Printf("[worker] failed to retrieve monitors for execution: %v", err)
 			continue
 		}
@@ -37,12 +54,19 @@ func (w *MonitorWorker) StartWorker() error {
 		for _, m := range monitors {
 			logPrefix := fmt.Sprintf("[worker] [monitor_config_id: %d | monitor_config_name: %s]", m.ID, m.Name)
 
-			m.Running = true
-			if err := w.database.Save(&m).Error; err != nil {
+			claimedAt, err := claimMonitor(w.database, m.ID)
+			if err != nil {
 				log.Printf("%s failed to set monitor as running: %v", logPrefix, err)
 				continue
 			}
 
+			if claimedAt == 0 {
+				continue
+			}
+
+			m.Running = true
+			m.RunningSince = claimedAt
+
 			go ExecuteMonitor(w.database, m)
 		}
 
@@ -53,7 +77,7 @@ func (w *MonitorWorker) StartWorker() error {
 func (w *MonitorWorker) updateRunningMonitorsToFalse() error {
 	if err := w.database.Model(&MonitorConfig{}).
 		Where("running = ?", true).
-		Update("running", false).Error; err != nil {
+		Updates(map[string]any{"running": false, "running_since": 0}).Error; err != nil {
 		return fmt.Errorf("failed to update running monitors: %w", err)
 	}
 
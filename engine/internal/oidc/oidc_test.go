@@ -0,0 +1,244 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+)
+
+const (
+	testClientID = "sentinel"
+	testCode     = "authorization-code"
+	testNonce    = "nonce"
+	testKid      = "key-1"
+)
+
+var testNow = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// testIdP is a small in-process identity provider. It remembers the PKCE
+// challenge of the last authorization request and only hands out an ID
+// token for a code verifier that matches it.
+type testIdP struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	issuer    string
+	challenge string
+	// claims and kid shape the next ID token.
+	claims jwt.MapClaims
+	kid    string
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	idp := &testIdP{key: key, kid: testKid}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(metadata{
+			Issuer:                idp.issuer,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JwksURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []jsonWebKey{{
+			Kid: testKid,
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if r.PostFormValue("code") != testCode || base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims)
+		token.Header["kid"] = idp.kid
+
+		signed, err := token.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	idp.issuer = idp.server.URL
+	idp.claims = jwt.MapClaims{
+		"iss":   idp.issuer,
+		"aud":   testClientID,
+		"sub":   "subject-1",
+		"nonce": testNonce,
+		"iat":   testNow.Unix(),
+		"exp":   testNow.Add(5 * time.Minute).Unix(),
+	}
+
+	return idp
+}
+
+func (idp *testIdP) provider() *Provider {
+	return NewProvider(Config{
+		Issuer:      idp.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "https://sentinel.example.com/auth/oidc/callback",
+		Scopes:      []string{"openid", "profile"},
+	}, idp.server.Client(), clock.Func(func() time.Time { return testNow }))
+}
+
+// authorize starts a sign in like the browser would and records the
+// challenge the provider sent.
+func (idp *testIdP) authorize(t *testing.T, provider *Provider, verifier string) url.Values {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", testNonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+
+	if !strings.HasPrefix(authURL, idp.server.URL+"/authorize?") {
+		t.Fatalf("auth URL %q does not use the discovered endpoint", authURL)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid auth URL: %v", err)
+	}
+
+	query := parsed.Query()
+	idp.challenge = query.Get("code_challenge")
+
+	return query
+}
+
+func TestAuthCodeURLSendsPKCEChallenge(t *testing.T) {
+	idp := newTestIdP(t)
+
+	query := idp.authorize(t, idp.provider(), "verifier")
+
+	sum := sha256.Sum256([]byte("verifier"))
+	if query.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(sum[:]) || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("challenge = %q (%s), want the S256 hash of the verifier", query.Get("code_challenge"), query.Get("code_challenge_method"))
+	}
+
+	for name, want := range map[string]string{
+		"response_type": "code",
+		"client_id":     testClientID,
+		"state":         "state",
+		"nonce":         testNonce,
+		"scope":         "openid profile",
+	} {
+		if got := query.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestExchangeReturnsVerifiedClaims(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := idp.provider()
+	idp.authorize(t, provider, "verifier")
+
+	claims, err := provider.Exchange(context.Background(), testCode, "verifier", testNonce)
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+
+	if claims["sub"] != "subject-1" {
+		t.Errorf("sub = %v, want subject-1", claims["sub"])
+	}
+}
+
+func TestExchangeRequiresMatchingCodeVerifier(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := idp.provider()
+	idp.authorize(t, provider, "verifier")
+
+	if _, err := provider.Exchange(context.Background(), testCode, "another verifier", testNonce); !errors.Is(err, ErrExchange) {
+		t.Fatalf("expected ErrExchange, got %v", err)
+	}
+}
+
+func TestExchangeRejectsInvalidIDTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(idp *testIdP)
+	}{
+		{name: "nonce", modify: func(idp *testIdP) { idp.claims["nonce"] = "replayed" }},
+		{name: "audience", modify: func(idp *testIdP) { idp.claims["aud"] = "another-client" }},
+		{name: "issuer", modify: func(idp *testIdP) { idp.claims["iss"] = "https://evil.example.com" }},
+		{name: "expired", modify: func(idp *testIdP) { idp.claims["exp"] = testNow.Add(-2 * time.Minute).Unix() }},
+		{name: "no expiry", modify: func(idp *testIdP) { delete(idp.claims, "exp") }},
+		{name: "unknown key", modify: func(idp *testIdP) { idp.kid = "rotated-away" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t)
+			provider := idp.provider()
+			idp.authorize(t, provider, "verifier")
+			tt.modify(idp)
+
+			if _, err := provider.Exchange(context.Background(), testCode, "verifier", testNonce); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.issuer = "https://evil.example.com"
+
+	if _, err := idp.provider().AuthCodeURL(context.Background(), "state", testNonce, "verifier"); !errors.Is(err, ErrDiscovery) {
+		t.Fatalf("expected ErrDiscovery, got %v", err)
+	}
+}
+
+func TestStringListReadsStringOrList(t *testing.T) {
+	claims := jwt.MapClaims{
+		"single": "admins",
+		"list":   []any{"admins", "ops", "admins", 42},
+	}
+
+	if got := StringList(claims, "single"); len(got) != 1 || got[0] != "admins" {
+		t.Errorf("single = %v", got)
+	}
+
+	if got := StringList(claims, "list"); strings.Join(got, ",") != "admins,ops" {
+		t.Errorf("list = %v, want admins,ops", got)
+	}
+
+	if got := StringList(claims, "missing"); got != nil {
+		t.Errorf("missing = %v, want nil", got)
+	}
+}
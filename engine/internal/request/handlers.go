@@ -17,21 +17,23 @@ type RequestLogHandler struct {
 	database *gorm.DB
 
 	apiKeyMiddleware gin.HandlerFunc
+	accessMiddleware gin.HandlerFunc
 }
 
-func NewHandler(db *gorm.DB, apiKeyMiddleware gin.HandlerFunc) *RequestLogHandler {
+func NewHandler(db *gorm.DB, apiKeyMiddleware gin.HandlerFunc, accessMiddleware gin.HandlerFunc) *RequestLogHandler {
 	return &RequestLogHandler{
 		database:         db,
 		apiKeyMiddleware: apiKeyMiddleware,
+		accessMiddleware: accessMiddleware,
 	}
 }
 
 func (h *RequestLogHandler) SetupRoutes(r *gin.Engine) {
 	request := r.Group("/requests")
 	{
-		request.GET("", h.HandleListRequestLogs)
+		request.GET("", h.accessMiddleware, h.HandleListRequestLogs)
 		request.POST("", h.apiKeyMiddleware, h.HandleCaptureLog)
-		request.GET("/metrics", h.HandleGetMetrics)
+		request.GET("/metrics", h.accessMiddleware, h.HandleGetMetrics)
 	}
 }
 
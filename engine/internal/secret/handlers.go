@@ -0,0 +1,86 @@
+package secret
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SecretHandler struct {
+	database *gorm.DB
+
+	adminMiddleware gin.HandlerFunc
+}
+
+// NewHandler keeps the routes behind adminMiddleware, as a secret can be
+// used by any monitor or integration.
+func NewHandler(db *gorm.DB, adminMiddleware gin.HandlerFunc) *SecretHandler {
+	return &SecretHandler{
+		database:        db,
+		adminMiddleware: adminMiddleware,
+	}
+}
+
+func (h *SecretHandler) SetupRoutes(r *gin.Engine) {
+	secrets := r.Group("/secrets")
+	{
+		secrets.GET("", h.adminMiddleware, h.HandleListSecrets)
+		secrets.PUT("/:name", audit.Action("secret.save"), h.adminMiddleware, h.HandleSaveSecret)
+		secrets.DELETE("/:name", audit.Action("secret.delete"), h.adminMiddleware, h.HandleDeleteSecret)
+	}
+}
+
+func (h *SecretHandler) HandleListSecrets(c *gin.Context) {
+	var secrets []Secret
+	if err := h.database.Order("name ASC").Find(&secrets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to list secrets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": secrets})
+}
+
+// HandleSaveSecret creates the secret or replaces its value, so rotating a
+// secret does not require touching the monitors that reference it.
+func (h *SecretHandler) HandleSaveSecret(c *gin.Context) {
+	name := c.Param("name")
+	if !ValidName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "secret names may only contain letters, digits, '_', '.' and '-'"})
+		return
+	}
+
+	var req SaveSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	item := Secret{Name: name, Value: req.Value}
+	if err := h.database.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "secret saved successfully"})
+}
+
+func (h *SecretHandler) HandleDeleteSecret(c *gin.Context) {
+	tx := h.database.Where("name = ?", c.Param("name")).Delete(&Secret{})
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "failed to delete secret"})
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "secret not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "secret deleted successfully"})
+}
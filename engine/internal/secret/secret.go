@@ -0,0 +1,106 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+)
+
+var (
+	namePattern      = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+	referencePattern = regexp.MustCompile(`\$\{secret:([A-Za-z0-9_.-]{1,128})\}`)
+)
+
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Reference returns the placeholder that resolves to the named secret.
+func Reference(name string) string {
+	return "${secret:" + name + "}"
+}
+
+// IsReference reports whether value is nothing but a secret reference.
+func IsReference(value string) bool {
+	match := referencePattern.FindStringIndex(value)
+	return match != nil && match[0] == 0 && match[1] == len(value)
+}
+
+// References lists the secret names used in value, in order of appearance.
+func References(value string) []string {
+	var names []string
+	for _, match := range referencePattern.FindAllStringSubmatch(value, -1) {
+		names = append(names, match[1])
+	}
+
+	return names
+}
+
+// Expand replaces every secret reference in value with the stored secret.
+// Values without references are returned untouched and cost no query.
+func Expand(database *gorm.DB, value string) (string, error) {
+	names := References(value)
+	if len(names) == 0 {
+		return value, nil
+	}
+
+	var secrets []Secret
+	if err := database.Where("name IN ?", names).Find(&secrets).Error; err != nil {
+		return "", fmt.Errorf("failed to load secrets: %v", err)
+	}
+
+	values := make(map[string]string, len(secrets))
+	for _, item := range secrets {
+		values[item.Name] = item.Value
+	}
+
+	var missing error
+	expanded := referencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		name := referencePattern.FindStringSubmatch(reference)[1]
+
+		secretValue, ok := values[name]
+		if !ok && missing == nil {
+			missing = fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+		}
+
+		return secretValue
+	})
+	if missing != nil {
+		return "", missing
+	}
+
+	return expanded, nil
+}
+
+// Missing returns the names out of names that have no stored secret.
+func Missing(database *gorm.DB, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var existing []string
+	if err := database.Model(&Secret{}).Where("name IN ?", names).Pluck("name", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		found[name] = true
+	}
+
+	var missing []string
+	for _, name := range names {
+		if !found[name] {
+			missing = append(missing, name)
+			found[name] = true
+		}
+	}
+
+	return missing, nil
+}
@@ -0,0 +1,16 @@
+package secret
+
+// Secret is a named value that monitors and integrations reference as
+// ${secret:NAME} instead of storing it inline. Values are write-only through
+// the API.
+type Secret struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Name      string `gorm:"not null;uniqueIndex" json:"name"`
+	Value     string `gorm:"not null" json:"-"`
+	CreatedAt int64  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt int64  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+type SaveSecretRequest struct {
+	Value string `json:"value" binding:"required"`
+}
@@ -15,20 +15,30 @@ type IHandler interface {
 type Server struct {
 	config config.Config
 
-	handlers []IHandler
+	handlers    []IHandler
+	middlewares []gin.HandlerFunc
 }
 
-func New(config config.Config, handlers []IHandler) *Server {
+// New creates the server. The middlewares run on every route, after CORS.
+func New(config config.Config, handlers []IHandler, middlewares ...gin.HandlerFunc) *Server {
 	return &Server{
-		handlers: handlers,
-		config:   config,
+		handlers:    handlers,
+		middlewares: middlewares,
+		config:      config,
 	}
 }
 
 func (s *Server) Run() error {
 	r := gin.Default()
 
+	// Sign-in lockouts are keyed by client IP, which must not come from a
+	// forwarded header anyone can set.
+	if err := r.SetTrustedProxies(s.config.TrustedProxies); err != nil {
+		return err
+	}
+
 	s.useCors(r)
+	r.Use(s.middlewares...)
 
 	for _, handler := range s.handlers {
 		handler.SetupRoutes(r)
@@ -0,0 +1,35 @@
+package session
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PruneWorker struct {
+	database *gorm.DB
+}
+
+// NewPruneWorker returns a worker that deletes expired and revoked sessions.
+func NewPruneWorker(db *gorm.DB) *PruneWorker {
+	return &PruneWorker{
+		database: db,
+	}
+}
+
+func (w *PruneWorker) StartWorker() {
+	log.Println("[prune-sessions-worker] starting prune sessions worker")
+
+	for {
+		pruned, err := Prune(w.database)
+
+		if err != nil {
+			log.Printf("[prune-sessions-worker] failed to prune sessions: %v", err)
+		} else if pruned > 0 {
+			log.Printf("[prune-sessions-worker] pruned %d expired or revoked sessions", pruned)
+		}
+
+		time.Sleep(1 * time.Hour)
+	}
+}
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"gorm.io/gorm"
+)
+
+const (
+	// IdleTimeout is how long a session stays valid without being used. Every
+	// use pushes its expiry forward by this much.
+	IdleTimeout = 24 * time.Hour
+	// MaxLifetime caps how far sliding can extend a session, after which the
+	// user has to sign in again.
+	MaxLifetime = 30 * 24 * time.Hour
+	// touchInterval limits how often a session in use is written back.
+	touchInterval = time.Minute
+)
+
+var ErrSessionInvalid = errors.New("session is expired or revoked")
+
+// Session is a signed in browser. Its ID is the jti of the token handed out
+// at sign in, so revoking the session invalidates the token.
+type Session struct {
+	ID         string `gorm:"primaryKey;type:varchar(64)" json:"id"`
+	UserID     uint   `gorm:"not null;index" json:"user_id"`
+	IP         string `gorm:"not null;default:''" json:"ip"`
+	UserAgent  string `gorm:"not null;default:''" json:"user_agent"`
+	CreatedAt  int64  `gorm:"not null" json:"created_at"`
+	LastSeenAt int64  `gorm:"not null" json:"last_seen_at"`
+	ExpiresAt  int64  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt  int64  `gorm:"not null;default:0" json:"revoked_at"`
+	// TwoFactor is set when the sign in was completed with a second factor.
+	TwoFactor bool `gorm:"not null;default:false" json:"two_factor"`
+	Current   bool `gorm:"-" json:"current"`
+}
+
+// Create starts a session for the user. Sessions of the user that already
+// expired are removed along the way.
+func Create(database *gorm.DB, userID uint, ip string, userAgent string, twoFactor bool) (Session, error) {
+	id, err := newID()
+	if err != nil {
+		return Session{}, err
+	}
+
+	now := clock.System.Now()
+
+	session := Session{
+		ID:         id,
+		UserID:     userID,
+		IP:         ip,
+		UserAgent:  userAgent,
+		CreatedAt:  now.Unix(),
+		LastSeenAt: now.Unix(),
+		ExpiresAt:  now.Add(IdleTimeout).Unix(),
+		TwoFactor:  twoFactor,
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND expires_at < ?", userID, now.Unix()).Delete(&Session{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&session).Error
+	})
+
+	return session, err
+}
+
+// Find returns the session if it is still active.
+func Find(database *gorm.DB, id string) (Session, error) {
+	var session Session
+	if err := database.First(&session, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return session, ErrSessionInvalid
+		}
+		return session, err
+	}
+
+	if !session.Active(clock.System.Now()) {
+		return session, ErrSessionInvalid
+	}
+
+	return session, nil
+}
+
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == 0 && s.ExpiresAt > now.Unix()
+}
+
+// Touch slides the expiry of a session in use and reports whether it moved,
+// in which case the token should be reissued. Sessions used within the last
+// minute are left alone to avoid a write on every request.
+func Touch(database *gorm.DB, session *Session) (bool, error) {
+	if clock.System.Now().Unix()-session.LastSeenAt < int64(touchInterval.Seconds()) {
+		return false, nil
+	}
+
+	return true, Extend(database, session)
+}
+
+// Extend pushes the expiry of a session to a full idle timeout from now,
+// within its maximum lifetime.
+func Extend(database *gorm.DB, session *Session) error {
+	now := clock.System.Now()
+	expiresAt := min(now.Add(IdleTimeout).Unix(), session.CreatedAt+int64(MaxLifetime.Seconds()))
+
+	if err := database.Model(session).UpdateColumns(map[string]any{
+		"last_seen_at": now.Unix(),
+		"expires_at":   expiresAt,
+	}).Error; err != nil {
+		return err
+	}
+
+	session.LastSeenAt = now.Unix()
+	session.ExpiresAt = expiresAt
+
+	return nil
+}
+
+// ListActive returns the active sessions of a user, most recently used first.
+func ListActive(database *gorm.DB, userID uint) ([]Session, error) {
+	sessions := []Session{}
+	err := database.
+		Where("user_id = ? AND revoked_at = 0 AND expires_at > ?", userID, clock.System.Now().Unix()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+
+	return sessions, err
+}
+
+// Revoke ends one session of a user and reports whether it was active.
+func Revoke(database *gorm.DB, userID uint, id string) (bool, error) {
+	tx := database.Model(&Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at = 0", id, userID).
+		UpdateColumn("revoked_at", clock.System.Now().Unix())
+
+	return tx.RowsAffected > 0, tx.Error
+}
+
+// RevokeAll ends every session of a user except the ones in keep and returns
+// how many were ended.
+func RevokeAll(database *gorm.DB, userID uint, keep ...string) (int64, error) {
+	query := database.Model(&Session{}).Where("user_id = ? AND revoked_at = 0", userID)
+	if len(keep) > 0 {
+		query = query.Where("id NOT IN ?", keep)
+	}
+
+	tx := query.UpdateColumn("revoked_at", clock.System.Now().Unix())
+
+	return tx.RowsAffected, tx.Error
+}
+
+// Prune deletes the sessions that expired or were revoked, which can never
+// be used again, and returns how many were deleted.
+func Prune(database *gorm.DB) (int64, error) {
+	tx := database.Where("expires_at <= ? OR revoked_at > 0", clock.System.Now().Unix()).Delete(&Session{})
+
+	return tx.RowsAffected, tx.Error
+}
+
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
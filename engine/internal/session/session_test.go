@@ -0,0 +1,169 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mateusgcoelho/sentinel/engine/internal/clock"
+	"github.com/mateusgcoelho/sentinel/engine/internal/database/dbtest"
+	"gorm.io/gorm"
+)
+
+var sessionStart = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// useTestClock freezes clock.System at sessionStart for the test and returns
+// a function moving it forward.
+func useTestClock(t *testing.T) func(d time.Duration) {
+	t.Helper()
+
+	now := sessionStart
+	previous := clock.System
+	clock.System = clock.Func(func() time.Time { return now })
+	t.Cleanup(func() { clock.System = previous })
+
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func newSession(t *testing.T, database *gorm.DB, userID uint) Session {
+	t.Helper()
+
+	session, err := Create(database, userID, "127.0.0.1", "test", false)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	return session
+}
+
+func TestTouchSlidesWithinMaxLifetime(t *testing.T) {
+	advance := useTestClock(t)
+	database := dbtest.Open(t, &Session{})
+
+	session := newSession(t, database, 1)
+
+	elapsed := 30 * time.Second
+	advance(elapsed)
+
+	if moved, err := Touch(database, &session); err != nil || moved {
+		t.Fatalf("touch within a minute = %t, %v, want no write", moved, err)
+	}
+
+	// Used every 20 hours, the session lives on until its maximum lifetime.
+	for {
+		advance(20 * time.Hour)
+		elapsed += 20 * time.Hour
+
+		if elapsed >= MaxLifetime {
+			break
+		}
+
+		if _, err := Find(database, session.ID); err != nil {
+			t.Fatalf("session expired after %s: %v", elapsed, err)
+		}
+
+		if moved, err := Touch(database, &session); err != nil || !moved {
+			t.Fatalf("touch after %s = %t, %v, want the expiry moved", elapsed, moved, err)
+		}
+
+		want := sessionStart.Add(min(elapsed+IdleTimeout, MaxLifetime)).Unix()
+		if session.ExpiresAt != want {
+			t.Fatalf("after %s expires at %d, want %d", elapsed, session.ExpiresAt, want)
+		}
+	}
+
+	if _, err := Find(database, session.ID); !errors.Is(err, ErrSessionInvalid) {
+		t.Errorf("err = %v past the maximum lifetime, want %v", err, ErrSessionInvalid)
+	}
+}
+
+func TestSessionExpiresWhenIdle(t *testing.T) {
+	advance := useTestClock(t)
+	database := dbtest.Open(t, &Session{})
+
+	session := newSession(t, database, 1)
+
+	advance(IdleTimeout)
+	if _, err := Find(database, session.ID); !errors.Is(err, ErrSessionInvalid) {
+		t.Errorf("err = %v after the idle timeout, want %v", err, ErrSessionInvalid)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	useTestClock(t)
+	database := dbtest.Open(t, &Session{})
+
+	session := newSession(t, database, 1)
+
+	if revoked, err := Revoke(database, 2, session.ID); err != nil || revoked {
+		t.Fatalf("revoke by another user = %t, %v, want false", revoked, err)
+	}
+
+	if revoked, err := Revoke(database, 1, session.ID); err != nil || !revoked {
+		t.Fatalf("revoke = %t, %v, want true", revoked, err)
+	}
+
+	if _, err := Find(database, session.ID); !errors.Is(err, ErrSessionInvalid) {
+		t.Errorf("err = %v after revoking, want %v", err, ErrSessionInvalid)
+	}
+
+	if revoked, err := Revoke(database, 1, session.ID); err != nil || revoked {
+		t.Errorf("second revoke = %t, %v, want false", revoked, err)
+	}
+}
+
+func TestRevokeAllKeepsOnlyTheGivenSessions(t *testing.T) {
+	useTestClock(t)
+	database := dbtest.Open(t, &Session{})
+
+	current := newSession(t, database, 1)
+	newSession(t, database, 1)
+	newSession(t, database, 1)
+	other := newSession(t, database, 2)
+
+	if count, err := RevokeAll(database, 1, current.ID); err != nil || count != 2 {
+		t.Fatalf("revoked %d, %v, want the 2 other sessions", count, err)
+	}
+
+	sessions, err := ListActive(database, 1)
+	if err != nil {
+		t.Fatalf("ListActive failed: %v", err)
+	}
+
+	if len(sessions) != 1 || sessions[0].ID != current.ID {
+		t.Errorf("active sessions = %+v, want only the kept one", sessions)
+	}
+
+	if _, err := Find(database, other.ID); err != nil {
+		t.Errorf("session of another user was revoked: %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	advance := useTestClock(t)
+	database := dbtest.Open(t, &Session{})
+
+	newSession(t, database, 1)
+	advance(IdleTimeout / 2)
+
+	revoked := newSession(t, database, 1)
+	if _, err := Revoke(database, 1, revoked.ID); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+
+	active := newSession(t, database, 1)
+	advance(IdleTimeout / 2)
+
+	if pruned, err := Prune(database); err != nil || pruned != 2 {
+		t.Fatalf("pruned %d, %v, want the expired and the revoked session", pruned, err)
+	}
+
+	var remaining []string
+	if err := database.Model(&Session{}).Pluck("id", &remaining).Error; err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+
+	if len(remaining) != 1 || remaining[0] != active.ID {
+		t.Errorf("remaining = %q, want only the active session", remaining)
+	}
+}
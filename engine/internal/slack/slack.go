@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -66,7 +67,45 @@ func SendRecoverMessage(webhookURL, monitorName string, failedAttempts int) erro
 	return sendSlackBlocks(webhookURL, payload)
 }
 
-func SendAlertMessage(webhookURL, monitorName string, errorMessage string, failedAttempts int) error {
+func SendAlertMessage(webhookURL, monitorName string, errorMessage string, failedAttempts int, dependents []string) error {
+	fields := []map[string]string{
+		{
+			"type": "mrkdwn",
+			"text": "*Status:*\n❌ Unhealthy",
+		},
+		{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf(
+				"*Consecutive failures:*\n%d",
+				failedAttempts,
+			),
+		},
+		{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf(
+				"*Last error:*\n%s",
+				errorMessage,
+			),
+		},
+		{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf(
+				"*Time:*\n%s",
+				time.Now().Format("2006-01-02 15:04:05"),
+			),
+		},
+	}
+
+	if len(dependents) > 0 {
+		fields = append(fields, map[string]string{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf(
+				"*Affected dependents:*\n%s",
+				strings.Join(dependents, ", "),
+			),
+		})
+	}
+
 	payload := SlackBlocksPayload{
 		Blocks: []any{
 			map[string]any{
@@ -87,24 +126,65 @@ func SendAlertMessage(webhookURL, monitorName string, errorMessage string, faile
 				},
 			},
 			map[string]any{
-				"type": "section",
-				"fields": []map[string]string{
-					{
-						"type": "mrkdwn",
-						"text": "*Status:*\n❌ Unhealthy",
-					},
+				"type":   "section",
+				"fields": fields,
+			},
+			map[string]any{
+				"type": "divider",
+			},
+			map[string]any{
+				"type": "context",
+				"elements": []map[string]string{
 					{
 						"type": "mrkdwn",
-						"text": fmt.Sprintf(
-							"*Consecutive failures:*\n%d",
-							failedAttempts,
-						),
+						"text": "🔧 Automatic monitor • Sentinel (JMCDynamics)",
 					},
+				},
+			},
+		},
+	}
+
+	return sendSlackBlocks(webhookURL, payload)
+}
+
+func SendFlappingMessage(webhookURL, monitorName string, flapping bool, changeRate int) error {
+	header := "🔁 Flapping detected!"
+	text := fmt.Sprintf(
+		"<!here>\n*%s* keeps changing state. Individual alerts are paused until it settles.",
+		monitorName,
+	)
+	if !flapping {
+		header = "🧘 Flapping settled"
+		text = fmt.Sprintf(
+			"*%s* stopped flapping. Regular alerts are back on.",
+			monitorName,
+		)
+	}
+
+	payload := SlackBlocksPayload{
+		Blocks: []any{
+			map[string]any{
+				"type": "header",
+				"text": map[string]string{
+					"type": "plain_text",
+					"text": header,
+				},
+			},
+			map[string]any{
+				"type": "section",
+				"text": map[string]string{
+					"type": "mrkdwn",
+					"text": text,
+				},
+			},
+			map[string]any{
+				"type": "section",
+				"fields": []map[string]string{
 					{
 						"type": "mrkdwn",
 						"text": fmt.Sprintf(
-							"*Last error:*\n%s",
-							errorMessage,
+							"*State change rate:*\n%d%%",
+							changeRate,
 						),
 					},
 					{
@@ -0,0 +1,65 @@
+package slug
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxLength = 64
+
+var pattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Valid reports whether value is a lowercase, dash separated slug.
+func Valid(value string) bool {
+	return len(value) <= maxLength && pattern.MatchString(value)
+}
+
+// Make derives a slug from a display name. Characters outside a-z and 0-9
+// become dashes, and a name without any of them falls back to fallback.
+func Make(name string, fallback string) string {
+	var builder strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		if !dash && builder.Len() > 0 {
+			builder.WriteByte('-')
+			dash = true
+		}
+	}
+
+	value := strings.Trim(builder.String(), "-")
+	if len(value) > maxLength-4 {
+		value = strings.Trim(value[:maxLength-4], "-")
+	}
+
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+// Unique appends a numeric suffix to base until taken reports it as free.
+func Unique(base string, taken func(string) (bool, error)) (string, error) {
+	candidate := base
+
+	for i := 2; ; i++ {
+		exists, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			return candidate, nil
+		}
+
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
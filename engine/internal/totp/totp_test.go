@@ -0,0 +1,118 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, base32 encoded.
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+// rfcVectors are the SHA-1 vectors of RFC 6238 appendix B, cut to the six
+// digits used here.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestGenerateMatchesRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	for _, vector := range rfcVectors {
+		if got := generate(key, vector.unix/stepPeriod); got != vector.code {
+			t.Errorf("T=%d: code = %s, want %s", vector.unix, got, vector.code)
+		}
+	}
+}
+
+func TestValidateAcceptsRFC6238Vectors(t *testing.T) {
+	for _, vector := range rfcVectors {
+		step, valid := Validate(rfcSecret, vector.code, time.Unix(vector.unix, 0))
+		if !valid {
+			t.Errorf("T=%d: code %s was refused", vector.unix, vector.code)
+			continue
+		}
+
+		if step != vector.unix/stepPeriod {
+			t.Errorf("T=%d: step = %d, want %d", vector.unix, step, vector.unix/stepPeriod)
+		}
+	}
+}
+
+func TestValidateAllowsOneStepOfSkew(t *testing.T) {
+	// 1111111109 is step 37037036, 1111111111 the next one.
+	const code = "081804"
+	const step = 1111111109 / stepPeriod
+
+	tests := []struct {
+		name  string
+		steps int64
+		valid bool
+	}{
+		{name: "two steps early", steps: -2, valid: false},
+		{name: "one step early", steps: -1, valid: true},
+		{name: "same step", steps: 0, valid: true},
+		{name: "one step late", steps: 1, valid: true},
+		{name: "two steps late", steps: 2, valid: false},
+	}
+
+	for _, tt := range tests {
+		now := time.Unix((step+tt.steps)*stepPeriod, 0)
+
+		matched, valid := Validate(rfcSecret, code, now)
+		if valid != tt.valid {
+			t.Errorf("%s: valid = %t, want %t", tt.name, valid, tt.valid)
+			continue
+		}
+
+		// The matched step is the code's, not the current one, so replays
+		// across the skew are caught.
+		if valid && matched != step {
+			t.Errorf("%s: matched step %d, want %d", tt.name, matched, step)
+		}
+	}
+}
+
+func TestValidateRefusesMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	for _, tt := range []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{name: "eight digits", secret: rfcSecret, code: "94287082"},
+		{name: "five digits", secret: rfcSecret, code: "28708"},
+		{name: "invalid secret", secret: "not base32!", code: "287082"},
+		{name: "other secret", secret: strings.Repeat("A", 32), code: "287082"},
+	} {
+		if _, valid := Validate(tt.secret, tt.code, now); valid {
+			t.Errorf("%s: code was accepted", tt.name)
+		}
+	}
+}
+
+func TestGenerateSecretRoundTrips(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret failed: %v", err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q decodes to %d bytes, %v", secret, len(key), err)
+	}
+
+	now := time.Unix(1234567890, 0)
+	if _, valid := Validate(secret, generate(key, now.Unix()/stepPeriod), now); !valid {
+		t.Error("a code of a generated secret was refused")
+	}
+}
@@ -4,24 +4,28 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/audit"
 	"github.com/mateusgcoelho/sentinel/engine/internal/password"
+	"github.com/mateusgcoelho/sentinel/engine/internal/session"
 	"gorm.io/gorm"
 )
 
 type UserHandler struct {
-	database *gorm.DB
+	database       *gorm.DB
+	authMiddleware gin.HandlerFunc
 }
 
-func NewHandler(db *gorm.DB) *UserHandler {
+func NewHandler(db *gorm.DB, authMiddleware gin.HandlerFunc) *UserHandler {
 	return &UserHandler{
-		database: db,
+		database:       db,
+		authMiddleware: authMiddleware,
 	}
 }
 
 func (h *UserHandler) SetupRoutes(r *gin.Engine) {
 	user := r.Group("/users")
 	{
-		user.PATCH("", h.HandleUpdateProfile)
+		user.PATCH("", audit.Action("user.update_profile"), h.authMiddleware, h.HandleUpdateProfile)
 	}
 }
 
@@ -34,12 +38,24 @@ func (h *UserHandler) HandleUpdateProfile(c *gin.Context) {
 		return
 	}
 
+	audit.SetTarget(c, "USER", user.ID)
+
+	if user.OidcSubject != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "password is managed by your identity provider"})
+		return
+	}
+
 	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
+	if password.Compare(user.Password, req.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new password must differ from the current one"})
+		return
+	}
+
 	hashedPassword, err := password.Hash(req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
@@ -47,11 +63,23 @@ func (h *UserHandler) HandleUpdateProfile(c *gin.Context) {
 	}
 
 	user.Password = hashedPassword
+	user.MustChangePassword = false
+
+	// Every session, including the current one, ends with the old password.
+	err = h.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
 
-	if err := h.database.Save(&user).Error; err != nil {
+		_, err := session.RevokeAll(tx, user.ID)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update profile"})
 		return
 	}
 
+	audit.SetSummary(c, nil, gin.H{"password": "changed"})
+
 	c.JSON(http.StatusOK, gin.H{"message": "profile updated successfully"})
 }
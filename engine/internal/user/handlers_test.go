@@ -0,0 +1,72 @@
+package user
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mateusgcoelho/sentinel/engine/internal/database/dbtest"
+	"github.com/mateusgcoelho/sentinel/engine/internal/password"
+	"github.com/mateusgcoelho/sentinel/engine/internal/session"
+)
+
+func TestUpdateProfileRevokesEverySession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := dbtest.Open(t, &User{}, &session.Session{})
+
+	hashedPassword, err := password.Hash("old-password")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	account := User{Username: "jane", Password: hashedPassword, MustChangePassword: true}
+	other := User{Username: "john", Password: hashedPassword}
+	if err := database.Create(&[]*User{&account, &other}).Error; err != nil {
+		t.Fatalf("failed to create users: %v", err)
+	}
+
+	var sessions []session.Session
+	for _, userID := range []uint{account.ID, account.ID, other.ID} {
+		userSession, err := session.Create(database, userID, "127.0.0.1", "test", false)
+		if err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+		sessions = append(sessions, userSession)
+	}
+
+	// Every session of the user ends, including the one making the request.
+	signedIn := func(c *gin.Context) { c.Set("user_id", fmt.Sprint(account.ID)) }
+	h := NewHandler(database, signedIn)
+
+	r := gin.New()
+	h.SetupRoutes(r)
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPatch, "/users", strings.NewReader(`{"password": "new-password"}`)))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", recorder.Code, recorder.Body)
+	}
+
+	for i, userSession := range sessions[:2] {
+		if _, err := session.Find(database, userSession.ID); err == nil {
+			t.Errorf("session %d of the user is still active", i)
+		}
+	}
+
+	if _, err := session.Find(database, sessions[2].ID); err != nil {
+		t.Errorf("session of another user was revoked: %v", err)
+	}
+
+	var stored User
+	if err := database.First(&stored, account.ID).Error; err != nil {
+		t.Fatalf("failed to reload user: %v", err)
+	}
+
+	if !password.Compare(stored.Password, "new-password") || stored.MustChangePassword {
+		t.Errorf("password was not changed or the change is still required")
+	}
+}
@@ -1,9 +1,49 @@
 package user
 
+type Role string
+
+const (
+	RoleAdmin  Role = "ADMIN"
+	RoleViewer Role = "VIEWER"
+)
+
+// Roles lists every role, least privileged first.
+var Roles = []Role{RoleViewer, RoleAdmin}
+
+// CanWrite reports whether the role may change anything besides the user's
+// own sign in. Unknown and empty roles cannot.
+func (r Role) CanWrite() bool {
+	return r == RoleAdmin
+}
+
 type User struct {
 	ID       uint   `gorm:"primaryKey"`
 	Username string `gorm:"uniqueIndex;not null"`
+	// Password is empty for users provisioned through single sign-on, which
+	// cannot sign in locally.
 	Password string `gorm:"not null"`
+	Role     Role   `gorm:"not null;default:'ADMIN'"`
+	// MustChangePassword limits the user to changing their password, e.g.
+	// while the first admin still has a default or generated one.
+	MustChangePassword bool `gorm:"not null;default:false"`
+	// OidcSubject is the identity provider's subject of users provisioned
+	// through single sign-on.
+	OidcSubject *string `gorm:"uniqueIndex"`
+	// TotpSecret is set when enrollment starts, but only asked for at sign in
+	// once a code confirmed it and TotpEnabled is set. TotpLastStep is the
+	// step of the last accepted code, so a code cannot be used twice.
+	TotpSecret   string `gorm:"not null;default:''"`
+	TotpEnabled  bool   `gorm:"not null;default:false"`
+	TotpLastStep int64  `gorm:"not null;default:0"`
+}
+
+// RecoveryCode is a single use code that replaces a TOTP code when the
+// authenticator is lost. Only its hash is stored.
+type RecoveryCode struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID uint   `gorm:"not null;index"`
+	Hash   string `gorm:"not null"`
+	UsedAt int64  `gorm:"not null;default:0"`
 }
 
 type UpdateProfileRequest struct {